@@ -0,0 +1,346 @@
+package mockgcp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+// resourceNode is a resource held in the MockService that can carry an IAM policy, named the way Cloud
+// Asset names it (//cloudresourcemanager.googleapis.com/projects/x).  Parent is the full name of the
+// resource the policy is inherited from, and is empty at the top of the hierarchy
+type resourceNode struct {
+	Name        string
+	AssetType   string
+	Parent      string
+	Project     string
+	DisplayName string
+	Labels      map[string]string
+	Holder      *IamPolicyHolder
+}
+
+// shortName returns the resource name without the //service.googleapis.com/ prefix
+func (n *resourceNode) shortName() string {
+	return strings.SplitN(strings.TrimPrefix(n.Name, "//"), "/", 2)[1]
+}
+
+// service returns the API service the resource belongs to, such as storage.googleapis.com
+func (n *resourceNode) service() string {
+	return strings.SplitN(strings.TrimPrefix(n.Name, "//"), "/", 2)[0]
+}
+
+// matches returns true if name is the full or short name of the resource
+func (n *resourceNode) matches(name string) bool {
+	return name == n.Name || name == n.shortName()
+}
+
+// crmResourceName returns the full Cloud Asset name of an organization, folder or project
+func crmResourceName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return "//cloudresourcemanager.googleapis.com/" + name
+}
+
+// resourceNodes returns every resource in the service that can carry a policy, parents before children
+func (s *MockService) resourceNodes() []*resourceNode {
+	nodes := []*resourceNode{}
+	for _, organization := range s.Organizations.OrganizationList {
+		nodes = append(nodes, &resourceNode{
+			Name:        crmResourceName(organization.OrganizationID),
+			AssetType:   "cloudresourcemanager.googleapis.com/Organization",
+			DisplayName: organization.Domain,
+			Holder:      &organization.IamPolicyHolder,
+		})
+	}
+	for _, folder := range s.Folders.FolderList {
+		nodes = append(nodes, &resourceNode{
+			Name:        crmResourceName(folder.FolderID),
+			AssetType:   "cloudresourcemanager.googleapis.com/Folder",
+			Parent:      crmResourceName(folder.Parent),
+			DisplayName: folder.DisplayName,
+			Holder:      &folder.IamPolicyHolder,
+		})
+	}
+	for _, project := range s.Projects.ProjectList {
+		projectName := crmResourceName(project.ProjectID)
+		nodes = append(nodes, &resourceNode{
+			Name:        projectName,
+			AssetType:   "cloudresourcemanager.googleapis.com/Project",
+			Parent:      crmResourceName(project.Parent),
+			Project:     project.ProjectID,
+			DisplayName: project.DisplayName,
+			Holder:      &project.IamPolicyHolder,
+		})
+		child := func(name, assetType, parent string, labels map[string]string, holder *IamPolicyHolder) *resourceNode {
+			node := &resourceNode{Name: name, AssetType: assetType, Parent: parent, Project: project.ProjectID, Labels: labels, Holder: holder}
+			nodes = append(nodes, node)
+			return node
+		}
+		for _, account := range project.ServiceAccounts {
+			if !account.Deleted {
+				node := child("//iam.googleapis.com/"+account.Name, "iam.googleapis.com/ServiceAccount", projectName, nil, &account.IamPolicyHolder)
+				node.DisplayName = account.DisplayName
+			}
+		}
+		for _, bucket := range project.Buckets {
+			child("//storage.googleapis.com/"+bucket.Name, "storage.googleapis.com/Bucket", projectName, bucket.Labels, &bucket.IamPolicyHolder)
+		}
+		for _, topic := range project.Topics {
+			child("//pubsub.googleapis.com/"+topic.Name, "pubsub.googleapis.com/Topic", projectName, topic.Labels, &topic.IamPolicyHolder)
+		}
+		for _, subscription := range project.Subscriptions {
+			child("//pubsub.googleapis.com/"+subscription.Name, "pubsub.googleapis.com/Subscription", projectName, subscription.Labels, &subscription.IamPolicyHolder)
+		}
+		for _, secret := range project.Secrets {
+			child("//secretmanager.googleapis.com/"+secret.Name, "secretmanager.googleapis.com/Secret", projectName, secret.Labels, &secret.IamPolicyHolder)
+		}
+		for _, keyRing := range project.KeyRings {
+			keyRingName := "//cloudkms.googleapis.com/" + keyRing.Name
+			child(keyRingName, "cloudkms.googleapis.com/KeyRing", projectName, nil, &keyRing.IamPolicyHolder)
+			for _, cryptoKey := range keyRing.CryptoKeys {
+				child("//cloudkms.googleapis.com/"+cryptoKey.Name, "cloudkms.googleapis.com/CryptoKey", keyRingName, cryptoKey.Labels, &cryptoKey.IamPolicyHolder)
+			}
+		}
+	}
+	return nodes
+}
+
+// resourceIndex looks up resource nodes by their full name
+type resourceIndex map[string]*resourceNode
+
+// newResourceIndex indexes nodes by their full name
+func newResourceIndex(nodes []*resourceNode) resourceIndex {
+	index := resourceIndex{}
+	for _, node := range nodes {
+		index[node.Name] = node
+	}
+	return index
+}
+
+// ancestors returns node followed by each resource above it in the hierarchy, nearest first
+func (index resourceIndex) ancestors(node *resourceNode) []*resourceNode {
+	chain := []*resourceNode{}
+	seen := map[string]bool{}
+	for ancestor := node; ancestor != nil && !seen[ancestor.Name]; ancestor = index[ancestor.Parent] {
+		seen[ancestor.Name] = true
+		chain = append(chain, ancestor)
+	}
+	return chain
+}
+
+// AccessQuery is a question for AnalyzeIamPolicy.  Set Resource to ask who can access it, set Identity to
+// ask what it can access, or set both.  Permissions narrows the answer to those permissions, and
+// RequestTime is the time conditions are evaluated at (now if it isn't set)
+type AccessQuery struct {
+	Resource    string
+	Identity    string
+	Permissions []string
+	RequestTime time.Time
+}
+
+// AccessResult is one answer from AnalyzeIamPolicy: Identity has Permissions on Resource through Role,
+// and Path explains where that comes from.  Conditional is set when the binding's condition can't be
+// evaluated, like Cloud Asset's CONDITIONAL evaluation value: the access may or may not hold, so
+// TestIamPermissions treats it as not granted and WhoCanAccess and WhatCanAccess leave it out
+type AccessResult struct {
+	Resource    string
+	Identity    string
+	Role        string
+	Permissions []string
+	Path        AccessPath
+	Conditional bool
+}
+
+// AccessPath explains an AccessResult.  PolicyResource is the resource whose policy holds the binding,
+// which is an ancestor of the resource when access is inherited.  Member is the member in the binding,
+// and Groups lists the groups from Member down to the identity when access comes through a group.
+// Condition is the binding's condition, if it has one
+type AccessPath struct {
+	PolicyResource string
+	Member         string
+	Groups         []string
+	Condition      *cloudresourcemanager.Expr
+}
+
+// AnalyzeIamPolicy answers "who can do what on which resource" over everything in the service, like Cloud
+// Asset's analyzeIamPolicy.  Policies are inherited down the organization, folder and project hierarchy,
+// roles are expanded with RolePermissions, groups are expanded through the Groups Service and conditions are
+// evaluated with EvaluateCondition.  Resource names can be full (//storage.googleapis.com/bucket) or short
+// (projects/x).  Identities are IAM members, and allUsers or allAuthenticatedUsers bindings answer for any
+// identity asked about
+func (s *MockService) AnalyzeIamPolicy(query AccessQuery) ([]*AccessResult, error) {
+	if query.RequestTime.IsZero() {
+		query.RequestTime = s.now()
+	}
+	nodes := s.resourceNodes()
+	index := newResourceIndex(nodes)
+	found := query.Resource == ""
+	results := []*AccessResult{}
+	for _, node := range nodes {
+		if query.Resource != "" && !node.matches(query.Resource) {
+			continue
+		}
+		found = true
+		for _, ancestor := range index.ancestors(node) {
+			results = append(results, s.analyzeBindings(query, node, ancestor)...)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, query.Resource)
+	}
+	return results, nil
+}
+
+// analyzeBindings returns the access the bindings in the policy of ancestor grant on node
+func (s *MockService) analyzeBindings(query AccessQuery, node, ancestor *resourceNode) []*AccessResult {
+	results := []*AccessResult{}
+	policy := ancestor.Holder.GetIamPolicy()
+	for _, binding := range policy.Bindings {
+		if binding == nil {
+			continue
+		}
+		permissions := grantedPermissions(binding.Role, query.Permissions)
+		if len(permissions) == 0 {
+			continue
+		}
+		conditional := false
+		if binding.Condition != nil {
+			ok, err := EvaluateCondition(binding.Condition.Expression, ConditionContext{
+				RequestTime:     query.RequestTime,
+				ResourceName:    node.shortName(),
+				ResourceType:    node.AssetType,
+				ResourceService: node.service(),
+			})
+			conditional = err != nil
+			if !ok && !conditional {
+				continue
+			}
+		}
+		for _, member := range binding.Members {
+			for _, identity := range s.memberIdentities(member) {
+				if !identityMatches(identity.Member, query.Identity) {
+					continue
+				}
+				results = append(results, &AccessResult{
+					Resource:    node.Name,
+					Identity:    identity.Member,
+					Role:        binding.Role,
+					Permissions: permissions,
+					Path: AccessPath{
+						PolicyResource: ancestor.Name,
+						Member:         member,
+						Groups:         identity.Groups,
+						Condition:      binding.Condition,
+					},
+					Conditional: conditional,
+				})
+			}
+		}
+	}
+	return results
+}
+
+// memberIdentity is an identity a binding member stands for, and the chain of groups that leads to it
+type memberIdentity struct {
+	Member string
+	Groups []string
+}
+
+// memberIdentities returns every identity a binding member stands for.  A group member stands for itself
+// and everyone in it, directly or through nested groups, nearest first
+func (s *MockService) memberIdentities(member string) []memberIdentity {
+	identities := []memberIdentity{{Member: member}}
+	if !strings.HasPrefix(member, "group:") {
+		return identities
+	}
+	group := s.Groups.findByEmail(memberEmail(member))
+	if group == nil {
+		return identities
+	}
+	type step struct {
+		group *Group
+		path  []string
+	}
+	queue := []step{{group, []string{member}}}
+	seen := map[string]bool{member: true}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, membership := range current.group.Memberships {
+			if seen[membership.Member] {
+				continue
+			}
+			seen[membership.Member] = true
+			identities = append(identities, memberIdentity{Member: membership.Member, Groups: copyStrings(current.path)})
+			if membership.Type() != "GROUP" {
+				continue
+			}
+			if nested := s.Groups.findByEmail(membership.Email()); nested != nil {
+				queue = append(queue, step{nested, append(copyStrings(current.path), membership.Member)})
+			}
+		}
+	}
+	return identities
+}
+
+// grantedPermissions returns the permissions role grants out of those asked for, or all of the role's
+// permissions if none were asked for
+func grantedPermissions(role string, permissions []string) []string {
+	granted := RolePermissions[role]
+	if len(permissions) == 0 {
+		return copyStrings(granted)
+	}
+	var matched []string
+	for _, permission := range permissions {
+		if stringInSlice(permission, granted) {
+			matched = append(matched, permission)
+		}
+	}
+	return matched
+}
+
+// identityMatches returns true if a binding identity answers for the identity asked about.  Any identity
+// matches when none was asked for, allUsers matches everyone and allAuthenticatedUsers everyone but allUsers
+func identityMatches(identity, asked string) bool {
+	switch {
+	case asked == "" || identity == asked || identity == "allUsers":
+		return true
+	case identity == "allAuthenticatedUsers":
+		return asked != "allUsers"
+	}
+	return false
+}
+
+// WhoCanAccess returns the identities that have permission on resource, directly, through the hierarchy or
+// through groups
+func (s *MockService) WhoCanAccess(resource, permission string) ([]string, error) {
+	results, err := s.AnalyzeIamPolicy(AccessQuery{Resource: resource, Permissions: []string{permission}})
+	if err != nil {
+		return nil, err
+	}
+	identities := []string{}
+	for _, result := range results {
+		if !result.Conditional && !stringInSlice(result.Identity, identities) {
+			identities = append(identities, result.Identity)
+		}
+	}
+	return identities, nil
+}
+
+// WhatCanAccess returns the full names of the resources identity has permission on
+func (s *MockService) WhatCanAccess(identity, permission string) ([]string, error) {
+	results, err := s.AnalyzeIamPolicy(AccessQuery{Identity: identity, Permissions: []string{permission}})
+	if err != nil {
+		return nil, err
+	}
+	resources := []string{}
+	for _, result := range results {
+		if !result.Conditional && !stringInSlice(result.Resource, resources) {
+			resources = append(resources, result.Resource)
+		}
+	}
+	return resources, nil
+}
@@ -0,0 +1,109 @@
+package mockgcp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+func TestMockService_AnalyzeIamPolicy(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Organizations.NewOrganization("organizations/1", "testdomain.co", GeneratePolicy(NewBinding("roles/viewer", "group:auditors@testdomain.co")))
+	folder := service.Folders.NewFolder("folders/2", "prod", GeneratePolicy(NewBinding("roles/owner", "user:bob@testdomain.co")))
+	folder.Parent = "organizations/1"
+	project := service.Projects.NewProject("projects/test-project", "", nil)
+	project.Parent = "folders/2"
+	service.Topics.NewTopic("test-project", "test-topic", GeneratePolicy(NewBinding("roles/pubsub.publisher", "user:carol@testdomain.co")))
+	auditors := service.Groups.NewGroup("auditors@testdomain.co", "")
+	security := service.Groups.NewGroup("security@testdomain.co", "")
+	auditors.AddMember("group:security@testdomain.co")
+	security.AddMember("user:alice@testdomain.co")
+
+	t.Run("should find who can access resource through hierarchy and groups", func(t *testing.T) {
+		got, err := service.WhoCanAccess("projects/test-project/topics/test-topic", "pubsub.topics.get")
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		want := []string{"user:bob@testdomain.co", "group:auditors@testdomain.co", "group:security@testdomain.co", "user:alice@testdomain.co"}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("should explain access path through nested groups", func(t *testing.T) {
+		results, _ := service.AnalyzeIamPolicy(AccessQuery{
+			Resource:    "//cloudresourcemanager.googleapis.com/projects/test-project",
+			Identity:    "user:alice@testdomain.co",
+			Permissions: []string{"resourcemanager.projects.get"},
+		})
+		if len(results) != 1 {
+			t.Fatalf("expected 1 result but got %v", len(results))
+		}
+
+		want := AccessPath{
+			PolicyResource: "//cloudresourcemanager.googleapis.com/organizations/1",
+			Member:         "group:auditors@testdomain.co",
+			Groups:         []string{"group:auditors@testdomain.co", "group:security@testdomain.co"},
+		}
+		got := results[0].Path
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("should find what identity can access", func(t *testing.T) {
+		got, _ := service.WhatCanAccess("user:carol@testdomain.co", "pubsub.topics.publish")
+		want := []string{"//pubsub.googleapis.com/projects/test-project/topics/test-topic"}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("should skip bindings whose condition doesn't hold", func(t *testing.T) {
+		binding := NewBinding("roles/editor", "user:dave@testdomain.co")
+		binding.Condition = &cloudresourcemanager.Expr{Expression: `request.time < timestamp("2020-01-01T00:00:00Z")`}
+		project.SetIamPolicy(GeneratePolicy(binding))
+
+		results, _ := service.AnalyzeIamPolicy(AccessQuery{Identity: "user:dave@testdomain.co"})
+		if len(results) != 0 {
+			t.Errorf("expected no results but got %v", len(results))
+		}
+
+		results, _ = service.AnalyzeIamPolicy(AccessQuery{Identity: "user:dave@testdomain.co", RequestTime: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)})
+		if len(results) == 0 {
+			t.Errorf("expected results before the condition expires but got none")
+		}
+	})
+	t.Run("should report bindings whose condition can't be evaluated as conditional", func(t *testing.T) {
+		binding := NewBinding("roles/editor", "user:erin@testdomain.co")
+		binding.Condition = &cloudresourcemanager.Expr{Expression: `resource.matchTag("env", "prod")`}
+		project.SetIamPolicy(GeneratePolicy(binding))
+
+		results, err := service.AnalyzeIamPolicy(AccessQuery{Identity: "user:erin@testdomain.co"})
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if len(results) == 0 || !results[0].Conditional {
+			t.Errorf("expected conditional results but got %v", results)
+		}
+
+		got, err := service.WhatCanAccess("user:erin@testdomain.co", "resourcemanager.projects.get")
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("got %v want none", got)
+		}
+	})
+	t.Run("should return err if resource doesn't exist", func(t *testing.T) {
+		_, err := service.AnalyzeIamPolicy(AccessQuery{Resource: "projects/missing"})
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
@@ -0,0 +1,454 @@
+package mockgcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	cloudasset "google.golang.org/api/cloudasset/v1"
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+)
+
+// AssetsService is a mock of google Cloud Asset Inventory's v1 Service.  It searches and exports the
+// resources held in the MockService, so inventory tooling can run against it offline
+type AssetsService struct {
+	Service *MockService
+	// Exports holds the newline delimited JSON written by ExportAssets, keyed by the destination GCS uri
+	Exports map[string][]byte
+}
+
+// NewAssetsService will return a new Assets Service
+func NewAssetsService(s *MockService) *AssetsService {
+	return &AssetsService{Service: s, Exports: map[string][]byte{}}
+}
+
+// assetScope is a resource node with everything about where it sits in the hierarchy that a search or
+// export reports
+type assetScope struct {
+	node         *resourceNode
+	ancestors    []string
+	folders      []string
+	organization string
+	parentType   string
+}
+
+// scopedNodes returns the resources inside scope (an organization, folder or project such as
+// projects/test-project) with their ancestry, parents before children
+func (r *AssetsService) scopedNodes(scope string) ([]*assetScope, error) {
+	nodes := r.Service.resourceNodes()
+	index := newResourceIndex(nodes)
+	scopeName := crmResourceName(scope)
+	if _, ok := index[scopeName]; !ok {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, scope)
+	}
+	scoped := []*assetScope{}
+	for _, node := range nodes {
+		for _, ancestor := range index.ancestors(node) {
+			if ancestor.Name == scopeName {
+				scoped = append(scoped, newAssetScope(node, index))
+				break
+			}
+		}
+	}
+	return scoped, nil
+}
+
+// newAssetScope works out where node sits in the hierarchy
+func newAssetScope(node *resourceNode, index resourceIndex) *assetScope {
+	item := &assetScope{node: node}
+	for _, ancestor := range index.ancestors(node) {
+		short := ancestor.shortName()
+		switch ancestor.AssetType {
+		case "cloudresourcemanager.googleapis.com/Project":
+			item.ancestors = append(item.ancestors, short)
+		case "cloudresourcemanager.googleapis.com/Folder":
+			item.ancestors = append(item.ancestors, short)
+			item.folders = append(item.folders, short)
+		case "cloudresourcemanager.googleapis.com/Organization":
+			item.ancestors = append(item.ancestors, short)
+			item.organization = short
+		}
+	}
+	if parent, ok := index[node.Parent]; ok {
+		item.parentType = parent.AssetType
+	}
+	return item
+}
+
+// assetTypeMatches returns true if assetType matches one of assetTypes.  Like the real API these are
+// regular expressions, so storage.googleapis.com.* matches a whole service.  An empty list matches everything
+func assetTypeMatches(assetType string, assetTypes []string) bool {
+	if len(assetTypes) == 0 {
+		return true
+	}
+	for _, t := range assetTypes {
+		if matched, err := regexp.MatchString("^(?:"+t+")$", assetType); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// assetQueryMatches returns true if the resource matches every term of an asset search query.  It supports
+// the field:value terms policy:, policy.role.permissions:, memberTypes:, project:, name:, displayName:,
+// assetType:, resource: and labels.KEY:, plus bare words which match the name, display name or policy.
+// Matching is case insensitive and partial, like the real API, and AND between terms is optional
+func assetQueryMatches(query string, item *assetScope) (bool, error) {
+	node := item.node
+	policy := node.Holder.GetIamPolicy()
+	for _, term := range strings.Fields(query) {
+		if term == "AND" {
+			continue
+		}
+		if term == "OR" || term == "NOT" {
+			return false, fmt.Errorf("unsupported query operator: %v", term)
+		}
+		field, value := "", term
+		if i := strings.Index(term, ":"); i > 0 {
+			field, value = term[:i], term[i+1:]
+		}
+		value = strings.ToLower(strings.Trim(value, `"`))
+		var ok bool
+		switch {
+		case field == "":
+			ok = containsFold(node.Name, value) || containsFold(node.DisplayName, value) || policyMentions(policy, value)
+		case field == "policy":
+			ok = policyMentions(policy, value)
+		case field == "policy.role.permissions":
+			ok = policyGrantsPermission(policy, value)
+		case field == "memberTypes":
+			ok = policyHasMemberType(policy, value)
+		case field == "project":
+			ok = node.Project != "" && strings.EqualFold(strings.TrimPrefix(node.Project, "projects/"), strings.TrimPrefix(value, "projects/"))
+		case field == "name" || field == "resource":
+			ok = containsFold(node.Name, value)
+		case field == "displayName":
+			ok = containsFold(node.DisplayName, value)
+		case field == "assetType":
+			ok = strings.EqualFold(node.AssetType, value)
+		case strings.HasPrefix(field, "labels."):
+			label, exists := node.Labels[strings.TrimPrefix(field, "labels.")]
+			ok = exists && (value == "*" || containsFold(label, value))
+		default:
+			return false, fmt.Errorf("unsupported query field: %v", field)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// containsFold returns true if s contains the lower case substr, ignoring case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), substr)
+}
+
+// policyMentions returns true if any role or member in the policy contains value
+func policyMentions(policy *cloudresourcemanager.Policy, value string) bool {
+	for _, binding := range policy.Bindings {
+		if binding == nil {
+			continue
+		}
+		if containsFold(binding.Role, value) {
+			return true
+		}
+		for _, member := range binding.Members {
+			if containsFold(member, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// policyGrantsPermission returns true if a role bound in the policy grants permission
+func policyGrantsPermission(policy *cloudresourcemanager.Policy, permission string) bool {
+	for _, binding := range policy.Bindings {
+		if binding == nil {
+			continue
+		}
+		for _, granted := range RolePermissions[binding.Role] {
+			if strings.EqualFold(granted, permission) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// policyHasMemberType returns true if the policy has a member of memberType, such as user or serviceAccount
+func policyHasMemberType(policy *cloudresourcemanager.Policy, memberType string) bool {
+	for _, binding := range policy.Bindings {
+		if binding == nil {
+			continue
+		}
+		for _, member := range binding.Members {
+			if strings.EqualFold(strings.SplitN(member, ":", 2)[0], memberType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasBindings returns true if the policy has at least one binding
+func hasBindings(policy *cloudresourcemanager.Policy) bool {
+	for _, binding := range policy.Bindings {
+		if binding != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// assetPolicy converts a policy to the Cloud Asset type, dropping any nil bindings
+func assetPolicy(policy *cloudresourcemanager.Policy) (*cloudasset.Policy, error) {
+	policy = copyPolicy(policy)
+	bindings := policy.Bindings[:0]
+	for _, binding := range policy.Bindings {
+		if binding != nil {
+			bindings = append(bindings, binding)
+		}
+	}
+	policy.Bindings = bindings
+	converted := &cloudasset.Policy{}
+	if err := convertPolicy(policy, converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// SearchAllResources will take a scope (organizations/x, folders/x or projects/x) and returns a
+// SearchAllResources Call, so we can run a Do() method on it.
+func (r *AssetsService) SearchAllResources(scope string) *AssetsSearchAllResourcesCall {
+	return &AssetsSearchAllResourcesCall{Service: r.Service, Scope: scope}
+}
+
+// AssetsSearchAllResourcesCall is a structure that is returned by Assets.SearchAllResources.  Then we call
+// Do() on it to return the resources found
+type AssetsSearchAllResourcesCall struct {
+	Service    *MockService
+	Scope      string
+	query      string
+	assetTypes []string
+	ctx        context.Context
+}
+
+// Query sets the search query, such as "project:test-project labels.env:prod"
+func (c *AssetsSearchAllResourcesCall) Query(query string) *AssetsSearchAllResourcesCall {
+	c.query = query
+	return c
+}
+
+// AssetTypes limits the search to the given asset types
+func (c *AssetsSearchAllResourcesCall) AssetTypes(assetTypes ...string) *AssetsSearchAllResourcesCall {
+	c.assetTypes = assetTypes
+	return c
+}
+
+// Do will be called on AssetsSearchAllResourcesCall and return the resources in scope matching the query
+func (c *AssetsSearchAllResourcesCall) Do(opts ...googleapi.CallOption) (result *cloudasset.SearchAllResourcesResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Assets.SearchAllResources", c.Scope, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	scoped, err := c.Service.Assets.scopedNodes(c.Scope)
+	if err != nil {
+		return nil, err
+	}
+	response := &cloudasset.SearchAllResourcesResponse{}
+	for _, item := range scoped {
+		if !assetTypeMatches(item.node.AssetType, c.assetTypes) {
+			continue
+		}
+		ok, err := assetQueryMatches(c.query, item)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		result := &cloudasset.ResourceSearchResult{
+			Name:                   item.node.Name,
+			AssetType:              item.node.AssetType,
+			DisplayName:            item.node.DisplayName,
+			Labels:                 copyLabels(item.node.Labels),
+			Folders:                item.folders,
+			Organization:           item.organization,
+			ParentFullResourceName: item.node.Parent,
+			ParentAssetType:        item.parentType,
+			Project:                item.node.Project,
+		}
+		response.Results = append(response.Results, result)
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *AssetsSearchAllResourcesCall) Context(ctx context.Context) *AssetsSearchAllResourcesCall {
+	c.ctx = ctx
+	return c
+}
+
+// SearchAllIamPolicies will take a scope (organizations/x, folders/x or projects/x) and returns a
+// SearchAllIamPolicies Call, so we can run a Do() method on it.
+func (r *AssetsService) SearchAllIamPolicies(scope string) *AssetsSearchAllIamPoliciesCall {
+	return &AssetsSearchAllIamPoliciesCall{Service: r.Service, Scope: scope}
+}
+
+// AssetsSearchAllIamPoliciesCall is a structure that is returned by Assets.SearchAllIamPolicies.  Then we
+// call Do() on it to return the policies found
+type AssetsSearchAllIamPoliciesCall struct {
+	Service    *MockService
+	Scope      string
+	query      string
+	assetTypes []string
+	ctx        context.Context
+}
+
+// Query sets the search query, such as "policy:roles/owner memberTypes:user"
+func (c *AssetsSearchAllIamPoliciesCall) Query(query string) *AssetsSearchAllIamPoliciesCall {
+	c.query = query
+	return c
+}
+
+// AssetTypes limits the search to policies on the given asset types
+func (c *AssetsSearchAllIamPoliciesCall) AssetTypes(assetTypes ...string) *AssetsSearchAllIamPoliciesCall {
+	c.assetTypes = assetTypes
+	return c
+}
+
+// Do will be called on AssetsSearchAllIamPoliciesCall and return the non-empty policies in scope matching
+// the query
+func (c *AssetsSearchAllIamPoliciesCall) Do(opts ...googleapi.CallOption) (result *cloudasset.SearchAllIamPoliciesResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Assets.SearchAllIamPolicies", c.Scope, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	scoped, err := c.Service.Assets.scopedNodes(c.Scope)
+	if err != nil {
+		return nil, err
+	}
+	response := &cloudasset.SearchAllIamPoliciesResponse{}
+	for _, item := range scoped {
+		policy := item.node.Holder.GetIamPolicy()
+		if !hasBindings(policy) || !assetTypeMatches(item.node.AssetType, c.assetTypes) {
+			continue
+		}
+		ok, err := assetQueryMatches(c.query, item)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		converted, err := assetPolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+		response.Results = append(response.Results, &cloudasset.IamPolicySearchResult{
+			Resource:     item.node.Name,
+			AssetType:    item.node.AssetType,
+			Project:      item.node.Project,
+			Folders:      item.folders,
+			Organization: item.organization,
+			Policy:       converted,
+		})
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *AssetsSearchAllIamPoliciesCall) Context(ctx context.Context) *AssetsSearchAllIamPoliciesCall {
+	c.ctx = ctx
+	return c
+}
+
+// ExportAssets will take a parent (organizations/x, folders/x or projects/x) and an exportassetsrequest and
+// returns an ExportAssets Call, so we can run a Do() method on it.
+func (r *AssetsService) ExportAssets(parent string, exportassetsrequest *cloudasset.ExportAssetsRequest) *AssetsExportAssetsCall {
+	return &AssetsExportAssetsCall{Service: r.Service, Parent: parent, Exportassetsrequest: exportassetsrequest}
+}
+
+// AssetsExportAssetsCall is a structure that is returned by Assets.ExportAssets.  Then we call Do() on it to
+// export the assets
+type AssetsExportAssetsCall struct {
+	Service             *MockService
+	Parent              string
+	Exportassetsrequest *cloudasset.ExportAssetsRequest
+	ctx                 context.Context
+}
+
+// Do will be called on AssetsExportAssetsCall to write the assets in the parent as newline delimited JSON
+// to Assets.Exports, under the request's GCS uri.  ContentType RESOURCE (the default) exports the resources
+// and IAM_POLICY exports their non-empty policies.  It returns a finished Operation
+func (c *AssetsExportAssetsCall) Do(opts ...googleapi.CallOption) (result *cloudasset.Operation, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Assets.ExportAssets", c.Parent, c.Exportassetsrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	request := c.Exportassetsrequest
+	if request == nil || request.OutputConfig == nil || request.OutputConfig.GcsDestination == nil || request.OutputConfig.GcsDestination.Uri == "" {
+		return nil, fmt.Errorf("gcs destination uri is required")
+	}
+	contentType := request.ContentType
+	if contentType == "" || contentType == "CONTENT_TYPE_UNSPECIFIED" {
+		contentType = "RESOURCE"
+	}
+	if contentType != "RESOURCE" && contentType != "IAM_POLICY" {
+		return nil, fmt.Errorf("unsupported content type: %v", contentType)
+	}
+	scoped, err := c.Service.Assets.scopedNodes(c.Parent)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	updateTime := c.Service.now().UTC().Format(time.RFC3339Nano)
+	for _, item := range scoped {
+		if !assetTypeMatches(item.node.AssetType, request.AssetTypes) {
+			continue
+		}
+		asset := &cloudasset.Asset{
+			Name:       item.node.Name,
+			AssetType:  item.node.AssetType,
+			Ancestors:  item.ancestors,
+			UpdateTime: updateTime,
+		}
+		if contentType == "IAM_POLICY" {
+			policy := item.node.Holder.GetIamPolicy()
+			if !hasBindings(policy) {
+				continue
+			}
+			if asset.IamPolicy, err = assetPolicy(policy); err != nil {
+				return nil, err
+			}
+		} else {
+			asset.Resource = &cloudasset.Resource{Parent: item.node.Parent}
+		}
+		if err := encoder.Encode(asset); err != nil {
+			return nil, err
+		}
+	}
+	c.Service.Assets.Exports[request.OutputConfig.GcsDestination.Uri] = buf.Bytes()
+	output, err := json.Marshal(request.OutputConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudasset.Operation{Done: true, Response: output}, nil
+}
+
+// Context sets the context used by Do
+func (c *AssetsExportAssetsCall) Context(ctx context.Context) *AssetsExportAssetsCall {
+	c.ctx = ctx
+	return c
+}
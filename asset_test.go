@@ -0,0 +1,131 @@
+package mockgcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	cloudasset "google.golang.org/api/cloudasset/v1"
+)
+
+func newAssetTestService() *MockService {
+	service, _ := NewService(context.TODO())
+	service.Organizations.NewOrganization("organizations/1", "testdomain.co", GeneratePolicy(NewBinding("roles/viewer", "group:auditors@testdomain.co")))
+	folder := service.Folders.NewFolder("folders/2", "prod", nil)
+	folder.Parent = "organizations/1"
+	project := service.Projects.NewProject("projects/test-project", "Test Project", GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co")))
+	project.Parent = "folders/2"
+	service.Projects.NewProject("projects/other-project", "", nil)
+	service.Topics.NewTopic("test-project", "test-topic", GeneratePolicy(NewBinding("roles/pubsub.publisher", "serviceAccount:app@test-project.iam.gserviceaccount.com")))
+	return service
+}
+
+func TestAssets_SearchAllResources_Do(t *testing.T) {
+	service := newAssetTestService()
+
+	t.Run("should return resources in scope with their ancestry", func(t *testing.T) {
+		response, err := service.Assets.SearchAllResources("folders/2").Do()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		want := []string{
+			"//cloudresourcemanager.googleapis.com/folders/2",
+			"//cloudresourcemanager.googleapis.com/projects/test-project",
+			"//pubsub.googleapis.com/projects/test-project/topics/test-topic",
+		}
+		got := []string{}
+		for _, result := range response.Results {
+			got = append(got, result.Name)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+		if response.Results[2].Organization != "organizations/1" {
+			t.Errorf("got %v want %v", response.Results[2].Organization, "organizations/1")
+		}
+	})
+	t.Run("should filter by project and asset type", func(t *testing.T) {
+		response, _ := service.Assets.SearchAllResources("organizations/1").Query("project:test-project").AssetTypes("pubsub.googleapis.com.*").Do()
+
+		if len(response.Results) != 1 || response.Results[0].AssetType != "pubsub.googleapis.com/Topic" {
+			t.Errorf("expected only the topic but got %v", response.Results)
+		}
+	})
+	t.Run("should return err if scope doesn't exist", func(t *testing.T) {
+		_, err := service.Assets.SearchAllResources("projects/missing").Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestAssets_SearchAllIamPolicies_Do(t *testing.T) {
+	service := newAssetTestService()
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"policy:roles/owner", []string{"//cloudresourcemanager.googleapis.com/projects/test-project"}},
+		{"memberTypes:serviceAccount", []string{"//pubsub.googleapis.com/projects/test-project/topics/test-topic"}},
+		{"memberTypes:group", []string{"//cloudresourcemanager.googleapis.com/organizations/1"}},
+		{"policy:alice@testdomain.co project:test-project", []string{"//cloudresourcemanager.googleapis.com/projects/test-project"}},
+		{"policy.role.permissions:pubsub.topics.publish", []string{"//cloudresourcemanager.googleapis.com/projects/test-project", "//pubsub.googleapis.com/projects/test-project/topics/test-topic"}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			response, err := service.Assets.SearchAllIamPolicies("organizations/1").Query(test.query).Do()
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			got := []string{}
+			for _, result := range response.Results {
+				got = append(got, result.Resource)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAssets_ExportAssets_Do(t *testing.T) {
+	service := newAssetTestService()
+	uri := "gs://test-bucket/assets.json"
+
+	_, err := service.Assets.ExportAssets("projects/test-project", &cloudasset.ExportAssetsRequest{
+		ContentType:  "IAM_POLICY",
+		OutputConfig: &cloudasset.OutputConfig{GcsDestination: &cloudasset.GcsDestination{Uri: uri}},
+	}).Do()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	var assets []*cloudasset.Asset
+	scanner := bufio.NewScanner(bytes.NewReader(service.Assets.Exports[uri]))
+	for scanner.Scan() {
+		asset := &cloudasset.Asset{}
+		if err := json.Unmarshal(scanner.Bytes(), asset); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		assets = append(assets, asset)
+	}
+
+	if len(assets) != 2 {
+		t.Fatalf("expected 2 assets but got %v", len(assets))
+	}
+	want := []string{"projects/test-project", "folders/2", "organizations/1"}
+	if !reflect.DeepEqual(assets[0].Ancestors, want) {
+		t.Errorf("got %v want %v", assets[0].Ancestors, want)
+	}
+	if assets[1].IamPolicy.Bindings[0].Role != "roles/pubsub.publisher" {
+		t.Errorf("got %v want %v", assets[1].IamPolicy.Bindings[0].Role, "roles/pubsub.publisher")
+	}
+}
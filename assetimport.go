@@ -0,0 +1,314 @@
+package mockgcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	cloudasset "google.golang.org/api/cloudasset/v1"
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+// AssetImporter seeds a MockService with a real organization's hierarchy and policies, read from the output
+// of `gcloud asset search-all-iam-policies --format=json` or a Cloud Asset export file (newline delimited
+// Assets, like ExportAssets writes).  Organizations, folders and projects are created or updated with their
+// parents and policies.  Other asset types are skipped.
+//
+// Cloud Asset names projects by number, so projects are named projects/{number} unless a RESOURCE export
+// has given their project ID.  Import the RESOURCE export before the policies to get project IDs.  Set
+// Anonymize to replace every member email with a made up one; the same email always gets the same
+// replacement, and Anonymized maps each original member to its replacement
+type AssetImporter struct {
+	Service    *MockService
+	Anonymize  bool
+	Anonymized map[string]string
+
+	projectIDs map[string]string
+	counts     map[string]int
+}
+
+// NewAssetImporter returns an AssetImporter that imports into s
+func NewAssetImporter(s *MockService) *AssetImporter {
+	return &AssetImporter{Service: s, Anonymized: map[string]string{}, projectIDs: map[string]string{}, counts: map[string]int{}}
+}
+
+// ImportFile imports the file at path
+func (i *AssetImporter) ImportFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return i.Import(file)
+}
+
+// Import reads a JSON array or newline delimited JSON of IamPolicySearchResults or Assets and imports them.
+// Field names can be camelCase (gcloud) or snake_case (Cloud Asset exports to GCS)
+func (i *AssetImporter) Import(r io.Reader) error {
+	if i.Anonymized == nil {
+		i.Anonymized = map[string]string{}
+	}
+	if i.projectIDs == nil {
+		i.projectIDs = map[string]string{}
+	}
+	if i.counts == nil {
+		i.counts = map[string]int{}
+	}
+	records, err := readAssetRecords(r)
+	if err != nil {
+		return err
+	}
+	assets := []*cloudasset.Asset{}
+	results := []*cloudasset.IamPolicySearchResult{}
+	for _, record := range records {
+		data, err := json.Marshal(camelKeys(record))
+		if err != nil {
+			return err
+		}
+		if _, ok := record["name"]; ok {
+			asset := &cloudasset.Asset{}
+			if err := json.Unmarshal(data, asset); err != nil {
+				return fmt.Errorf("asset invalid: %v", err)
+			}
+			assets = append(assets, asset)
+			continue
+		}
+		result := &cloudasset.IamPolicySearchResult{}
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("iam policy search result invalid: %v", err)
+		}
+		results = append(results, result)
+	}
+
+	// Project IDs come first, so the policies in the same file land on the right project
+	for _, asset := range assets {
+		if asset.AssetType == "cloudresourcemanager.googleapis.com/Project" && asset.Resource != nil {
+			data := assetResourceData(asset)
+			if id, ok := data["projectId"].(string); ok && id != "" {
+				i.projectIDs[strings.TrimPrefix(asset.Name, "//cloudresourcemanager.googleapis.com/")] = "projects/" + id
+			}
+		}
+	}
+	for _, asset := range assets {
+		if err := i.importAsset(asset); err != nil {
+			return err
+		}
+	}
+	for _, result := range results {
+		if err := i.importSearchResult(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readAssetRecords reads the JSON objects out of a JSON array or newline delimited JSON
+func readAssetRecords(r io.Reader) ([]map[string]interface{}, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	records := []map[string]interface{}{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, fmt.Errorf("asset json invalid: %v", err)
+		}
+		return records, nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		record := map[string]interface{}{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("asset json line %v invalid: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// camelKeys returns value with snake_case object keys turned into camelCase.  Resource data is left alone,
+// as it's already in the resource's REST form
+func camelKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			parts := strings.Split(key, "_")
+			for j := 1; j < len(parts); j++ {
+				if parts[j] != "" {
+					parts[j] = strings.ToUpper(parts[j][:1]) + parts[j][1:]
+				}
+			}
+			if key == "data" {
+				result[key] = item
+			} else {
+				result[strings.Join(parts, "")] = camelKeys(item)
+			}
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for j, item := range v {
+			result[j] = camelKeys(item)
+		}
+		return result
+	}
+	return value
+}
+
+// assetResourceData returns the resource data of an asset, or an empty map if it has none
+func assetResourceData(asset *cloudasset.Asset) map[string]interface{} {
+	data := map[string]interface{}{}
+	if asset.Resource != nil && len(asset.Resource.Data) > 0 {
+		json.Unmarshal(asset.Resource.Data, &data)
+	}
+	return data
+}
+
+// crmName returns the short name (projects/x) of a full resource manager name, with project numbers
+// swapped for IDs when they're known.  It returns "" for other resources
+func (i *AssetImporter) crmName(name string) string {
+	name = strings.TrimPrefix(name, "//cloudresourcemanager.googleapis.com/")
+	if !strings.HasPrefix(name, "projects/") && !strings.HasPrefix(name, "folders/") && !strings.HasPrefix(name, "organizations/") {
+		return ""
+	}
+	if id, ok := i.projectIDs[name]; ok {
+		return id
+	}
+	return name
+}
+
+// importChain creates each resource in chain (nearest first, like Cloud Asset ancestors) with the next one
+// as its parent, and returns the holder of the first
+func (i *AssetImporter) importChain(chain []string) *IamPolicyHolder {
+	var holder *IamPolicyHolder
+	for j := len(chain) - 1; j >= 0; j-- {
+		parent := ""
+		if j+1 < len(chain) && !strings.HasPrefix(chain[j], "organizations/") {
+			parent = chain[j+1]
+		}
+		holder = i.Service.importResource(chain[j], "", parent)
+	}
+	return holder
+}
+
+// importAsset imports an organization, folder or project asset
+func (i *AssetImporter) importAsset(asset *cloudasset.Asset) error {
+	name := i.crmName(asset.Name)
+	if name == "" {
+		return nil
+	}
+	chain := []string{name}
+	for _, ancestor := range asset.Ancestors {
+		if ancestor = i.crmName(ancestor); ancestor != "" && ancestor != name {
+			chain = append(chain, ancestor)
+		}
+	}
+	if len(chain) == 1 && asset.Resource != nil && asset.Resource.Parent != "" {
+		chain = append(chain, i.crmName(asset.Resource.Parent))
+	}
+	holder := i.importChain(chain)
+
+	data := assetResourceData(asset)
+	displayName, _ := data["displayName"].(string)
+	if legacyName, ok := data["name"].(string); ok && displayName == "" && !strings.Contains(legacyName, "/") {
+		displayName = legacyName
+	}
+	if displayName != "" {
+		i.Service.importResource(name, displayName, "")
+	}
+	if asset.IamPolicy != nil {
+		return i.importPolicy(holder, asset.IamPolicy)
+	}
+	return nil
+}
+
+// importSearchResult imports the policy of an organization, folder or project search result
+func (i *AssetImporter) importSearchResult(result *cloudasset.IamPolicySearchResult) error {
+	name := i.crmName(result.Resource)
+	if name == "" {
+		return nil
+	}
+	chain := []string{name}
+	for _, ancestor := range append(append([]string{result.Project}, result.Folders...), result.Organization) {
+		if ancestor = i.crmName(ancestor); ancestor != "" && ancestor != chain[len(chain)-1] && ancestor != name {
+			chain = append(chain, ancestor)
+		}
+	}
+	holder := i.importChain(chain)
+	if result.Policy != nil {
+		return i.importPolicy(holder, result.Policy)
+	}
+	return nil
+}
+
+// importPolicy sets the holder's policy from a Cloud Asset policy, anonymizing members if asked to
+func (i *AssetImporter) importPolicy(holder *IamPolicyHolder, assetPolicy *cloudasset.Policy) error {
+	policy := &cloudresourcemanager.Policy{}
+	if err := convertPolicy(assetPolicy, policy); err != nil {
+		return err
+	}
+	if i.Anonymize {
+		for _, binding := range policy.Bindings {
+			for j, member := range binding.Members {
+				binding.Members[j] = i.anonymize(member)
+			}
+		}
+	}
+	holder.SetIamPolicy(policy)
+	return nil
+}
+
+// anonymize returns the made up member standing in for member.  Members without an email or domain, like
+// allUsers or projectOwner:x, are kept.  Workforce and workload identity principals keep their pool and
+// have the subject, group or attribute value at the end replaced
+func (i *AssetImporter) anonymize(member string) string {
+	if anonymized, ok := i.Anonymized[member]; ok {
+		return anonymized
+	}
+	if strings.HasPrefix(member, "deleted:") {
+		return "deleted:" + i.anonymize(strings.SplitN(strings.TrimPrefix(member, "deleted:"), "?", 2)[0])
+	}
+	parts := strings.SplitN(member, ":", 2)
+	if len(parts) != 2 {
+		return member
+	}
+	kind, value := parts[0], parts[1]
+	var anonymized string
+	switch {
+	case kind == "principal" || kind == "principalSet":
+		slash := strings.LastIndex(value, "/")
+		prefix, last := value[:slash+1], value[slash+1:]
+		if slash <= 0 || last == "" || last == "*" {
+			return member
+		}
+		label := path.Base(strings.TrimSuffix(prefix, "/"))
+		i.counts[label]++
+		anonymized = fmt.Sprintf("%v:%v%v-%d", kind, prefix, label, i.counts[label])
+		if strings.Contains(last, "@") {
+			anonymized += "@example.com"
+		}
+	case kind == "domain":
+		i.counts[kind]++
+		anonymized = fmt.Sprintf("domain:domain-%d.example.com", i.counts[kind])
+	case kind == "serviceAccount" && strings.Contains(value, "@"):
+		i.counts[kind]++
+		anonymized = fmt.Sprintf("serviceAccount:serviceaccount-%d@example.iam.gserviceaccount.com", i.counts[kind])
+	case strings.Contains(value, "@"):
+		i.counts[kind]++
+		anonymized = fmt.Sprintf("%v:%v-%d@example.com", kind, strings.ToLower(kind), i.counts[kind])
+	default:
+		return member
+	}
+	i.Anonymized[member] = anonymized
+	return anonymized
+}
@@ -0,0 +1,114 @@
+package mockgcp
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAssetImporter_ImportFile(t *testing.T) {
+	t.Run("should rebuild the hierarchy and policies", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		importer := NewAssetImporter(service)
+		if err := importer.ImportFile("testdata/asset_export.json"); err != nil {
+			t.Fatal(err)
+		}
+		if err := importer.ImportFile("testdata/iam_policies.json"); err != nil {
+			t.Fatal(err)
+		}
+
+		project := service.Projects.get("projects/prod-project")
+		if project == nil {
+			t.Fatalf("expected the project to be named by its project ID")
+		}
+		if project.Parent != "folders/45" || project.DisplayName != "Prod Project" {
+			t.Errorf("got %v %v", project.Parent, project.DisplayName)
+		}
+		if !BindingContains(PolicyContains(project.GetIamPolicy(), "roles/owner"), "user:alice@corp.example") {
+			t.Errorf("expected alice to be an owner in %v", project.GetIamPolicy().Bindings)
+		}
+		unnamed := service.Projects.get("projects/789")
+		if unnamed == nil || unnamed.Parent != "folders/46" {
+			t.Fatalf("expected projects/789 under folders/46 but got %v", unnamed)
+		}
+		folders := map[string]string{}
+		for _, folder := range service.Folders.FolderList {
+			folders[folder.FolderID] = folder.Parent
+		}
+		if folders["folders/45"] != "organizations/1" || folders["folders/46"] != "folders/45" {
+			t.Errorf("got folder parents %v", folders)
+		}
+		if len(service.Organizations.OrganizationList) != 1 || service.Organizations.OrganizationList[0].Domain != "corp.example" {
+			t.Errorf("got %v", service.Organizations.OrganizationList)
+		}
+		if service.Buckets.find("prod-logs") != nil {
+			t.Errorf("expected other asset types to be skipped")
+		}
+	})
+	t.Run("should answer policy analysis on the imported org", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		if err := NewAssetImporter(service).ImportFile("testdata/iam_policies.json"); err != nil {
+			t.Fatal(err)
+		}
+		results, err := service.AnalyzeIamPolicy(AccessQuery{Resource: "projects/123456", Permissions: []string{"resourcemanager.projects.get"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, result := range results {
+			found = found || (result.Identity == "group:auditors@corp.example" && result.Path.PolicyResource == "//cloudresourcemanager.googleapis.com/folders/45")
+		}
+		if !found {
+			t.Errorf("expected auditors to inherit access from folders/45 in %v", results)
+		}
+	})
+	t.Run("should anonymize member emails consistently", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		importer := NewAssetImporter(service)
+		importer.Anonymize = true
+		if err := importer.ImportFile("testdata/iam_policies.json"); err != nil {
+			t.Fatal(err)
+		}
+		alice := importer.Anonymized["user:alice@corp.example"]
+		if alice == "" || strings.Contains(alice, "corp.example") {
+			t.Fatalf("got %v for alice", alice)
+		}
+		policy := service.Projects.get("projects/123456").GetIamPolicy()
+		if !BindingContains(PolicyContains(policy, "roles/owner"), alice) || !BindingContains(PolicyContains(policy, "roles/editor"), alice) {
+			t.Errorf("expected %v as owner and editor in %v", alice, policy.Bindings)
+		}
+		viewers, _ := PolicyRoleMembers(policy, "roles/viewer")
+		if viewers[0] != "allUsers" || !strings.HasPrefix(viewers[1], "deleted:user:") {
+			t.Errorf("got %v", viewers)
+		}
+		for _, project := range service.Projects.ProjectList {
+			for _, binding := range project.GetIamPolicy().Bindings {
+				for _, member := range binding.Members {
+					if strings.Contains(member, "corp.example") || strings.Contains(member, "prod-project") {
+						t.Errorf("expected %v to be anonymized", member)
+					}
+				}
+			}
+		}
+	})
+	t.Run("should anonymize principals and keep their pool", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		importer := &AssetImporter{Service: service, Anonymize: true}
+		pool := "//iam.googleapis.com/locations/global/workforcePools/corp-pool/"
+		search := fmt.Sprintf(`[{"resource": "//cloudresourcemanager.googleapis.com/projects/123456", "project": "projects/123456",
+			"policy": {"bindings": [{"role": "roles/viewer", "members": ["principal:%[1]vsubject/alice@corp.example",
+			"principalSet:%[1]vgroup/admins", "principalSet:%[1]v*"]}]}}]`, pool)
+		if err := importer.Import(strings.NewReader(search)); err != nil {
+			t.Fatal(err)
+		}
+
+		got, _ := PolicyRoleMembers(service.Projects.get("projects/123456").GetIamPolicy(), "roles/viewer")
+		want := []string{"principal:" + pool + "subject/subject-1@example.com", "principalSet:" + pool + "group/group-1", "principalSet:" + pool + "*"}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+}
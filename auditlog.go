@@ -0,0 +1,224 @@
+package mockgcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	logging "google.golang.org/api/logging/v2"
+)
+
+// auditLogID is the log Admin Activity audit logs are written to, url encoded the way it appears in LogName
+const auditLogID = "cloudaudit.googleapis.com%2Factivity"
+
+// AuditLog is the protoPayload of a Cloud Audit Log entry (type.googleapis.com/google.cloud.audit.AuditLog).
+// ServiceData carries the policy delta of SetIamPolicy calls
+type AuditLog struct {
+	Type               string                   `json:"@type"`
+	ServiceName        string                   `json:"serviceName"`
+	MethodName         string                   `json:"methodName"`
+	ResourceName       string                   `json:"resourceName"`
+	AuthenticationInfo *AuditAuthenticationInfo `json:"authenticationInfo,omitempty"`
+	ServiceData        *AuditData               `json:"serviceData,omitempty"`
+}
+
+// AuditAuthenticationInfo is who made the call that was logged
+type AuditAuthenticationInfo struct {
+	PrincipalEmail string `json:"principalEmail,omitempty"`
+}
+
+// AuditData is the IAM serviceData of an audit log (type.googleapis.com/google.iam.v1.logging.AuditData)
+type AuditData struct {
+	Type        string       `json:"@type"`
+	PolicyDelta *PolicyDelta `json:"policyDelta,omitempty"`
+}
+
+// AuditLogFilter picks audit log entries out of an AuditLogsService.  ResourceName matches the start of the
+// entry's resource name, LogName matches the start of the entry's log name (projects/x), and Since and
+// Until bound the entry's timestamp.  Empty fields match everything
+type AuditLogFilter struct {
+	ServiceName    string
+	MethodName     string
+	ResourceName   string
+	PrincipalEmail string
+	LogName        string
+	Since          time.Time
+	Until          time.Time
+}
+
+// matches returns true if an entry with payload passes the filter
+func (f AuditLogFilter) matches(entry *logging.LogEntry, payload *AuditLog) bool {
+	if f.ServiceName != "" && payload.ServiceName != f.ServiceName {
+		return false
+	}
+	if f.MethodName != "" && payload.MethodName != f.MethodName {
+		return false
+	}
+	if !strings.HasPrefix(payload.ResourceName, f.ResourceName) || !strings.HasPrefix(entry.LogName, f.LogName) {
+		return false
+	}
+	if f.PrincipalEmail != "" && (payload.AuthenticationInfo == nil || payload.AuthenticationInfo.PrincipalEmail != f.PrincipalEmail) {
+		return false
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+	if err != nil {
+		return f.Since.IsZero() && f.Until.IsZero()
+	}
+	if !f.Since.IsZero() && timestamp.Before(f.Since) {
+		return false
+	}
+	return f.Until.IsZero() || !timestamp.After(f.Until)
+}
+
+// AuditLogsService holds the Admin Activity audit logs written by every mutating call made through the
+// mock's API (Create, Delete, SetIamPolicy and the like).  Resources made with the NewX helpers are test
+// fixtures and aren't logged.  Cloud Identity calls aren't logged either, as GCP writes those to the
+// Workspace audit logs instead
+type AuditLogsService struct {
+	Service *MockService
+	Entries []*logging.LogEntry
+}
+
+// NewAuditLogsService returns a AuditLogsService with no entries
+func NewAuditLogsService(s *MockService) *AuditLogsService {
+	rs := &AuditLogsService{Service: s}
+	return rs
+}
+
+// AuditLogPayload decodes the AuditLog in an entry's protoPayload
+func AuditLogPayload(entry *logging.LogEntry) (*AuditLog, error) {
+	payload := &AuditLog{}
+	if err := json.Unmarshal(entry.ProtoPayload, payload); err != nil {
+		return nil, fmt.Errorf("audit log payload invalid: %v", err)
+	}
+	return payload, nil
+}
+
+// Query returns the entries that pass filter, oldest first
+func (r *AuditLogsService) Query(filter AuditLogFilter) []*logging.LogEntry {
+	entries := []*logging.LogEntry{}
+	for _, entry := range r.Entries {
+		payload, err := AuditLogPayload(entry)
+		if err != nil {
+			continue
+		}
+		if filter.matches(entry, payload) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Export writes the entries that pass filter to w as newline delimited JSON, one LogEntry per line, the
+// format a Cloud Logging export to Cloud Storage or `gcloud logging read --format=json` gives
+func (r *AuditLogsService) Export(w io.Writer, filter AuditLogFilter) error {
+	for _, entry := range r.Query(filter) {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record appends an audit log entry for a call.  logParent is the project, folder or organization the entry
+// is written to, and delta is the policy delta of a SetIamPolicy call, or nil
+func (r *AuditLogsService) record(serviceName, methodName, resourceName, logParent string, delta *PolicyDelta) {
+	payload := &AuditLog{
+		Type:               "type.googleapis.com/google.cloud.audit.AuditLog",
+		ServiceName:        serviceName,
+		MethodName:         methodName,
+		ResourceName:       resourceName,
+		AuthenticationInfo: &AuditAuthenticationInfo{PrincipalEmail: memberEmail(r.Service.Caller)},
+	}
+	if delta != nil {
+		payload.ServiceData = &AuditData{Type: "type.googleapis.com/google.iam.v1.logging.AuditData", PolicyDelta: delta}
+	}
+	protoPayload, _ := json.Marshal(payload)
+	now := r.Service.now().UTC().Format(time.RFC3339Nano)
+	r.Entries = append(r.Entries, &logging.LogEntry{
+		InsertId:         fmt.Sprintf("%d", len(r.Entries)+1),
+		LogName:          logParent + "/logs/" + auditLogID,
+		ProtoPayload:     protoPayload,
+		Resource:         auditResource(serviceName, resourceName, logParent),
+		Severity:         "NOTICE",
+		Timestamp:        now,
+		ReceiveTimestamp: now,
+	})
+}
+
+// recordMutation appends an audit log entry for a call that changes a resource under a project, which is
+// where the entry is written
+func (r *AuditLogsService) recordMutation(serviceName, methodName, resourceName string) {
+	r.record(serviceName, methodName, resourceName, projectOf(resourceName), nil)
+}
+
+// auditResource returns the monitored resource an audit log entry is about
+func auditResource(serviceName, resourceName, logParent string) *logging.MonitoredResource {
+	labels := map[string]string{}
+	parts := strings.SplitN(logParent, "/", 2)
+	if len(parts) == 2 {
+		labels[strings.TrimSuffix(parts[0], "s")+"_id"] = parts[1]
+	}
+	resourceType := "audited_resource"
+	switch {
+	case serviceName == "cloudresourcemanager.googleapis.com":
+		resourceType = strings.TrimSuffix(parts[0], "s")
+	case serviceName == "iam.googleapis.com":
+		resourceType = "service_account"
+	case serviceName == "storage.googleapis.com":
+		resourceType = "gcs_bucket"
+		labels["bucket_name"] = strings.TrimPrefix(resourceName, "projects/_/buckets/")
+	case serviceName == "pubsub.googleapis.com" && strings.Contains(resourceName, "/subscriptions/"):
+		resourceType = "pubsub_subscription"
+	case serviceName == "pubsub.googleapis.com":
+		resourceType = "pubsub_topic"
+	default:
+		labels["service"] = serviceName
+	}
+	return &logging.MonitoredResource{Type: resourceType, Labels: labels}
+}
+
+// setIamPolicyMethods is the audit log methodName GCP uses for SetIamPolicy on each asset type
+var setIamPolicyMethods = map[string]string{
+	"cloudresourcemanager.googleapis.com/Organization": "SetIamPolicy",
+	"cloudresourcemanager.googleapis.com/Folder":       "SetIamPolicy",
+	"cloudresourcemanager.googleapis.com/Project":      "SetIamPolicy",
+	"iam.googleapis.com/ServiceAccount":                "google.iam.admin.v1.SetIAMPolicy",
+	"storage.googleapis.com/Bucket":                    "storage.setIamPermissions",
+	"pubsub.googleapis.com/Topic":                      "google.iam.v1.IAMPolicy.SetIamPolicy",
+	"pubsub.googleapis.com/Subscription":               "google.iam.v1.IAMPolicy.SetIamPolicy",
+	"secretmanager.googleapis.com/Secret":              "google.cloud.secretmanager.v1.SecretManagerService.SetIamPolicy",
+	"cloudkms.googleapis.com/KeyRing":                  "google.iam.v1.IAMPolicy.SetIamPolicy",
+	"cloudkms.googleapis.com/CryptoKey":                "google.iam.v1.IAMPolicy.SetIamPolicy",
+}
+
+// bucketAuditName returns the resource name storage audit logs use for a bucket
+func bucketAuditName(bucket string) string {
+	return "projects/_/buckets/" + bucket
+}
+
+// auditSetIamPolicy records the SetIamPolicy audit log for the resource holding holder, with the delta
+// between before and after
+func (s *MockService) auditSetIamPolicy(holder *IamPolicyHolder, before, after *cloudresourcemanager.Policy) {
+	for _, node := range s.resourceNodes() {
+		if node.Holder != holder {
+			continue
+		}
+		resourceName, logParent := node.shortName(), node.shortName()
+		if node.Project != "" {
+			logParent = node.Project
+		}
+		if node.AssetType == "storage.googleapis.com/Bucket" {
+			resourceName = bucketAuditName(resourceName)
+		}
+		s.AuditLogs.record(node.service(), setIamPolicyMethods[node.AssetType], resourceName, logParent, DiffPolicies(before, after))
+		return
+	}
+}
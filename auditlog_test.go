@@ -0,0 +1,129 @@
+package mockgcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	logging "google.golang.org/api/logging/v2"
+	"google.golang.org/api/pubsub/v1"
+	"google.golang.org/api/storage/v1"
+)
+
+func TestAuditLogsService_SetIamPolicy(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Caller = "user:alice@testdomain.co"
+	service.Projects.NewProject("projects/test-project", "", GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co")))
+
+	service.Projects.SetIamPolicy("projects/test-project", &cloudresourcemanager.SetIamPolicyRequest{
+		Policy: GeneratePolicy(NewBinding("roles/owner", "user:bob@testdomain.co")),
+	}).Do()
+
+	entries := service.AuditLogs.Query(AuditLogFilter{MethodName: "SetIamPolicy"})
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry but got %v", len(entries))
+	}
+	if entries[0].LogName != "projects/test-project/logs/cloudaudit.googleapis.com%2Factivity" {
+		t.Errorf("got %v want %v", entries[0].LogName, "projects/test-project/logs/cloudaudit.googleapis.com%2Factivity")
+	}
+	payload, err := AuditLogPayload(entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.ResourceName != "projects/test-project" || payload.AuthenticationInfo.PrincipalEmail != "alice@testdomain.co" {
+		t.Errorf("got %v %v", payload.ResourceName, payload.AuthenticationInfo.PrincipalEmail)
+	}
+	want := []*BindingDelta{
+		{Action: "REMOVE", Role: "roles/owner", Member: "user:alice@testdomain.co"},
+		{Action: "ADD", Role: "roles/owner", Member: "user:bob@testdomain.co"},
+	}
+	got := payload.ServiceData.PolicyDelta.BindingDeltas
+	if len(got) != len(want) {
+		t.Fatalf("got %v deltas want %v", len(got), len(want))
+	}
+	for i := range want {
+		if *got[i] != *want[i] {
+			t.Errorf("got %v want %v", got[i], want[i])
+		}
+	}
+}
+
+func TestAuditLogsService_Query(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject("projects/test-project", "", nil)
+	service.Topics.NewTopic("test-project", "fixture-topic", nil)
+
+	service.Caller = "user:alice@testdomain.co"
+	service.Topics.Create("projects/test-project/topics/test-topic", &pubsub.Topic{}).Do()
+	service.Topics.SetIamPolicy("projects/test-project/topics/test-topic", &pubsub.SetIamPolicyRequest{
+		Policy: &pubsub.Policy{Bindings: []*pubsub.Binding{{Role: "roles/pubsub.publisher", Members: []string{"allUsers"}}}},
+	}).Do()
+	service.Caller = "serviceAccount:deployer@test-project.iam.gserviceaccount.com"
+	service.Buckets.Insert("test-project", &storage.Bucket{Name: "test-bucket"}).Do()
+	service.Buckets.Delete("test-bucket").Do()
+	service.Topics.Delete("projects/test-project/topics/missing-topic").Do()
+
+	t.Run("should only log successful calls made through the API", func(t *testing.T) {
+		if len(service.AuditLogs.Entries) != 4 {
+			t.Errorf("got %v want %v", len(service.AuditLogs.Entries), 4)
+		}
+	})
+	tests := []struct {
+		name   string
+		filter AuditLogFilter
+		want   []string
+	}{
+		{"should match every entry with an empty filter", AuditLogFilter{}, []string{"google.pubsub.v1.Publisher.CreateTopic", "google.iam.v1.IAMPolicy.SetIamPolicy", "storage.buckets.create", "storage.buckets.delete"}},
+		{"should filter by principal", AuditLogFilter{PrincipalEmail: "deployer@test-project.iam.gserviceaccount.com"}, []string{"storage.buckets.create", "storage.buckets.delete"}},
+		{"should filter by service", AuditLogFilter{ServiceName: "pubsub.googleapis.com"}, []string{"google.pubsub.v1.Publisher.CreateTopic", "google.iam.v1.IAMPolicy.SetIamPolicy"}},
+		{"should filter by resource name prefix", AuditLogFilter{ResourceName: "projects/_/buckets/"}, []string{"storage.buckets.create", "storage.buckets.delete"}},
+		{"should filter by method", AuditLogFilter{MethodName: "storage.buckets.delete"}, []string{"storage.buckets.delete"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, entry := range service.AuditLogs.Query(tt.filter) {
+				payload, _ := AuditLogPayload(entry)
+				got = append(got, payload.MethodName)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditLogsService_Export(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject("projects/test-project", "", nil)
+	service.Buckets.Insert("test-project", &storage.Bucket{Name: "test-bucket"}).Do()
+	service.Buckets.SetIamPolicy("test-bucket", &storage.Policy{
+		Bindings: []*storage.PolicyBindings{{Role: "roles/storage.objectViewer", Members: []string{"allUsers"}}},
+	}).Do()
+
+	var buffer bytes.Buffer
+	if err := service.AuditLogs.Export(&buffer, AuditLogFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines but got %v", len(lines))
+	}
+	entry := &logging.LogEntry{}
+	if err := json.Unmarshal([]byte(lines[1]), entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Resource.Type != "gcs_bucket" || entry.Resource.Labels["bucket_name"] != "test-bucket" {
+		t.Errorf("got %v %v", entry.Resource.Type, entry.Resource.Labels)
+	}
+	payload, _ := AuditLogPayload(entry)
+	if payload.MethodName != "storage.setIamPermissions" || payload.ResourceName != "projects/_/buckets/test-bucket" {
+		t.Errorf("got %v %v", payload.MethodName, payload.ResourceName)
+	}
+	if !strings.Contains(lines[1], `{"action":"ADD","role":"roles/storage.objectViewer","member":"allUsers"}`) {
+		t.Errorf("expected a policy delta adding allUsers in %v", lines[1])
+	}
+}
@@ -0,0 +1,253 @@
+package mockgcp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Call is a record of a single Do() call made through the mock.  Method is the service and method it was
+// made on (Projects.SetIamPolicy), Resource the resource name it was made with, and Request a deep copy of
+// the request body it was given (such as the *cloudresourcemanager.SetIamPolicyRequest), if the method
+// takes one, so changing the request after the call doesn't change the record.
+// Response and Err are what the call returned, and Caller and Time are the service's Caller and Clock when
+// it was made
+type Call struct {
+	Method   string
+	Resource string
+	Request  interface{}
+	Response interface{}
+	Err      error
+	Caller   string
+	Time     time.Time
+
+	calls *CallsService
+}
+
+// end records what the call returned
+func (c *Call) end(response interface{}, err error) {
+	c.calls.mu.Lock()
+	defer c.calls.mu.Unlock()
+	if err != nil {
+		response = nil
+	}
+	c.Response = response
+	c.Err = err
+}
+
+// String describes the call for test failures
+func (c *Call) String() string {
+	if c.Err != nil {
+		return fmt.Sprintf("%v(%v) by %v failed: %v", c.Method, c.Resource, c.Caller, c.Err)
+	}
+	return fmt.Sprintf("%v(%v) by %v", c.Method, c.Resource, c.Caller)
+}
+
+// ExpectedCall picks calls out of the CallsService.  Method and Resource are path.Match patterns, the same
+// as a Fault's, so Projects.* matches every project call and organizations/* every call on an
+// organization.  If Request is set the call's request has to be reflect.DeepEqual to it.  Empty fields
+// match everything
+type ExpectedCall struct {
+	Method   string
+	Resource string
+	Request  interface{}
+}
+
+// matches returns true if call is one of the expected calls
+func (e ExpectedCall) matches(call *Call) bool {
+	if e.Method != "" {
+		if ok, _ := path.Match(e.Method, call.Method); !ok {
+			return false
+		}
+	}
+	if e.Resource != "" {
+		if ok, _ := path.Match(e.Resource, call.Resource); !ok {
+			return false
+		}
+	}
+	return e.Request == nil || reflect.DeepEqual(e.Request, call.Request)
+}
+
+// String describes the expected call for test failures
+func (e ExpectedCall) String() string {
+	method, resource := e.Method, e.Resource
+	if method == "" {
+		method = "*"
+	}
+	if resource == "" {
+		resource = "*"
+	}
+	return fmt.Sprintf("%v(%v)", method, resource)
+}
+
+// CallsService records every Do() call made through the mock, whether it went through the Go API or the
+// REST Handler, so tests can check what the code under test did
+type CallsService struct {
+	Service *MockService
+
+	mu    sync.Mutex
+	calls []*Call
+}
+
+// NewCallsService returns a CallsService with no calls recorded
+func NewCallsService(s *MockService) *CallsService {
+	rs := &CallsService{Service: s}
+	return rs
+}
+
+// All returns every recorded call, in the order they were made
+func (r *CallsService) All() []*Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Call(nil), r.calls...)
+}
+
+// Find returns the recorded calls that match expected, in the order they were made
+func (r *CallsService) Find(expected ExpectedCall) []*Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	found := []*Call{}
+	for _, call := range r.calls {
+		if expected.matches(call) {
+			found = append(found, call)
+		}
+	}
+	return found
+}
+
+// Reset forgets every recorded call
+func (r *CallsService) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+// ExpectCall fails the test unless exactly one call matches expected, and returns that call (or nil)
+func (r *CallsService) ExpectCall(t TestingT, expected ExpectedCall) *Call {
+	t.Helper()
+	calls := r.ExpectCalls(t, 1, expected)
+	if len(calls) != 1 {
+		return nil
+	}
+	return calls[0]
+}
+
+// ExpectCalls fails the test unless exactly count calls match expected, and returns the calls that did
+func (r *CallsService) ExpectCalls(t TestingT, count int, expected ExpectedCall) []*Call {
+	t.Helper()
+	calls := r.Find(expected)
+	if len(calls) != count {
+		t.Errorf("got %v calls to %v want %v: %v", len(calls), expected, count, calls)
+	}
+	return calls
+}
+
+// ExpectNoCalls fails the test if any call matches expected
+func (r *CallsService) ExpectNoCalls(t TestingT, expected ExpectedCall) {
+	t.Helper()
+	if calls := r.Find(expected); len(calls) > 0 {
+		t.Errorf("got calls to %v want none: %v", expected, calls)
+	}
+}
+
+// ExpectInOrder fails the test unless calls matching each of expected were made in that order.  Other
+// calls can come before, between and after them
+func (r *CallsService) ExpectInOrder(t TestingT, expected ...ExpectedCall) {
+	t.Helper()
+	next := 0
+	for _, call := range r.All() {
+		if next < len(expected) && expected[next].matches(call) {
+			next++
+		}
+	}
+	if next < len(expected) {
+		t.Errorf("got no call to %v after %v in %v", expected[next], expected[:next], r.All())
+	}
+}
+
+// beginCall is run at the start of every Do() call.  It records the call, and returns the error the call
+// should fail with before it touches any state, from a scripted fault or a quota it's over.  A call whose
+// context is done fails with the context's error, the way the google clients return context.Canceled
+// and context.DeadlineExceeded, and that includes a context that ends while injected latency is holding
+// the call up.  Do() hands what it returns to the call's end
+func (s *MockService) beginCall(ctx context.Context, method, resource string, request interface{}) (*Call, error) {
+	call := &Call{Method: method, Resource: resource, Request: copyRequest(request), Caller: s.Caller, Time: s.now(), calls: s.Calls}
+	s.Calls.mu.Lock()
+	s.Calls.calls = append(s.Calls.calls, call)
+	s.Calls.mu.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return call, err
+	}
+	latency, err := s.Faults.check(method, resource)
+	if latency > 0 {
+		select {
+		case <-ctx.Done():
+			return call, ctx.Err()
+		case <-s.after(latency):
+		}
+	}
+	if err != nil {
+		return call, err
+	}
+	return call, s.Quotas.take(method, resource)
+}
+
+// copyRequest returns a deep copy of a request body.  Exported fields are copied all the way down, and
+// unexported ones are copied as they are
+func copyRequest(request interface{}) interface{} {
+	if request == nil {
+		return nil
+	}
+	return copyValue(reflect.ValueOf(request)).Interface()
+}
+
+// copyValue returns a deep copy of v, for copyRequest
+func copyValue(v reflect.Value) reflect.Value {
+	result := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return result
+		}
+		result.Set(reflect.New(v.Type().Elem()))
+		result.Elem().Set(copyValue(v.Elem()))
+	case reflect.Interface:
+		if v.IsNil() {
+			return result
+		}
+		result.Set(copyValue(v.Elem()))
+	case reflect.Struct:
+		result.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if result.Field(i).CanSet() {
+				result.Field(i).Set(copyValue(v.Field(i)))
+			}
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return result
+		}
+		result.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			result.Index(i).Set(copyValue(v.Index(i)))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return result
+		}
+		result.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		for _, key := range v.MapKeys() {
+			result.SetMapIndex(key, copyValue(v.MapIndex(key)))
+		}
+	default:
+		result.Set(v)
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package mockgcp
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+func TestCallsService(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Organizations.NewOrganization("organizations/1", "testdomain.co", nil)
+	service.Projects.NewProject("projects/test-project", "", nil)
+	service.Caller = "user:alice@testdomain.co"
+	request := &cloudresourcemanager.SetIamPolicyRequest{Policy: GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co"))}
+	service.Projects.GetIamPolicy("projects/test-project", nil).Do()
+	service.Projects.SetIamPolicy("projects/test-project", request).Do()
+	service.Projects.GetIamPolicy("projects/missing-project", nil).Do()
+	service.Buckets.Delete("missing-bucket").Do()
+
+	t.Run("should record the request, response and caller", func(t *testing.T) {
+		call := service.Calls.ExpectCall(t, ExpectedCall{Method: "Projects.SetIamPolicy", Resource: "projects/test-project", Request: request})
+		if call == nil {
+			t.FailNow()
+		}
+		policy, ok := call.Response.(*cloudresourcemanager.Policy)
+		if !ok || !BindingContains(PolicyContains(policy, "roles/owner"), "user:alice@testdomain.co") {
+			t.Errorf("got %v want the policy that was set", call.Response)
+		}
+		if call.Caller != "user:alice@testdomain.co" || call.Time.IsZero() || call.Err != nil {
+			t.Errorf("got %v", call)
+		}
+	})
+	t.Run("should record a copy of the request", func(t *testing.T) {
+		mutated := &cloudresourcemanager.SetIamPolicyRequest{Policy: GeneratePolicy(NewBinding("roles/viewer", "user:bob@testdomain.co"))}
+		service.Projects.SetIamPolicy("projects/test-project", mutated).Do()
+		mutated.Policy.Bindings[0].Members[0] = "user:mallory@testdomain.co"
+
+		call := service.Calls.ExpectCall(t, ExpectedCall{Method: "Projects.SetIamPolicy", Request: &cloudresourcemanager.SetIamPolicyRequest{
+			Policy: GeneratePolicy(NewBinding("roles/viewer", "user:bob@testdomain.co")),
+		}})
+		if call != nil && call.Request == mutated {
+			t.Errorf("got the caller's request want a copy")
+		}
+	})
+	t.Run("should record errors", func(t *testing.T) {
+		calls := append(service.Calls.Find(ExpectedCall{Resource: "projects/missing-*"}), service.Calls.Find(ExpectedCall{Resource: "missing-*"})...)
+		if len(calls) != 2 || calls[0].Err == nil || calls[1].Err == nil || calls[0].Response != nil {
+			t.Errorf("got %v want two failed calls", calls)
+		}
+	})
+	t.Run("should pass the expectations that hold", func(t *testing.T) {
+		service.Calls.ExpectCalls(t, 2, ExpectedCall{Method: "Projects.GetIamPolicy"})
+		service.Calls.ExpectNoCalls(t, ExpectedCall{Resource: "organizations/*"})
+		service.Calls.ExpectInOrder(t,
+			ExpectedCall{Method: "Projects.GetIamPolicy", Resource: "projects/test-project"},
+			ExpectedCall{Method: "Projects.SetIamPolicy"},
+			ExpectedCall{Method: "Buckets.Delete"},
+		)
+	})
+	t.Run("should fail the expectations that don't", func(t *testing.T) {
+		fake := &fakeT{}
+		service.Calls.ExpectCall(fake, ExpectedCall{Method: "Projects.GetIamPolicy"})
+		service.Calls.ExpectNoCalls(fake, ExpectedCall{Method: "Projects.SetIamPolicy"})
+		service.Calls.ExpectInOrder(fake, ExpectedCall{Method: "Projects.SetIamPolicy"}, ExpectedCall{Method: "Projects.GetIamPolicy", Resource: "projects/test-project"})
+		service.Calls.ExpectCall(fake, ExpectedCall{Method: "Projects.SetIamPolicy", Request: &cloudresourcemanager.SetIamPolicyRequest{}})
+		if len(fake.errors) != 4 {
+			t.Errorf("got %v want 4 failures", fake.errors)
+		}
+	})
+	t.Run("should record calls made through the handler", func(t *testing.T) {
+		service.Calls.Reset()
+		servedClient(t, service).Projects.Get("projects/test-project").Do()
+		service.Calls.ExpectCall(t, ExpectedCall{Method: "Projects.Get", Resource: "projects/test-project"})
+	})
+}
@@ -0,0 +1,105 @@
+package mockgcp
+
+import (
+	"context"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	option "google.golang.org/api/option"
+)
+
+// ClientItf is the interface GCPClient matches, so code can take a ClientItf and be handed the mock
+// GCPClient in tests and a RealClient in production
+type ClientItf interface {
+	ProjectSetIamPolicy(resource string, setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest) PolicyCallItf
+	ProjectGetIamPolicy(resource string, getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest) PolicyCallItf
+	ProjectsSearch() *cloudresourcemanager.ProjectsSearchCall
+	ProjectsGet(name string) *cloudresourcemanager.ProjectsGetCall
+	ProjectsDelete(name string) *cloudresourcemanager.ProjectsDeleteCall
+	ProjectsUndelete(name string, undeleteprojectrequest *cloudresourcemanager.UndeleteProjectRequest) *cloudresourcemanager.ProjectsUndeleteCall
+	FolderSetIamPolicy(resource string, setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest) PolicyCallItf
+	FolderGetIamPolicy(resource string, getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest) PolicyCallItf
+	FoldersSearch() *cloudresourcemanager.FoldersSearchCall
+	OrganizationSetIamPolicy(resource string, setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest) PolicyCallItf
+	OrganizationGetIamPolicy(resource string, getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest) PolicyCallItf
+	OrganizationsSearch() *cloudresourcemanager.OrganizationsSearchCall
+}
+
+var (
+	_ ClientItf = (*GCPClient)(nil)
+	_ ClientItf = (*RealClient)(nil)
+)
+
+// RealClient is the production ClientItf, wrapping the methods of a real cloud resource manager service
+// the same way GCPClient wraps the mock's
+type RealClient struct {
+	Service *cloudresourcemanager.Service
+}
+
+// NewRealClient returns a RealClient around a cloud resource manager service made with opts
+func NewRealClient(ctx context.Context, opts ...option.ClientOption) (*RealClient, error) {
+	service, err := cloudresourcemanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &RealClient{Service: service}, nil
+}
+
+// ProjectSetIamPolicy is a wrapper for the Projects.SetIamPolicy method
+func (client *RealClient) ProjectSetIamPolicy(resource string, setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest) PolicyCallItf {
+	return client.Service.Projects.SetIamPolicy(resource, setiampolicyrequest)
+}
+
+// ProjectGetIamPolicy is a wrapper for the Projects.GetIamPolicy method
+func (client *RealClient) ProjectGetIamPolicy(resource string, getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest) PolicyCallItf {
+	return client.Service.Projects.GetIamPolicy(resource, getiampolicyrequest)
+}
+
+// ProjectsSearch is a wrapper for the Projects.Search method
+func (client *RealClient) ProjectsSearch() *cloudresourcemanager.ProjectsSearchCall {
+	return client.Service.Projects.Search()
+}
+
+// ProjectsGet is a wrapper for the Projects.Get method
+func (client *RealClient) ProjectsGet(name string) *cloudresourcemanager.ProjectsGetCall {
+	return client.Service.Projects.Get(name)
+}
+
+// ProjectsDelete is a wrapper for the Projects.Delete method
+func (client *RealClient) ProjectsDelete(name string) *cloudresourcemanager.ProjectsDeleteCall {
+	return client.Service.Projects.Delete(name)
+}
+
+// ProjectsUndelete is a wrapper for the Projects.Undelete method
+func (client *RealClient) ProjectsUndelete(name string, undeleteprojectrequest *cloudresourcemanager.UndeleteProjectRequest) *cloudresourcemanager.ProjectsUndeleteCall {
+	return client.Service.Projects.Undelete(name, undeleteprojectrequest)
+}
+
+// FolderSetIamPolicy is a wrapper for the Folders.SetIamPolicy method
+func (client *RealClient) FolderSetIamPolicy(resource string, setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest) PolicyCallItf {
+	return client.Service.Folders.SetIamPolicy(resource, setiampolicyrequest)
+}
+
+// FolderGetIamPolicy is a wrapper for the Folders.GetIamPolicy method
+func (client *RealClient) FolderGetIamPolicy(resource string, getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest) PolicyCallItf {
+	return client.Service.Folders.GetIamPolicy(resource, getiampolicyrequest)
+}
+
+// FoldersSearch is a wrapper for the Folders.Search method
+func (client *RealClient) FoldersSearch() *cloudresourcemanager.FoldersSearchCall {
+	return client.Service.Folders.Search()
+}
+
+// OrganizationSetIamPolicy is a wrapper for the Organizations.SetIamPolicy method
+func (client *RealClient) OrganizationSetIamPolicy(resource string, setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest) PolicyCallItf {
+	return client.Service.Organizations.SetIamPolicy(resource, setiampolicyrequest)
+}
+
+// OrganizationGetIamPolicy is a wrapper for the Organizations.GetIamPolicy method
+func (client *RealClient) OrganizationGetIamPolicy(resource string, getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest) PolicyCallItf {
+	return client.Service.Organizations.GetIamPolicy(resource, getiampolicyrequest)
+}
+
+// OrganizationsSearch is a wrapper for the Organizations.Search method
+func (client *RealClient) OrganizationsSearch() *cloudresourcemanager.OrganizationsSearchCall {
+	return client.Service.Organizations.Search()
+}
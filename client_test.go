@@ -0,0 +1,95 @@
+package mockgcp
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	option "google.golang.org/api/option"
+)
+
+// seededService returns a service with an organization, folder and project the client suite works on
+func seededService() *MockService {
+	service, _ := NewService(context.TODO())
+	service.Organizations.NewOrganization("organizations/1", "testdomain.co", GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co")))
+	service.Folders.NewFolder("folders/2", "Test Folder", GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co"))).Parent = "organizations/1"
+	service.Projects.NewProject("projects/test-project", "Test Project", GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co"))).Parent = "folders/2"
+	return service
+}
+
+// testClient runs the same checks against any ClientItf over a seededService
+func testClient(t *testing.T, client ClientItf) {
+	resources := []struct {
+		name string
+		get  func(string) PolicyCallItf
+		set  func(string, *cloudresourcemanager.Policy) PolicyCallItf
+	}{
+		{"projects/test-project",
+			func(r string) PolicyCallItf {
+				return client.ProjectGetIamPolicy(r, &cloudresourcemanager.GetIamPolicyRequest{})
+			},
+			func(r string, p *cloudresourcemanager.Policy) PolicyCallItf {
+				return client.ProjectSetIamPolicy(r, &cloudresourcemanager.SetIamPolicyRequest{Policy: p})
+			}},
+		{"folders/2",
+			func(r string) PolicyCallItf {
+				return client.FolderGetIamPolicy(r, &cloudresourcemanager.GetIamPolicyRequest{})
+			},
+			func(r string, p *cloudresourcemanager.Policy) PolicyCallItf {
+				return client.FolderSetIamPolicy(r, &cloudresourcemanager.SetIamPolicyRequest{Policy: p})
+			}},
+		{"organizations/1",
+			func(r string) PolicyCallItf {
+				return client.OrganizationGetIamPolicy(r, &cloudresourcemanager.GetIamPolicyRequest{})
+			},
+			func(r string, p *cloudresourcemanager.Policy) PolicyCallItf {
+				return client.OrganizationSetIamPolicy(r, &cloudresourcemanager.SetIamPolicyRequest{Policy: p})
+			}},
+	}
+	for _, resource := range resources {
+		t.Run("should get and set the policy of "+resource.name, func(t *testing.T) {
+			policy, err := resource.get(resource.name).Do()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !BindingContains(PolicyContains(policy, "roles/owner"), "user:alice@testdomain.co") {
+				t.Errorf("got %v want alice as owner", policy.Bindings)
+			}
+			policy.Bindings = append(policy.Bindings, NewBinding("roles/viewer", "user:bob@testdomain.co"))
+			if _, err := resource.set(resource.name, policy).Do(); err != nil {
+				t.Fatal(err)
+			}
+			policy, err = resource.get(resource.name).Do()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !BindingContains(PolicyContains(policy, "roles/viewer"), "user:bob@testdomain.co") {
+				t.Errorf("got %v want bob as viewer", policy.Bindings)
+			}
+		})
+	}
+	t.Run("should fail to get the policy of a missing project", func(t *testing.T) {
+		if _, err := client.ProjectGetIamPolicy("projects/missing-project", &cloudresourcemanager.GetIamPolicyRequest{}).Do(); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+func TestClientItf(t *testing.T) {
+	t.Run("GCPClient", func(t *testing.T) {
+		testClient(t, &GCPClient{Service: seededService()})
+	})
+	t.Run("RealClient", func(t *testing.T) {
+		testClient(t, &RealClient{Service: servedClient(t, seededService())})
+	})
+}
+
+func TestNewRealClient(t *testing.T) {
+	client, err := NewRealClient(context.TODO(), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.ProjectsSearch() == nil || client.FoldersSearch() == nil || client.OrganizationsSearch() == nil {
+		t.Errorf("expected search calls on the real service")
+	}
+}
@@ -0,0 +1,143 @@
+package mockgcp
+
+import (
+	"sync"
+	"time"
+)
+
+// purgeAfter is how long a deleted project or service account can be undeleted for, before GCP purges it
+const purgeAfter = 30 * 24 * time.Hour
+
+// Clock is where a MockService gets the time from.  Create, update and delete times, the 30 day purge of
+// deleted projects and service accounts, quota refills and request.time in IAM conditions all read it, and
+// latency injected by a Fault waits on it
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock a MockService uses unless it's given another one
+type systemClock struct{}
+
+// Now returns the current time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for d to pass and then sends the current time
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// FakeClock is a Clock that only moves when it's told to, so tests can jump past expiry times and purges
+// without waiting for them.  It's safe to use from more than one goroutine.
+//
+// A call with latency injected by a Fault waits until the clock is moved past it, so a call made on the
+// test's own goroutine never returns.  Make the call from another goroutine, use BlockUntil to wait for it
+// to reach the clock, and then Advance the clock
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	// changed is closed and cleared when a waiter is added, to wake BlockUntil
+	changed chan struct{}
+}
+
+// fakeWaiter is a channel returned by FakeClock.After, waiting for the fake time to reach at
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewFakeClock returns a FakeClock stopped at start
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the fake time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that's sent the fake time once the clock has been moved on by d
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiter := fakeWaiter{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.waiters = append(c.waiters, waiter)
+	c.fire()
+	if c.changed != nil {
+		close(c.changed)
+		c.changed = nil
+	}
+	return waiter.c
+}
+
+// Waiters returns how many calls are waiting for the clock to be moved on
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil waits until at least n calls are waiting for the clock to be moved on
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		if c.changed == nil {
+			c.changed = make(chan struct{})
+		}
+		changed := c.changed
+		c.mu.Unlock()
+		<-changed
+		c.mu.Lock()
+	}
+}
+
+// Advance moves the fake time forward by d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.fire()
+}
+
+// Set moves the fake time to t
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+	c.fire()
+}
+
+// fire sends the fake time to the waiters it has reached.  The caller holds mu
+func (c *FakeClock) fire() {
+	waiting := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.at.After(c.now) {
+			waiting = append(waiting, waiter)
+			continue
+		}
+		waiter.c <- c.now
+	}
+	c.waiters = waiting
+}
+
+// now returns the time on the service's Clock.  Resources made outside of a service use the real time
+func (s *MockService) now() time.Time {
+	if s == nil || s.Clock == nil {
+		return time.Now()
+	}
+	return s.Clock.Now()
+}
+
+// after waits for d to pass on the service's Clock
+func (s *MockService) after(d time.Duration) <-chan time.Time {
+	if s == nil || s.Clock == nil {
+		return time.After(d)
+	}
+	return s.Clock.After(d)
+}
@@ -0,0 +1,114 @@
+package mockgcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	newService := func() (*MockService, *FakeClock) {
+		service, _ := NewService(context.TODO())
+		clock := NewFakeClock(start)
+		service.Clock = clock
+		service.Projects.NewProject("projects/test-project", "", nil)
+		return service, clock
+	}
+
+	t.Run("should stamp create times from the clock", func(t *testing.T) {
+		service, clock := newService()
+		clock.Advance(time.Hour)
+		bucket := service.Buckets.NewBucket("test-project", "test-bucket", nil)
+		if !bucket.TimeCreated.Equal(start.Add(time.Hour)) {
+			t.Errorf("got %v want %v", bucket.TimeCreated, start.Add(time.Hour))
+		}
+	})
+	t.Run("should purge deleted projects after 30 days", func(t *testing.T) {
+		service, clock := newService()
+		if _, err := service.Projects.Delete("projects/test-project").Do(); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(29 * 24 * time.Hour)
+		if len(service.Projects.DeletedProjects()) != 1 {
+			t.Fatalf("expected the project to be waiting to be purged")
+		}
+		if _, err := service.Projects.Undelete("projects/test-project", nil).Do(); err != nil {
+			t.Fatal(err)
+		}
+		if project := service.Projects.get("projects/test-project"); project == nil || !project.DeleteTime.IsZero() {
+			t.Fatalf("expected the project to be back, got %v", project)
+		}
+
+		service.Projects.Delete("projects/test-project").Do()
+		clock.Advance(30 * 24 * time.Hour)
+		if len(service.Projects.DeletedProjects()) != 0 {
+			t.Errorf("expected the project to be purged")
+		}
+		if _, err := service.Projects.Undelete("projects/test-project", nil).Do(); err == nil {
+			t.Errorf("expected a purged project not to undelete")
+		}
+	})
+	t.Run("should stop undeleting service accounts after 30 days", func(t *testing.T) {
+		service, clock := newService()
+		serviceAccount := service.ServiceAccounts.NewServiceAccount("projects/test-project", "test-account", "")
+		service.ServiceAccounts.Delete(serviceAccount.Name).Do()
+		clock.Advance(31 * 24 * time.Hour)
+		if _, err := service.ServiceAccounts.Undelete("projects/-/serviceAccounts/"+serviceAccount.UniqueID, nil).Do(); err == nil {
+			t.Errorf("expected the service account to be past undeleting")
+		}
+	})
+	t.Run("should expire conditional bindings", func(t *testing.T) {
+		service, clock := newService()
+		service.Caller = "user:alice@testdomain.co"
+		binding := NewBinding("roles/viewer", "user:alice@testdomain.co")
+		binding.Condition = &cloudresourcemanager.Expr{Expression: `request.time < timestamp("2023-01-02T00:00:00Z")`}
+		service.Projects.get("projects/test-project").SetIamPolicy(GeneratePolicy(binding))
+		request := &cloudresourcemanager.TestIamPermissionsRequest{Permissions: []string{"resourcemanager.projects.get"}}
+
+		response, _ := service.Projects.TestIamPermissions("projects/test-project", request).Do()
+		if len(response.Permissions) != 1 {
+			t.Errorf("got %v want the permission before the binding expires", response.Permissions)
+		}
+		clock.Advance(48 * time.Hour)
+		response, _ = service.Projects.TestIamPermissions("projects/test-project", request).Do()
+		if len(response.Permissions) != 0 {
+			t.Errorf("got %v want no permissions once the binding expires", response.Permissions)
+		}
+		results, _ := service.AnalyzeIamPolicy(AccessQuery{Identity: "user:alice@testdomain.co", Resource: "projects/test-project"})
+		if len(results) != 0 {
+			t.Errorf("got %v want no access once the binding expires", results)
+		}
+	})
+	t.Run("should hold injected latency until the clock is advanced", func(t *testing.T) {
+		service, clock := newService()
+		service.Faults.Inject(Fault{Method: "Projects.GetIamPolicy", Latency: time.Minute})
+		done := make(chan error, 1)
+		go func() {
+			_, err := service.Projects.GetIamPolicy("projects/test-project", nil).Do()
+			done <- err
+		}()
+
+		clock.BlockUntil(1)
+		clock.Advance(30 * time.Second)
+		if clock.Waiters() != 1 {
+			t.Errorf("got %v waiters want 1 before the latency passed", clock.Waiters())
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("got %v before the latency passed", err)
+		case <-time.After(20 * time.Millisecond):
+		}
+		clock.Advance(30 * time.Second)
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("got %v want nil", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the call to finish once the latency passed")
+		}
+	})
+}
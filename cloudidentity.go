@@ -0,0 +1,802 @@
+package mockgcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/api/cloudidentity/v1"
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+)
+
+const defaultCustomer = "customers/C00000000"
+
+var (
+	membershipNameFormat = regexp.MustCompile(`^(groups/[^/]+)/memberships/[^/]+$`)
+	memberKeyQueryFormat = regexp.MustCompile(`member_key_id\s*==\s*'([^']+)'`)
+)
+
+// memberTypes maps the prefix of an IAM member to the Cloud Identity membership type
+var memberTypes = map[string]string{
+	"user":           "USER",
+	"group":          "GROUP",
+	"serviceAccount": "SERVICE_ACCOUNT",
+}
+
+// Group is a mock of a google Cloud Identity Group.  Members are kept in the IAM member form
+// (user:alice@example.com, group:eng@example.com) so they can be compared directly with policy bindings
+type Group struct {
+	Name        string
+	Email       string
+	DisplayName string
+	Description string
+	Parent      string
+	Labels      map[string]string
+	CreateTime  time.Time
+	Memberships []*Membership
+
+	service *MockService
+}
+
+// Membership is a mock of a google Cloud Identity Membership of a Group
+type Membership struct {
+	Name       string
+	Member     string
+	Roles      []string
+	CreateTime time.Time
+}
+
+// Email returns the email of the member, without its IAM prefix
+func (m *Membership) Email() string {
+	return memberEmail(m.Member)
+}
+
+// Type returns the Cloud Identity type of the member, such as USER or GROUP
+func (m *Membership) Type() string {
+	prefix := strings.SplitN(m.Member, ":", 2)[0]
+	if memberType, ok := memberTypes[prefix]; ok {
+		return memberType
+	}
+	return "OTHER"
+}
+
+// toAPI converts the mock group to the type returned by the Cloud Identity API
+func (g *Group) toAPI() *cloudidentity.Group {
+	return &cloudidentity.Group{
+		Name:        g.Name,
+		GroupKey:    &cloudidentity.EntityKey{Id: g.Email},
+		DisplayName: g.DisplayName,
+		Description: g.Description,
+		Parent:      g.Parent,
+		Labels:      copyLabels(g.Labels),
+		CreateTime:  g.CreateTime.Format(time.RFC3339Nano),
+	}
+}
+
+// toAPI converts the mock membership to the type returned by the Cloud Identity API
+func (m *Membership) toAPI() *cloudidentity.Membership {
+	membership := &cloudidentity.Membership{
+		Name:               m.Name,
+		PreferredMemberKey: &cloudidentity.EntityKey{Id: m.Email()},
+		Type:               m.Type(),
+		CreateTime:         m.CreateTime.Format(time.RFC3339Nano),
+	}
+	for _, role := range m.Roles {
+		membership.Roles = append(membership.Roles, &cloudidentity.MembershipRole{Name: role})
+	}
+	return membership
+}
+
+// memberEmail strips the IAM prefix (user:, group: ...) from a member
+func memberEmail(member string) string {
+	parts := strings.SplitN(member, ":", 2)
+	return parts[len(parts)-1]
+}
+
+// GroupsService is a mock of google Cloud Identity's groups Service
+type GroupsService struct {
+	Service     *MockService
+	GroupList   []*Group
+	Memberships *GroupsMembershipsService
+}
+
+// NewGroupsService will return a new Groups Service
+func NewGroupsService(s *MockService) *GroupsService {
+	rs := &GroupsService{Service: s}
+	rs.Memberships = &GroupsMembershipsService{Service: s}
+	return rs
+}
+
+// NewGroup creates a new group with the specified email and display name and returns a pointer to the
+// created group
+func (r *GroupsService) NewGroup(email, displayName string) *Group {
+	group := &Group{
+		Name:        "groups/" + generateUniqueID(),
+		Email:       email,
+		DisplayName: displayName,
+		Parent:      defaultCustomer,
+		Labels:      map[string]string{"cloudidentity.googleapis.com/groups.discussion_forum": ""},
+		CreateTime:  r.Service.now().UTC(),
+		service:     r.Service,
+	}
+	r.GroupList = append(r.GroupList, group)
+	return group
+}
+
+// AddMember adds member (in the IAM form, such as user:alice@example.com) to the group with the given
+// roles and returns the membership.  If no roles are given the member gets the MEMBER role
+func (g *Group) AddMember(member string, roles ...string) *Membership {
+	if len(roles) == 0 {
+		roles = []string{"MEMBER"}
+	}
+	membership := &Membership{
+		Name:       fmt.Sprintf("%v/memberships/%v", g.Name, generateUniqueID()),
+		Member:     member,
+		Roles:      copyStrings(roles),
+		CreateTime: g.service.now().UTC(),
+	}
+	g.Memberships = append(g.Memberships, membership)
+	return membership
+}
+
+// find returns the group with the given resource name, or nil if there isn't one
+func (r *GroupsService) find(name string) *Group {
+	for _, group := range r.GroupList {
+		if group.Name == name {
+			return group
+		}
+	}
+	return nil
+}
+
+// findByEmail returns the group with the given email, or nil if there isn't one
+func (r *GroupsService) findByEmail(email string) *Group {
+	for _, group := range r.GroupList {
+		if strings.EqualFold(group.Email, email) {
+			return group
+		}
+	}
+	return nil
+}
+
+// TransitiveMembers returns every member of the group with the given email, including the members of any
+// nested groups (and the nested groups themselves).  Members are returned in the IAM form, in the order
+// they are found.  It returns nil if the group doesn't exist
+func (r *GroupsService) TransitiveMembers(email string) []string {
+	group := r.findByEmail(email)
+	if group == nil {
+		return nil
+	}
+	members := []string{}
+	r.walkMembers(group, map[string]bool{group.Name: true}, true, func(m *Membership, direct bool) {
+		if !stringInSlice(m.Member, members) {
+			members = append(members, m.Member)
+		}
+	})
+	return members
+}
+
+// walkMembers calls fn for every membership of group and its nested groups.  direct is true for the
+// group's own memberships.  seen stops us looping forever when groups contain each other
+func (r *GroupsService) walkMembers(group *Group, seen map[string]bool, direct bool, fn func(m *Membership, direct bool)) {
+	for _, membership := range group.Memberships {
+		fn(membership, direct)
+		if membership.Type() != "GROUP" {
+			continue
+		}
+		nested := r.findByEmail(membership.Email())
+		if nested == nil || seen[nested.Name] {
+			continue
+		}
+		seen[nested.Name] = true
+		r.walkMembers(nested, seen, false, fn)
+	}
+}
+
+// hasTransitiveMember returns true if member is in the group with the given email, directly or through
+// nested groups.  Member can be an IAM member or a bare email
+func (r *GroupsService) hasTransitiveMember(email, member string) bool {
+	for _, m := range r.TransitiveMembers(email) {
+		if m == member || memberEmail(m) == member {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandPolicyMembers returns a copy of policy where each binding holding a group: member also holds
+// every transitive member of that group, so you can check whether someone effectively has a role with
+// PolicyContains and the binding's members.  Groups that aren't in the Groups Service are left as they are
+func (s *MockService) ExpandPolicyMembers(policy *cloudresourcemanager.Policy) *cloudresourcemanager.Policy {
+	expanded := copyPolicy(policy)
+	if expanded == nil {
+		return nil
+	}
+	for _, binding := range expanded.Bindings {
+		if binding == nil {
+			continue
+		}
+		for _, member := range binding.Members {
+			if !strings.HasPrefix(member, "group:") {
+				continue
+			}
+			for _, nested := range s.Groups.TransitiveMembers(memberEmail(member)) {
+				if !stringInSlice(nested, binding.Members) {
+					binding.Members = append(binding.Members, nested)
+				}
+			}
+		}
+	}
+	return expanded
+}
+
+// Create will take a group and returns a Create Call, so we can run a Do() method on it.
+func (r *GroupsService) Create(group *cloudidentity.Group) *GroupsCreateCall {
+	return &GroupsCreateCall{Service: r.Service, Group: group}
+}
+
+// GroupsCreateCall is a structure that is returned by Groups.Create.  Then we call Do() on it to create the group
+type GroupsCreateCall struct {
+	Service *MockService
+	Group   *cloudidentity.Group
+	ctx     context.Context
+}
+
+// Do will be called on GroupsCreateCall to create the group.  Like the real API it returns a finished
+// Operation with the group as its response
+func (c *GroupsCreateCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.Operation, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.Create", "", c.Group)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if c.Group == nil || c.Group.GroupKey == nil || c.Group.GroupKey.Id == "" {
+		return nil, fmt.Errorf("group key is required")
+	}
+	if c.Service.Groups.findByEmail(c.Group.GroupKey.Id) != nil {
+		return nil, fmt.Errorf("%v: %v", resourceAlreadyExistsError, c.Group.GroupKey.Id)
+	}
+	group := c.Service.Groups.NewGroup(c.Group.GroupKey.Id, c.Group.DisplayName)
+	group.Description = c.Group.Description
+	if c.Group.Parent != "" {
+		group.Parent = c.Group.Parent
+	}
+	if c.Group.Labels != nil {
+		group.Labels = copyLabels(c.Group.Labels)
+	}
+	return doneOperation(group.toAPI())
+}
+
+// Context sets the context used by Do
+func (c *GroupsCreateCall) Context(ctx context.Context) *GroupsCreateCall {
+	c.ctx = ctx
+	return c
+}
+
+// Get will take a group resource name and returns a Get Call, so we can run a Do() method on it.
+func (r *GroupsService) Get(name string) *GroupsGetCall {
+	return &GroupsGetCall{Service: r.Service, Name: name}
+}
+
+// GroupsGetCall is a structure that is returned by Groups.Get.  Then we call Do() on it to return the group
+type GroupsGetCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on GroupsGetCall and return the group found
+func (c *GroupsGetCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.Group, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.Get", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	group := c.Service.Groups.find(c.Name)
+	if group == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	return group.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *GroupsGetCall) Context(ctx context.Context) *GroupsGetCall {
+	c.ctx = ctx
+	return c
+}
+
+// List returns a List Call, so we can run a Do() method on it.  Filter by customer with Parent()
+func (r *GroupsService) List() *GroupsListCall {
+	return &GroupsListCall{Service: r.Service}
+}
+
+// GroupsListCall is a structure that is returned by Groups.List.  Then we call Do() on it to return the groups
+type GroupsListCall struct {
+	Service *MockService
+	parent  string
+	ctx     context.Context
+}
+
+// Parent sets the customer (customers/{customer_id}) to list the groups of
+func (c *GroupsListCall) Parent(parent string) *GroupsListCall {
+	c.parent = parent
+	return c
+}
+
+// Do will be called on GroupsListCall and return the groups of the customer
+func (c *GroupsListCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.ListGroupsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.List", c.parent, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	response := &cloudidentity.ListGroupsResponse{}
+	for _, group := range c.Service.Groups.GroupList {
+		if c.parent == "" || group.Parent == c.parent {
+			response.Groups = append(response.Groups, group.toAPI())
+		}
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *GroupsListCall) Context(ctx context.Context) *GroupsListCall {
+	c.ctx = ctx
+	return c
+}
+
+// Lookup returns a Lookup Call, so we can run a Do() method on it.  The group's email is set with GroupKeyId()
+func (r *GroupsService) Lookup() *GroupsLookupCall {
+	return &GroupsLookupCall{Service: r.Service}
+}
+
+// GroupsLookupCall is a structure that is returned by Groups.Lookup.  Then we call Do() on it to find the
+// resource name of a group from its email
+type GroupsLookupCall struct {
+	Service *MockService
+	email   string
+	ctx     context.Context
+}
+
+// GroupKeyId sets the email of the group to look up
+func (c *GroupsLookupCall) GroupKeyId(groupKeyId string) *GroupsLookupCall {
+	c.email = groupKeyId
+	return c
+}
+
+// Do will be called on GroupsLookupCall and return the group's resource name
+func (c *GroupsLookupCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.LookupGroupNameResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.Lookup", c.email, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	group := c.Service.Groups.findByEmail(c.email)
+	if group == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.email)
+	}
+	return &cloudidentity.LookupGroupNameResponse{Name: group.Name}, nil
+}
+
+// Context sets the context used by Do
+func (c *GroupsLookupCall) Context(ctx context.Context) *GroupsLookupCall {
+	c.ctx = ctx
+	return c
+}
+
+// Delete will take a group resource name and returns a Delete Call, so we can run a Do() method on it.
+func (r *GroupsService) Delete(name string) *GroupsDeleteCall {
+	return &GroupsDeleteCall{Service: r.Service, Name: name}
+}
+
+// GroupsDeleteCall is a structure that is returned by Groups.Delete.  Then we call Do() on it to delete the group
+type GroupsDeleteCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on GroupsDeleteCall to delete the group and its memberships.  Memberships of the deleted
+// group in other groups are left behind, and no longer expand to anyone
+func (c *GroupsDeleteCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.Operation, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.Delete", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	for i, group := range c.Service.Groups.GroupList {
+		if group.Name == c.Name {
+			c.Service.Groups.GroupList = append(c.Service.Groups.GroupList[:i], c.Service.Groups.GroupList[i+1:]...)
+			return doneOperation(nil)
+		}
+	}
+	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+}
+
+// Context sets the context used by Do
+func (c *GroupsDeleteCall) Context(ctx context.Context) *GroupsDeleteCall {
+	c.ctx = ctx
+	return c
+}
+
+// GroupsMembershipsService is a mock of google Cloud Identity's groups.memberships Service
+type GroupsMembershipsService struct {
+	Service *MockService
+}
+
+// find returns the membership with the given resource name, or nil if there isn't one
+func (r *GroupsMembershipsService) find(name string) (*Group, int) {
+	match := membershipNameFormat.FindStringSubmatch(name)
+	if match == nil {
+		return nil, -1
+	}
+	group := r.Service.Groups.find(match[1])
+	if group == nil {
+		return nil, -1
+	}
+	for i, membership := range group.Memberships {
+		if membership.Name == name {
+			return group, i
+		}
+	}
+	return nil, -1
+}
+
+// Create will take a group resource name and a membership and returns a Create Call, so we can run a Do()
+// method on it.
+func (r *GroupsMembershipsService) Create(parent string, membership *cloudidentity.Membership) *GroupsMembershipsCreateCall {
+	return &GroupsMembershipsCreateCall{Service: r.Service, Parent: parent, Membership: membership}
+}
+
+// GroupsMembershipsCreateCall is a structure that is returned by Groups.Memberships.Create.  Then we call Do()
+// on it to add the member to the group
+type GroupsMembershipsCreateCall struct {
+	Service    *MockService
+	Parent     string
+	Membership *cloudidentity.Membership
+	ctx        context.Context
+}
+
+// Do will be called on GroupsMembershipsCreateCall to add the membership.  The member is treated as a group
+// if a group with its email exists, a service account if it has a service account email, and a user otherwise
+func (c *GroupsMembershipsCreateCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.Operation, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.Memberships.Create", c.Parent, c.Membership)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	group := c.Service.Groups.find(c.Parent)
+	if group == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Parent)
+	}
+	if c.Membership == nil || c.Membership.PreferredMemberKey == nil || c.Membership.PreferredMemberKey.Id == "" {
+		return nil, fmt.Errorf("preferred member key is required")
+	}
+	email := c.Membership.PreferredMemberKey.Id
+	for _, membership := range group.Memberships {
+		if strings.EqualFold(membership.Email(), email) {
+			return nil, fmt.Errorf("%v: %v", resourceAlreadyExistsError, email)
+		}
+	}
+	member := "user:" + email
+	if c.Service.Groups.findByEmail(email) != nil {
+		member = "group:" + email
+	} else if strings.HasSuffix(email, serviceAccountDomain) {
+		member = "serviceAccount:" + email
+	}
+	var roles []string
+	for _, role := range c.Membership.Roles {
+		roles = append(roles, role.Name)
+	}
+	return doneOperation(group.AddMember(member, roles...).toAPI())
+}
+
+// Context sets the context used by Do
+func (c *GroupsMembershipsCreateCall) Context(ctx context.Context) *GroupsMembershipsCreateCall {
+	c.ctx = ctx
+	return c
+}
+
+// Get will take a membership resource name and returns a Get Call, so we can run a Do() method on it.
+func (r *GroupsMembershipsService) Get(name string) *GroupsMembershipsGetCall {
+	return &GroupsMembershipsGetCall{Service: r.Service, Name: name}
+}
+
+// GroupsMembershipsGetCall is a structure that is returned by Groups.Memberships.Get.  Then we call Do() on
+// it to return the membership
+type GroupsMembershipsGetCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on GroupsMembershipsGetCall and return the membership found
+func (c *GroupsMembershipsGetCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.Membership, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.Memberships.Get", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	group, i := c.Service.Groups.Memberships.find(c.Name)
+	if group == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	return group.Memberships[i].toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *GroupsMembershipsGetCall) Context(ctx context.Context) *GroupsMembershipsGetCall {
+	c.ctx = ctx
+	return c
+}
+
+// List will take a group resource name and returns a List Call, so we can run a Do() method on it.
+func (r *GroupsMembershipsService) List(parent string) *GroupsMembershipsListCall {
+	return &GroupsMembershipsListCall{Service: r.Service, Parent: parent}
+}
+
+// GroupsMembershipsListCall is a structure that is returned by Groups.Memberships.List.  Then we call Do()
+// on it to return the group's direct memberships
+type GroupsMembershipsListCall struct {
+	Service *MockService
+	Parent  string
+	ctx     context.Context
+}
+
+// Do will be called on GroupsMembershipsListCall and return the group's direct memberships
+func (c *GroupsMembershipsListCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.ListMembershipsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.Memberships.List", c.Parent, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	group := c.Service.Groups.find(c.Parent)
+	if group == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Parent)
+	}
+	response := &cloudidentity.ListMembershipsResponse{}
+	for _, membership := range group.Memberships {
+		response.Memberships = append(response.Memberships, membership.toAPI())
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *GroupsMembershipsListCall) Context(ctx context.Context) *GroupsMembershipsListCall {
+	c.ctx = ctx
+	return c
+}
+
+// Delete will take a membership resource name and returns a Delete Call, so we can run a Do() method on it.
+func (r *GroupsMembershipsService) Delete(name string) *GroupsMembershipsDeleteCall {
+	return &GroupsMembershipsDeleteCall{Service: r.Service, Name: name}
+}
+
+// GroupsMembershipsDeleteCall is a structure that is returned by Groups.Memberships.Delete.  Then we call
+// Do() on it to remove the member from the group
+type GroupsMembershipsDeleteCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on GroupsMembershipsDeleteCall to remove the membership
+func (c *GroupsMembershipsDeleteCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.Operation, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.Memberships.Delete", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	group, i := c.Service.Groups.Memberships.find(c.Name)
+	if group == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	group.Memberships = append(group.Memberships[:i], group.Memberships[i+1:]...)
+	return doneOperation(nil)
+}
+
+// Context sets the context used by Do
+func (c *GroupsMembershipsDeleteCall) Context(ctx context.Context) *GroupsMembershipsDeleteCall {
+	c.ctx = ctx
+	return c
+}
+
+// CheckTransitiveMembership will take a group resource name and returns a CheckTransitiveMembership Call,
+// so we can run a Do() method on it.  The member is set with Query("member_key_id == 'alice@example.com'")
+func (r *GroupsMembershipsService) CheckTransitiveMembership(parent string) *GroupsMembershipsCheckTransitiveMembershipCall {
+	return &GroupsMembershipsCheckTransitiveMembershipCall{Service: r.Service, Parent: parent}
+}
+
+// GroupsMembershipsCheckTransitiveMembershipCall is a structure that is returned by
+// Groups.Memberships.CheckTransitiveMembership.  Then we call Do() on it to check the membership
+type GroupsMembershipsCheckTransitiveMembershipCall struct {
+	Service *MockService
+	Parent  string
+	query   string
+	ctx     context.Context
+}
+
+// Query sets the member to check, in the form member_key_id == 'alice@example.com'
+func (c *GroupsMembershipsCheckTransitiveMembershipCall) Query(query string) *GroupsMembershipsCheckTransitiveMembershipCall {
+	c.query = query
+	return c
+}
+
+// Do will be called on GroupsMembershipsCheckTransitiveMembershipCall and return whether the member is in
+// the group, directly or through nested groups
+func (c *GroupsMembershipsCheckTransitiveMembershipCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.CheckTransitiveMembershipResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.Memberships.CheckTransitiveMembership", c.Parent, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	group := c.Service.Groups.find(c.Parent)
+	if group == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Parent)
+	}
+	email, err := memberKeyFromQuery(c.query)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudidentity.CheckTransitiveMembershipResponse{
+		HasMembership: c.Service.Groups.hasTransitiveMember(group.Email, email),
+	}, nil
+}
+
+// Context sets the context used by Do
+func (c *GroupsMembershipsCheckTransitiveMembershipCall) Context(ctx context.Context) *GroupsMembershipsCheckTransitiveMembershipCall {
+	c.ctx = ctx
+	return c
+}
+
+// SearchTransitiveMemberships will take a group resource name and returns a SearchTransitiveMemberships Call,
+// so we can run a Do() method on it.
+func (r *GroupsMembershipsService) SearchTransitiveMemberships(parent string) *GroupsMembershipsSearchTransitiveMembershipsCall {
+	return &GroupsMembershipsSearchTransitiveMembershipsCall{Service: r.Service, Parent: parent}
+}
+
+// GroupsMembershipsSearchTransitiveMembershipsCall is a structure that is returned by
+// Groups.Memberships.SearchTransitiveMemberships.  Then we call Do() on it to return every member of the group
+type GroupsMembershipsSearchTransitiveMembershipsCall struct {
+	Service *MockService
+	Parent  string
+	ctx     context.Context
+}
+
+// Do will be called on GroupsMembershipsSearchTransitiveMembershipsCall and return the direct and indirect
+// members of the group.  A member found both ways is reported as DIRECT
+func (c *GroupsMembershipsSearchTransitiveMembershipsCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.SearchTransitiveMembershipsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.Memberships.SearchTransitiveMemberships", c.Parent, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	group := c.Service.Groups.find(c.Parent)
+	if group == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Parent)
+	}
+	response := &cloudidentity.SearchTransitiveMembershipsResponse{}
+	relations := map[string]*cloudidentity.MemberRelation{}
+	c.Service.Groups.walkMembers(group, map[string]bool{group.Name: true}, true, func(m *Membership, direct bool) {
+		relationType := "INDIRECT"
+		if direct {
+			relationType = "DIRECT"
+		}
+		if relation, ok := relations[m.Member]; ok {
+			if direct {
+				relation.RelationType = relationType
+			}
+			return
+		}
+		relation := &cloudidentity.MemberRelation{
+			Member:             m.Member,
+			PreferredMemberKey: []*cloudidentity.EntityKey{{Id: m.Email()}},
+			RelationType:       relationType,
+		}
+		for _, role := range m.Roles {
+			relation.Roles = append(relation.Roles, &cloudidentity.TransitiveMembershipRole{Role: role})
+		}
+		relations[m.Member] = relation
+		response.Memberships = append(response.Memberships, relation)
+	})
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *GroupsMembershipsSearchTransitiveMembershipsCall) Context(ctx context.Context) *GroupsMembershipsSearchTransitiveMembershipsCall {
+	c.ctx = ctx
+	return c
+}
+
+// SearchTransitiveGroups will take a group resource name (groups/- like the real API) and returns a
+// SearchTransitiveGroups Call, so we can run a Do() method on it.
+func (r *GroupsMembershipsService) SearchTransitiveGroups(parent string) *GroupsMembershipsSearchTransitiveGroupsCall {
+	return &GroupsMembershipsSearchTransitiveGroupsCall{Service: r.Service, Parent: parent}
+}
+
+// GroupsMembershipsSearchTransitiveGroupsCall is a structure that is returned by
+// Groups.Memberships.SearchTransitiveGroups.  Then we call Do() on it to return the groups a member is in
+type GroupsMembershipsSearchTransitiveGroupsCall struct {
+	Service *MockService
+	Parent  string
+	query   string
+	ctx     context.Context
+}
+
+// Query sets the member to search for, in the form member_key_id == 'alice@example.com'
+func (c *GroupsMembershipsSearchTransitiveGroupsCall) Query(query string) *GroupsMembershipsSearchTransitiveGroupsCall {
+	c.query = query
+	return c
+}
+
+// Do will be called on GroupsMembershipsSearchTransitiveGroupsCall and return every group the member is in,
+// directly or through nested groups
+func (c *GroupsMembershipsSearchTransitiveGroupsCall) Do(opts ...googleapi.CallOption) (result *cloudidentity.SearchTransitiveGroupsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Groups.Memberships.SearchTransitiveGroups", c.Parent, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if c.Parent != "groups/-" {
+		return nil, fmt.Errorf("resource format invalid")
+	}
+	email, err := memberKeyFromQuery(c.query)
+	if err != nil {
+		return nil, err
+	}
+	response := &cloudidentity.SearchTransitiveGroupsResponse{}
+	for _, group := range c.Service.Groups.GroupList {
+		relationType := ""
+		for _, membership := range group.Memberships {
+			if strings.EqualFold(membership.Email(), email) {
+				relationType = "DIRECT"
+			}
+		}
+		if relationType == "" && c.Service.Groups.hasTransitiveMember(group.Email, email) {
+			relationType = "INDIRECT"
+		}
+		if relationType == "" {
+			continue
+		}
+		response.Memberships = append(response.Memberships, &cloudidentity.GroupRelation{
+			Group:        group.Name,
+			GroupKey:     &cloudidentity.EntityKey{Id: group.Email},
+			DisplayName:  group.DisplayName,
+			Labels:       copyLabels(group.Labels),
+			RelationType: relationType,
+		})
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *GroupsMembershipsSearchTransitiveGroupsCall) Context(ctx context.Context) *GroupsMembershipsSearchTransitiveGroupsCall {
+	c.ctx = ctx
+	return c
+}
+
+// memberKeyFromQuery returns the email from a query like member_key_id == 'alice@example.com'
+func memberKeyFromQuery(query string) (string, error) {
+	match := memberKeyQueryFormat.FindStringSubmatch(query)
+	if match == nil {
+		return "", fmt.Errorf("invalid query: %v", query)
+	}
+	return match[1], nil
+}
+
+// doneOperation returns a finished long running operation with response as its result
+func doneOperation(response interface{}) (*cloudidentity.Operation, error) {
+	operation := &cloudidentity.Operation{Done: true}
+	if response != nil {
+		data, err := json.Marshal(response)
+		if err != nil {
+			return nil, err
+		}
+		operation.Response = data
+	}
+	return operation, nil
+}
@@ -0,0 +1,124 @@
+package mockgcp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/cloudidentity/v1"
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+func TestGroupsMemberships_Create_Do(t *testing.T) {
+	t.Run("should add nested group as a group member", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		parent := service.Groups.NewGroup("parent@testdomain.co", "")
+		service.Groups.NewGroup("child@testdomain.co", "")
+
+		_, err := service.Groups.Memberships.Create(parent.Name, &cloudidentity.Membership{
+			PreferredMemberKey: &cloudidentity.EntityKey{Id: "child@testdomain.co"},
+		}).Do()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		response, _ := service.Groups.Memberships.List(parent.Name).Do()
+		want := "GROUP"
+		got := response.Memberships[0].Type
+
+		if got != want {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("should return err if group doesn't exist", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+
+		_, err := service.Groups.Memberships.Create("groups/missing", &cloudidentity.Membership{
+			PreferredMemberKey: &cloudidentity.EntityKey{Id: "test@testdomain.co"},
+		}).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestGroupsMemberships_Transitive_Do(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	admins := service.Groups.NewGroup("admins@testdomain.co", "")
+	engineers := service.Groups.NewGroup("engineers@testdomain.co", "")
+	admins.AddMember("group:engineers@testdomain.co")
+	engineers.AddMember("user:alice@testdomain.co")
+	engineers.AddMember("group:admins@testdomain.co")
+
+	t.Run("should find member through nested group", func(t *testing.T) {
+		got, _ := service.Groups.Memberships.CheckTransitiveMembership(admins.Name).Query("member_key_id == 'alice@testdomain.co'").Do()
+
+		if !got.HasMembership {
+			t.Errorf("expected alice to be a transitive member")
+		}
+	})
+	t.Run("should report direct and indirect members", func(t *testing.T) {
+		response, _ := service.Groups.Memberships.SearchTransitiveMemberships(admins.Name).Do()
+
+		want := map[string]string{
+			"group:engineers@testdomain.co": "DIRECT",
+			"user:alice@testdomain.co":      "INDIRECT",
+			"group:admins@testdomain.co":    "INDIRECT",
+		}
+		got := map[string]string{}
+		for _, relation := range response.Memberships {
+			got[relation.Member] = relation.RelationType
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("should find groups for member", func(t *testing.T) {
+		response, _ := service.Groups.Memberships.SearchTransitiveGroups("groups/-").Query("member_key_id == 'alice@testdomain.co'").Do()
+
+		want := map[string]string{"admins@testdomain.co": "INDIRECT", "engineers@testdomain.co": "DIRECT"}
+		got := map[string]string{}
+		for _, relation := range response.Memberships {
+			got[relation.GroupKey.Id] = relation.RelationType
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+}
+
+func TestMockService_ExpandPolicyMembers(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	admins := service.Groups.NewGroup("admins@testdomain.co", "")
+	engineers := service.Groups.NewGroup("engineers@testdomain.co", "")
+	admins.AddMember("group:engineers@testdomain.co")
+	engineers.AddMember("user:alice@testdomain.co")
+	policy := GeneratePolicy(NewBinding("roles/owner", "group:admins@testdomain.co"))
+
+	t.Run("should add transitive members to binding", func(t *testing.T) {
+		want := []string{"group:admins@testdomain.co", "group:engineers@testdomain.co", "user:alice@testdomain.co"}
+		got := PolicyContains(service.ExpandPolicyMembers(policy), "roles/owner").Members
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+		if len(PolicyContains(policy, "roles/owner").Members) != 1 {
+			t.Errorf("expected original policy to be unchanged")
+		}
+	})
+	t.Run("should grant permissions to caller through nested group", func(t *testing.T) {
+		service.Projects.NewProject("projects/test-project", "", policy)
+		service.Caller = "user:alice@testdomain.co"
+
+		got, _ := service.Projects.TestIamPermissions("projects/test-project", &cloudresourcemanager.TestIamPermissionsRequest{
+			Permissions: []string{"resourcemanager.projects.setIamPolicy"},
+		}).Do()
+
+		if len(got.Permissions) != 1 {
+			t.Errorf("expected caller to have permission but got %v", got.Permissions)
+		}
+	})
+}
@@ -0,0 +1,242 @@
+// Command mockgcp serves a mockgcp.MockService as its own process, so integration tests that aren't
+// written in Go (Terraform, Python scripts, gcloud with its endpoint overridden) can run against the mock.
+// It serves the resource manager v3 REST API, and optionally the gRPC API, seeded from a fixture file.
+//
+// The REST port also serves an admin API for the tests driving it:
+//
+//	POST /admin/reset               puts the seeded state back, and clears faults, quotas and recorded calls
+//	POST /admin/snapshot?name=x     takes a snapshot of the current state, called default if name isn't set
+//	POST /admin/restore?name=x      puts a snapshot back
+//	GET  /admin/dump?format=yaml    returns the current state as a fixture, in JSON unless format is yaml
+//
+// Settings are read from a YAML or JSON config file given with -config, and flags override them
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/celo-org/mockgcp"
+	"google.golang.org/grpc"
+	"gopkg.in/yaml.v3"
+)
+
+// config is what the server is run with
+type config struct {
+	// Addr is the address the REST API and the admin API are served on
+	Addr string `yaml:"addr" json:"addr"`
+	// GRPCAddr is the address the gRPC API is served on.  It isn't served if this is empty
+	GRPCAddr string `yaml:"grpcAddr" json:"grpcAddr"`
+	// Fixture is the fixture file the service is seeded from
+	Fixture string `yaml:"fixture" json:"fixture"`
+	// Caller is the member the service treats as making every call, for IAM checks and audit logs
+	Caller string `yaml:"caller" json:"caller"`
+}
+
+// loadConfig reads a config file.  JSON is read as YAML, which it's a subset of
+func loadConfig(path string) (*config, error) {
+	c := &config{Addr: ":8080"}
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("config %v invalid: %v", path, err)
+	}
+	return c, nil
+}
+
+// server holds the service along with its seeded state and snapshots.  The service isn't safe for
+// concurrent use, so requests are served one at a time
+type server struct {
+	mu        sync.Mutex
+	service   *mockgcp.MockService
+	seed      *mockgcp.Snapshot
+	snapshots map[string]*mockgcp.Snapshot
+	handler   http.Handler
+}
+
+// newServer returns a server with a service seeded as c says
+func newServer(c *config) (*server, error) {
+	service, err := mockgcp.NewService(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	service.Caller = c.Caller
+	if c.Fixture != "" {
+		if err := service.LoadFixtureFile(c.Fixture); err != nil {
+			return nil, err
+		}
+	}
+	return &server{
+		service:   service,
+		seed:      service.Snapshot(),
+		snapshots: map[string]*mockgcp.Snapshot{},
+		handler:   service.Handler(),
+	}, nil
+}
+
+// ServeHTTP serves the admin API, and the REST API for everything else
+func (s *server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if strings.HasPrefix(req.URL.Path, "/admin/") {
+		s.serveAdmin(w, req)
+		return
+	}
+	s.handler.ServeHTTP(w, req)
+}
+
+// serveAdmin serves a single admin request
+func (s *server) serveAdmin(w http.ResponseWriter, req *http.Request) {
+	name := req.URL.Query().Get("name")
+	if name == "" {
+		name = "default"
+	}
+	method := http.MethodPost
+	if req.URL.Path == "/admin/dump" {
+		method = http.MethodGet
+	}
+	if req.Method != method {
+		http.Error(w, fmt.Sprintf("%v %v is not allowed", req.Method, req.URL.Path), http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch req.URL.Path {
+	case "/admin/reset":
+		s.service.Restore(s.seed)
+		s.service.Faults.Clear()
+		s.service.Quotas.Clear()
+		s.service.Calls.Reset()
+	case "/admin/snapshot":
+		s.snapshots[name] = s.service.Snapshot()
+	case "/admin/restore":
+		snapshot, ok := s.snapshots[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf("snapshot %v not found", name), http.StatusNotFound)
+			return
+		}
+		s.service.Restore(snapshot)
+	case "/admin/dump":
+		var data []byte
+		var err error
+		if req.URL.Query().Get("format") == "yaml" {
+			w.Header().Set("Content-Type", "application/yaml")
+			data, err = yaml.Marshal(s.service.Fixture())
+		} else {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			data, err = json.MarshalIndent(s.service.Fixture(), "", "  ")
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(data)
+		return
+	default:
+		http.Error(w, fmt.Sprintf("%v not found", req.URL.Path), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serialize is a gRPC interceptor serving calls one at a time, along with the REST requests
+func (s *server) serialize(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return handler(ctx, req)
+}
+
+// serve serves the REST API on listener, and the gRPC API on grpcListener if it isn't nil, until ctx is done
+func (s *server) serve(ctx context.Context, listener, grpcListener net.Listener) error {
+	errs := make(chan error, 2)
+	httpServer := &http.Server{Handler: s}
+	go func() { errs <- httpServer.Serve(listener) }()
+	var grpcServer *grpc.Server
+	if grpcListener != nil {
+		grpcServer = mockgcp.NewGRPCServer(s.service, grpc.UnaryInterceptor(s.serialize))
+		go func() { errs <- grpcServer.Serve(grpcListener) }()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+	httpServer.Close()
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return err
+}
+
+// run serves the mock as c says until ctx is done
+func run(ctx context.Context, c *config) error {
+	s, err := newServer(c)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("serving REST on %v", listener.Addr())
+	var grpcListener net.Listener
+	if c.GRPCAddr != "" {
+		if grpcListener, err = net.Listen("tcp", c.GRPCAddr); err != nil {
+			listener.Close()
+			return err
+		}
+		log.Printf("serving gRPC on %v", grpcListener.Addr())
+	}
+	return s.serve(ctx, listener, grpcListener)
+}
+
+func main() {
+	configPath := flag.String("config", "", "YAML or JSON config file")
+	addr := flag.String("addr", "", "address to serve the REST and admin APIs on (default :8080)")
+	grpcAddr := flag.String("grpc-addr", "", "address to serve the gRPC API on, if any")
+	fixture := flag.String("fixture", "", "YAML or JSON fixture file to seed the mock from")
+	caller := flag.String("caller", "", "member making every call, such as user:alice@example.com")
+	flag.Parse()
+
+	c, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			c.Addr = *addr
+		case "grpc-addr":
+			c.GRPCAddr = *grpcAddr
+		case "fixture":
+			c.Fixture = *fixture
+		case "caller":
+			c.Caller = *caller
+		}
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, c); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
+	"cloud.google.com/go/resourcemanager/apiv3/resourcemanagerpb"
+	"github.com/celo-org/mockgcp"
+	"google.golang.org/api/cloudresourcemanager/v3"
+	option "google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const fixturePath = "../../testdata/org.yaml"
+
+// post makes an admin request and fails the test unless it gets want back
+func post(t *testing.T, url string, want int) {
+	t.Helper()
+	resp, err := http.Post(url, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != want {
+		t.Errorf("got %v from %v want %v", resp.StatusCode, url, want)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("should default to port 8080", func(t *testing.T) {
+		c, err := loadConfig("")
+		if err != nil || c.Addr != ":8080" {
+			t.Errorf("got %v %v want :8080", c, err)
+		}
+	})
+	t.Run("should read YAML and JSON", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, data := range map[string]string{
+			"config.yaml": "addr: :9000\ngrpcAddr: :9001\nfixture: org.yaml\ncaller: user:alice@testdomain.co\n",
+			"config.json": `{"addr": ":9000", "grpcAddr": ":9001", "fixture": "org.yaml", "caller": "user:alice@testdomain.co"}`,
+		} {
+			path := filepath.Join(dir, name)
+			os.WriteFile(path, []byte(data), 0644)
+			c, err := loadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *c != (config{Addr: ":9000", GRPCAddr: ":9001", Fixture: "org.yaml", Caller: "user:alice@testdomain.co"}) {
+				t.Errorf("got %+v from %v", c, name)
+			}
+		}
+	})
+}
+
+func TestServer(t *testing.T) {
+	s, err := newServer(&config{Fixture: fixturePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+	client, err := cloudresourcemanager.NewService(context.TODO(), option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewers := func() []string {
+		policy, err := client.Folders.GetIamPolicy("folders/2", &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, binding := range policy.Bindings {
+			if binding.Role == "roles/viewer" {
+				return binding.Members
+			}
+		}
+		return nil
+	}
+	setViewers := func(members ...string) {
+		policy := &cloudresourcemanager.Policy{Bindings: []*cloudresourcemanager.Binding{{Role: "roles/viewer", Members: members}}}
+		if _, err := client.Folders.SetIamPolicy("folders/2", &cloudresourcemanager.SetIamPolicyRequest{Policy: policy}).Do(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("should serve the seeded fixture", func(t *testing.T) {
+		project, err := client.Projects.Get("projects/prod-project").Do()
+		if err != nil || project.DisplayName != "Prod Project" {
+			t.Errorf("got %v %v want Prod Project", project, err)
+		}
+	})
+	t.Run("should snapshot and restore", func(t *testing.T) {
+		setViewers("user:bob@testdomain.co")
+		post(t, server.URL+"/admin/snapshot?name=bob", http.StatusNoContent)
+		setViewers("user:carol@testdomain.co")
+		post(t, server.URL+"/admin/restore?name=bob", http.StatusNoContent)
+		if got := viewers(); len(got) != 1 || got[0] != "user:bob@testdomain.co" {
+			t.Errorf("got %v want bob", got)
+		}
+		post(t, server.URL+"/admin/restore?name=missing", http.StatusNotFound)
+	})
+	t.Run("should reset to the fixture", func(t *testing.T) {
+		s.service.Faults.Inject(mockgcp.Fault{Method: "Folders.*", Times: 1, Code: 503})
+		post(t, server.URL+"/admin/reset", http.StatusNoContent)
+		if got := viewers(); len(got) != 1 || got[0] != "group:auditors@testdomain.co" {
+			t.Errorf("got %v want the auditors", got)
+		}
+		if len(s.service.Faults.Unconsumed()) != 0 {
+			t.Errorf("expected the faults to be cleared")
+		}
+	})
+	t.Run("should dump the state", func(t *testing.T) {
+		for _, format := range []string{"json", "yaml"} {
+			resp, err := http.Get(server.URL + "/admin/dump?format=" + format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || !strings.Contains(string(data), "projects/prod-project") {
+				t.Errorf("got %v %s want the fixture as %v", resp.StatusCode, data, format)
+			}
+		}
+	})
+	t.Run("should reject bad admin requests", func(t *testing.T) {
+		post(t, server.URL+"/admin/dump", http.StatusMethodNotAllowed)
+		post(t, server.URL+"/admin/missing", http.StatusNotFound)
+	})
+}
+
+func TestServer_serve(t *testing.T) {
+	s, err := newServer(&config{Fixture: fixturePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	grpcListener, _ := net.Listen("tcp", "127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.TODO())
+	served := make(chan error)
+	go func() { served <- s.serve(ctx, listener, grpcListener) }()
+
+	t.Run("should serve REST", func(t *testing.T) {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/v3/projects/prod-project")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("got %v want %v", resp.StatusCode, http.StatusOK)
+		}
+	})
+	t.Run("should serve gRPC", func(t *testing.T) {
+		conn, err := grpc.Dial(grpcListener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		projects, err := resourcemanager.NewProjectsClient(context.TODO(), option.WithGRPCConn(conn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		project, err := projects.GetProject(context.TODO(), &resourcemanagerpb.GetProjectRequest{Name: "projects/prod-project"})
+		if err != nil || project.DisplayName != "Prod Project" {
+			t.Errorf("got %v %v want Prod Project", project, err)
+		}
+	})
+	t.Run("should stop when the context is done", func(t *testing.T) {
+		cancel()
+		if err := <-served; err != nil {
+			t.Errorf("got %v want nil", err)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package mockgcp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	timeComparisonFormat = regexp.MustCompile(`^request\.time\s*(<=|>=|<|>)\s*timestamp\(\s*["']([^"']+)["']\s*\)$`)
+	nameComparisonFormat = regexp.MustCompile(`^resource\.(name|type|service)\s*(==|!=)\s*["']([^"']*)["']$`)
+	nameFunctionFormat   = regexp.MustCompile(`^resource\.name\.(startsWith|endsWith)\(\s*["']([^"']*)["']\s*\)$`)
+)
+
+// ConditionContext holds the attributes an IAM condition can be evaluated against
+type ConditionContext struct {
+	RequestTime     time.Time
+	ResourceName    string
+	ResourceType    string
+	ResourceService string
+}
+
+// EvaluateCondition evaluates an IAM condition expression against ctx.  It only understands the small part
+// of CEL that policies usually use: request.time compared with a timestamp(), resource.name, resource.type and
+// resource.service compared with == and !=, resource.name.startsWith() and endsWith(), and terms joined with
+// && and || (with && binding tighter) and grouped with parentheses.  Anything else returns an error rather
+// than a guess
+func EvaluateCondition(expression string, ctx ConditionContext) (bool, error) {
+	if strings.TrimSpace(expression) == "" {
+		return true, nil
+	}
+	parser := &conditionParser{expression: expression, ctx: ctx}
+	result, err := parser.parseOr()
+	if err != nil {
+		return false, err
+	}
+	parser.skipSpace()
+	if parser.pos < len(parser.expression) {
+		return false, fmt.Errorf("unexpected %q in condition", parser.expression[parser.pos:])
+	}
+	return result, nil
+}
+
+// conditionParser is a recursive descent parser for condition expressions.  It evaluates as it parses, but
+// always parses both sides of an operator so that unsupported terms are reported whatever they're joined to
+type conditionParser struct {
+	expression string
+	pos        int
+	ctx        ConditionContext
+}
+
+// parseOr parses terms joined with ||
+func (p *conditionParser) parseOr() (bool, error) {
+	result, err := p.parseAnd()
+	if err != nil {
+		return false, err
+	}
+	for p.consume("||") {
+		next, err := p.parseAnd()
+		if err != nil {
+			return false, err
+		}
+		result = result || next
+	}
+	return result, nil
+}
+
+// parseAnd parses terms joined with &&
+func (p *conditionParser) parseAnd() (bool, error) {
+	result, err := p.parseOperand()
+	if err != nil {
+		return false, err
+	}
+	for p.consume("&&") {
+		next, err := p.parseOperand()
+		if err != nil {
+			return false, err
+		}
+		result = result && next
+	}
+	return result, nil
+}
+
+// parseOperand parses a parenthesized expression or a single term
+func (p *conditionParser) parseOperand() (bool, error) {
+	if p.consume("(") {
+		result, err := p.parseOr()
+		if err != nil {
+			return false, err
+		}
+		if !p.consume(")") {
+			return false, fmt.Errorf("missing ) in condition: %v", p.expression)
+		}
+		return result, nil
+	}
+	start := p.pos
+	depth := 0
+	var quote byte
+scan:
+	for ; p.pos < len(p.expression); p.pos++ {
+		c := p.expression[p.pos]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				break scan
+			}
+			depth--
+		case depth == 0 && (strings.HasPrefix(p.expression[p.pos:], "&&") || strings.HasPrefix(p.expression[p.pos:], "||")):
+			break scan
+		}
+	}
+	term := strings.TrimSpace(p.expression[start:p.pos])
+	if term == "" {
+		return false, fmt.Errorf("missing term in condition: %v", p.expression)
+	}
+	return evaluateTerm(term, p.ctx)
+}
+
+// consume skips whitespace and then token if it comes next
+func (p *conditionParser) consume(token string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.expression[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+// skipSpace moves past any whitespace
+func (p *conditionParser) skipSpace() {
+	for p.pos < len(p.expression) && strings.ContainsRune(" \t\r\n", rune(p.expression[p.pos])) {
+		p.pos++
+	}
+}
+
+// evaluateTerm evaluates a single comparison from a condition expression
+func evaluateTerm(term string, ctx ConditionContext) (bool, error) {
+	if match := timeComparisonFormat.FindStringSubmatch(term); match != nil {
+		t, err := time.Parse(time.RFC3339, match[2])
+		if err != nil {
+			return false, fmt.Errorf("invalid timestamp in condition: %v", match[2])
+		}
+		switch match[1] {
+		case "<":
+			return ctx.RequestTime.Before(t), nil
+		case "<=":
+			return !ctx.RequestTime.After(t), nil
+		case ">":
+			return ctx.RequestTime.After(t), nil
+		default:
+			return !ctx.RequestTime.Before(t), nil
+		}
+	}
+	if match := nameComparisonFormat.FindStringSubmatch(term); match != nil {
+		value := ctx.ResourceName
+		switch match[1] {
+		case "type":
+			value = ctx.ResourceType
+		case "service":
+			value = ctx.ResourceService
+		}
+		return (value == match[3]) == (match[2] == "=="), nil
+	}
+	if match := nameFunctionFormat.FindStringSubmatch(term); match != nil {
+		if match[1] == "startsWith" {
+			return strings.HasPrefix(ctx.ResourceName, match[2]), nil
+		}
+		return strings.HasSuffix(ctx.ResourceName, match[2]), nil
+	}
+	return false, fmt.Errorf("unsupported condition: %v", term)
+}
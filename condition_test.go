@@ -0,0 +1,54 @@
+package mockgcp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	ctx := ConditionContext{
+		RequestTime:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		ResourceName: "projects/test-project/secrets/prod-db",
+		ResourceType: "secretmanager.googleapis.com/Secret",
+	}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{``, true},
+		{`request.time < timestamp("2030-01-01T00:00:00Z")`, true},
+		{`request.time >= timestamp("2030-01-01T00:00:00Z")`, false},
+		{`resource.name.startsWith("projects/test-project/secrets/prod-")`, true},
+		{`resource.type == "storage.googleapis.com/Bucket"`, false},
+		{`resource.type == "storage.googleapis.com/Bucket" || resource.name.endsWith("/prod-db")`, true},
+		{`(request.time < timestamp("2020-01-01T00:00:00Z")) && resource.name != ""`, false},
+		{`(resource.type == "storage.googleapis.com/Bucket" && resource.name != "") || resource.name.endsWith("/prod-db")`, true},
+		{`resource.name.endsWith("/prod-db") && (resource.type == "storage.googleapis.com/Bucket" || request.time > timestamp("2030-01-01T00:00:00Z"))`, false},
+		{`resource.name.startsWith("projects/test-project/secrets/prod-") && ((resource.name != "x"))`, true},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := EvaluateCondition(test.expression, ctx)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %v want %v", got, test.want)
+			}
+		})
+	}
+	t.Run("should return err for unsupported expressions", func(t *testing.T) {
+		_, err := EvaluateCondition(`request.auth.claims.foo == "bar"`, ctx)
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+	t.Run("should return err for unbalanced parentheses", func(t *testing.T) {
+		for _, expression := range []string{`(resource.name != ""`, `resource.name != "")`, `resource.name != "" && ()`} {
+			if _, err := EvaluateCondition(expression, ctx); err == nil {
+				t.Errorf("expected an error for %v but got none", expression)
+			}
+		}
+	})
+}
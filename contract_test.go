@@ -0,0 +1,354 @@
+package mockgcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+	option "google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// contractDir is where a recording of the contract suite against a real sandbox is kept
+const contractDir = "testdata/contract"
+
+// contract is the org the contract suite runs against.  Project sits in Folder, which sits in
+// Organization, and Member is added to and removed from each of their policies.  Lifecycle deletes and
+// undeletes Project
+type contract struct {
+	Project      string `json:"project"`
+	Folder       string `json:"folder"`
+	Organization string `json:"organization"`
+	Member       string `json:"member"`
+	Lifecycle    bool   `json:"lifecycle"`
+}
+
+// sandboxContract returns the real sandbox org set in the environment, and false if there isn't one
+func sandboxContract() (contract, bool) {
+	c := contract{
+		Project:      os.Getenv("MOCKGCP_CONTRACT_PROJECT"),
+		Folder:       os.Getenv("MOCKGCP_CONTRACT_FOLDER"),
+		Organization: os.Getenv("MOCKGCP_CONTRACT_ORGANIZATION"),
+		Member:       os.Getenv("MOCKGCP_CONTRACT_MEMBER"),
+		Lifecycle:    os.Getenv("MOCKGCP_CONTRACT_LIFECYCLE") != "",
+	}
+	return c, c.Project != "" && c.Folder != "" && c.Organization != "" && c.Member != ""
+}
+
+// mockContract returns a service seeded with an org for the contract suite
+func mockContract() (*MockService, contract) {
+	service, _ := NewService(context.TODO())
+	service.Organizations.NewOrganization("organizations/1", "testdomain.co", GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co")))
+	service.Folders.NewFolder("folders/2", "Test Folder", GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co"))).Parent = "organizations/1"
+	service.Projects.NewProject("projects/contract-project", "Contract Project", GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co"))).Parent = "folders/2"
+	return service, contract{
+		Project:      "projects/contract-project",
+		Folder:       "folders/2",
+		Organization: "organizations/1",
+		Member:       "user:bob@testdomain.co",
+		Lifecycle:    true,
+	}
+}
+
+// withMember returns policy with member added to or removed from role
+func withMember(policy *cloudresourcemanager.Policy, role, member string, add bool) *cloudresourcemanager.Policy {
+	bindings := []*cloudresourcemanager.Binding{}
+	found := false
+	for _, binding := range policy.Bindings {
+		if binding.Role == role && binding.Condition == nil {
+			found = true
+			members := []string{}
+			for _, m := range binding.Members {
+				if m != member {
+					members = append(members, m)
+				}
+			}
+			if add {
+				members = append(members, member)
+			}
+			if len(members) == 0 {
+				continue
+			}
+			binding.Members = members
+		}
+		bindings = append(bindings, binding)
+	}
+	if add && !found {
+		bindings = append(bindings, NewBinding(role, member))
+	}
+	policy.Bindings = bindings
+	return policy
+}
+
+// hasMember returns true if member has role in policy
+func hasMember(policy *cloudresourcemanager.Policy, role, member string) bool {
+	binding := PolicyContains(policy, role)
+	return binding != nil && BindingContains(binding, member)
+}
+
+// testContract runs the contract suite through client.  It checks the behavior the mock has to share with
+// GCP, so it has to pass against the mock and against a real sandbox alike
+func testContract(t *testing.T, client ClientItf, c contract) {
+	projectID := strings.TrimPrefix(c.Project, "projects/")
+	resources := []struct {
+		name string
+		get  func() (*cloudresourcemanager.Policy, error)
+		set  func(*cloudresourcemanager.Policy) (*cloudresourcemanager.Policy, error)
+	}{
+		{c.Project,
+			func() (*cloudresourcemanager.Policy, error) {
+				return client.ProjectGetIamPolicy(c.Project, &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+			},
+			func(p *cloudresourcemanager.Policy) (*cloudresourcemanager.Policy, error) {
+				return client.ProjectSetIamPolicy(c.Project, &cloudresourcemanager.SetIamPolicyRequest{Policy: p}).Do()
+			}},
+		{c.Folder,
+			func() (*cloudresourcemanager.Policy, error) {
+				return client.FolderGetIamPolicy(c.Folder, &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+			},
+			func(p *cloudresourcemanager.Policy) (*cloudresourcemanager.Policy, error) {
+				return client.FolderSetIamPolicy(c.Folder, &cloudresourcemanager.SetIamPolicyRequest{Policy: p}).Do()
+			}},
+		{c.Organization,
+			func() (*cloudresourcemanager.Policy, error) {
+				return client.OrganizationGetIamPolicy(c.Organization, &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+			},
+			func(p *cloudresourcemanager.Policy) (*cloudresourcemanager.Policy, error) {
+				return client.OrganizationSetIamPolicy(c.Organization, &cloudresourcemanager.SetIamPolicyRequest{Policy: p}).Do()
+			}},
+	}
+	for _, resource := range resources {
+		t.Run("should add and remove a member on "+resource.name, func(t *testing.T) {
+			policy, err := resource.get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			set, err := resource.set(withMember(policy, "roles/viewer", c.Member, true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !hasMember(set, "roles/viewer", c.Member) {
+				t.Errorf("got %v want the set policy returned", set.Bindings)
+			}
+			policy, err = resource.get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !hasMember(policy, "roles/viewer", c.Member) {
+				t.Errorf("got %v want %v as viewer", policy.Bindings, c.Member)
+			}
+			if _, err := resource.set(withMember(policy, "roles/viewer", c.Member, false)); err != nil {
+				t.Fatal(err)
+			}
+			policy, err = resource.get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hasMember(policy, "roles/viewer", c.Member) {
+				t.Errorf("got %v want %v removed", policy.Bindings, c.Member)
+			}
+		})
+	}
+	t.Run("should fail to get the policy of a missing project", func(t *testing.T) {
+		_, err := client.ProjectGetIamPolicy("projects/mockgcp-contract-missing", &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+		var apiError *googleapi.Error
+		if err == nil || (errors.As(err, &apiError) && apiError.Code != http.StatusForbidden && apiError.Code != http.StatusNotFound) {
+			t.Errorf("got %v want permission denied or not found", err)
+		}
+	})
+	t.Run("should search projects by id and parent", func(t *testing.T) {
+		for _, query := range []string{"id:" + projectID, "parent:" + c.Folder} {
+			response, err := client.ProjectsSearch().Query(query).Do()
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, project := range response.Projects {
+				found = found || project.ProjectId == projectID
+			}
+			if !found {
+				t.Errorf("got %v want %v found by %v", response.Projects, projectID, query)
+			}
+		}
+	})
+	t.Run("should search folders by parent", func(t *testing.T) {
+		response, err := client.FoldersSearch().Query("parent:" + c.Organization).Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, folder := range response.Folders {
+			found = found || folder.Name == c.Folder
+		}
+		if !found {
+			t.Errorf("got %v want %v", response.Folders, c.Folder)
+		}
+	})
+	t.Run("should search organizations", func(t *testing.T) {
+		response, err := client.OrganizationsSearch().Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, organization := range response.Organizations {
+			found = found || organization.Name == c.Organization
+		}
+		if !found {
+			t.Errorf("got %v want %v", response.Organizations, c.Organization)
+		}
+	})
+	t.Run("should reject a search on an unknown field", func(t *testing.T) {
+		_, err := client.ProjectsSearch().Query("mockgcpUnknownField:x").Do()
+		var apiError *googleapi.Error
+		if !errors.As(err, &apiError) || apiError.Code != http.StatusBadRequest {
+			t.Errorf("got %v want a 400", err)
+		}
+	})
+	if !c.Lifecycle {
+		return
+	}
+	t.Run("should delete and undelete the project", func(t *testing.T) {
+		if _, err := client.ProjectsDelete(c.Project).Do(); err != nil {
+			t.Fatal(err)
+		}
+		project, err := client.ProjectsGet(c.Project).Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.State != "DELETE_REQUESTED" {
+			t.Errorf("got %v want DELETE_REQUESTED", project.State)
+		}
+		response, err := client.ProjectsSearch().Query("id:" + projectID + " state:ACTIVE").Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Projects) != 0 {
+			t.Errorf("got %v want no active project", response.Projects)
+		}
+		if _, err := client.ProjectsUndelete(c.Project, &cloudresourcemanager.UndeleteProjectRequest{}).Do(); err != nil {
+			t.Fatal(err)
+		}
+		project, err = client.ProjectsGet(c.Project).Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.State != "ACTIVE" {
+			t.Errorf("got %v want ACTIVE", project.State)
+		}
+	})
+}
+
+// recordContract runs the contract suite through a Recorder over transport, and saves the cassette and the
+// contract it was recorded with in dir
+func recordContract(t *testing.T, dir, endpoint string, transport http.RoundTripper, c contract) {
+	recorder := NewRecorder(RecordMode, nil, transport)
+	opts := []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: recorder})}
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+	client, err := NewRealClient(context.TODO(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testContract(t, client, c)
+	data, _ := json.MarshalIndent(c, "", "  ")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "contract.json"), append(data, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Cassette.Save(filepath.Join(dir, "cassette.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// replayContract runs the contract suite against a cassette saved by recordContract, and then against the
+// mock loaded from the same cassette, so the mock has to answer the way GCP did
+func replayContract(t *testing.T, dir string) {
+	data, err := os.ReadFile(filepath.Join(dir, "contract.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := contract{}
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("recorded", func(t *testing.T) {
+		cassette, err := LoadCassette(filepath.Join(dir, "cassette.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := NewRealClient(context.TODO(), option.WithHTTPClient(&http.Client{Transport: NewRecorder(ReplayMode, cassette, nil)}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		testContract(t, client, c)
+	})
+	t.Run("mock", func(t *testing.T) {
+		cassette, err := LoadCassette(filepath.Join(dir, "cassette.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		service, _ := NewService(context.TODO())
+		if err := service.ImportCassette(cassette); err != nil {
+			t.Fatal(err)
+		}
+		testContract(t, &GCPClient{Service: service}, c)
+	})
+}
+
+func TestContract(t *testing.T) {
+	t.Run("GCPClient", func(t *testing.T) {
+		service, c := mockContract()
+		testContract(t, &GCPClient{Service: service}, c)
+	})
+	t.Run("RealClient", func(t *testing.T) {
+		service, c := mockContract()
+		testContract(t, &RealClient{Service: servedClient(t, service)}, c)
+	})
+	t.Run("sandbox", func(t *testing.T) {
+		c, ok := sandboxContract()
+		if !ok {
+			t.Skip("set MOCKGCP_CONTRACT_PROJECT, MOCKGCP_CONTRACT_FOLDER, MOCKGCP_CONTRACT_ORGANIZATION and MOCKGCP_CONTRACT_MEMBER to run against a real sandbox org")
+		}
+		transport, err := htransport.NewTransport(context.TODO(), http.DefaultTransport, option.WithScopes(cloudresourcemanager.CloudPlatformScope))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if os.Getenv("MOCKGCP_CONTRACT_RECORD") != "" {
+			recordContract(t, contractDir, "", transport, c)
+			return
+		}
+		client, err := NewRealClient(context.TODO(), option.WithHTTPClient(&http.Client{Transport: transport}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		testContract(t, client, c)
+	})
+	t.Run("cassette", func(t *testing.T) {
+		if _, err := os.Stat(filepath.Join(contractDir, "cassette.jsonl")); err != nil {
+			t.Skip("record a cassette against a sandbox org with MOCKGCP_CONTRACT_RECORD=1 to run offline")
+		}
+		replayContract(t, contractDir)
+	})
+}
+
+func TestContract_replay(t *testing.T) {
+	t.Run("should replay a recording and check the mock against it", func(t *testing.T) {
+		service, c := mockContract()
+		server := httptest.NewServer(service.Handler())
+		defer server.Close()
+		dir := t.TempDir()
+		recordContract(t, dir, server.URL+"/", http.DefaultTransport, c)
+		replayContract(t, dir)
+	})
+}
@@ -0,0 +1,290 @@
+package mockgcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// expectableMethods are the GCPClient methods that can be given expectations
+var expectableMethods = map[string]bool{
+	"ProjectGetIamPolicy":      true,
+	"ProjectSetIamPolicy":      true,
+	"FolderGetIamPolicy":       true,
+	"FolderSetIamPolicy":       true,
+	"OrganizationGetIamPolicy": true,
+	"OrganizationSetIamPolicy": true,
+	"ProjectsSearch":           true,
+	"ProjectsGet":              true,
+	"ProjectsDelete":           true,
+	"ProjectsUndelete":         true,
+	"FoldersSearch":            true,
+	"OrganizationsSearch":      true,
+}
+
+// CleanupT is the part of *testing.T that Expectations use to check themselves when the test ends
+type CleanupT interface {
+	TestingT
+	Cleanup(func())
+}
+
+// Matcher decides if an argument of a call is the one an Expectation is waiting for
+type Matcher interface {
+	Matches(x interface{}) bool
+	String() string
+}
+
+type anyMatcher struct{}
+
+func (anyMatcher) Matches(x interface{}) bool { return true }
+func (anyMatcher) String() string             { return "any" }
+
+// Any returns a Matcher that matches any argument
+func Any() Matcher {
+	return anyMatcher{}
+}
+
+type eqMatcher struct {
+	want interface{}
+}
+
+func (m eqMatcher) Matches(x interface{}) bool { return reflect.DeepEqual(m.want, x) }
+func (m eqMatcher) String() string             { return fmt.Sprintf("%v", m.want) }
+
+// Eq returns a Matcher that matches arguments reflect.DeepEqual to want.  Plain values given to With are
+// matched with Eq
+func Eq(want interface{}) Matcher {
+	return eqMatcher{want: want}
+}
+
+type funcMatcher struct {
+	description string
+	matches     func(x interface{}) bool
+}
+
+func (m funcMatcher) Matches(x interface{}) bool { return m.matches(x) }
+func (m funcMatcher) String() string             { return m.description }
+
+// MatchFunc returns a Matcher that matches arguments matches returns true for, described by description in
+// test failures
+func MatchFunc(description string, matches func(x interface{}) bool) Matcher {
+	return funcMatcher{description: description, matches: matches}
+}
+
+// Expectation is a call a GCPClient in expectation mode is told to expect, along with what it returns.
+// It expects exactly one call unless Times or AnyTimes says otherwise
+type Expectation struct {
+	method   string
+	args     []Matcher
+	policy   *cloudresourcemanager.Policy
+	response interface{}
+	err      error
+	min      int
+	max      int
+	calls    int
+}
+
+// With sets the matchers for the call's arguments, the resource and then the request.  Arguments that
+// aren't Matchers are matched with Eq, and missing arguments match anything
+func (e *Expectation) With(args ...interface{}) *Expectation {
+	e.args = nil
+	for _, arg := range args {
+		matcher, ok := arg.(Matcher)
+		if !ok {
+			matcher = Eq(arg)
+		}
+		e.args = append(e.args, matcher)
+	}
+	return e
+}
+
+// Return sets the policy and error the expected call's Do() returns
+func (e *Expectation) Return(policy *cloudresourcemanager.Policy, err error) *Expectation {
+	e.policy = policy
+	e.err = err
+	return e
+}
+
+// Respond sets the response and error the expected call's Do() returns, for the methods served by the
+// mock's Handler (ProjectsSearch, ProjectsGet, ProjectsDelete, ProjectsUndelete, FoldersSearch and
+// OrganizationsSearch).  response is what that method's Do() returns, such as a
+// *cloudresourcemanager.SearchProjectsResponse for ProjectsSearch or an *cloudresourcemanager.Operation
+// for ProjectsDelete
+func (e *Expectation) Respond(response interface{}, err error) *Expectation {
+	e.response = response
+	e.err = err
+	return e
+}
+
+// Times sets how many calls are expected
+func (e *Expectation) Times(n int) *Expectation {
+	e.min, e.max = n, n
+	return e
+}
+
+// AnyTimes lets the expectation be called any number of times, including none
+func (e *Expectation) AnyTimes() *Expectation {
+	e.min, e.max = 0, -1
+	return e
+}
+
+// matches returns true if the expectation is waiting for a call to method with args
+func (e *Expectation) matches(method string, args []interface{}) bool {
+	if e.method != method || (e.max >= 0 && e.calls >= e.max) {
+		return false
+	}
+	for i, matcher := range e.args {
+		if i >= len(args) || !matcher.Matches(args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// String describes the expectation for test failures
+func (e *Expectation) String() string {
+	args := []string{}
+	for _, matcher := range e.args {
+		args = append(args, matcher.String())
+	}
+	return fmt.Sprintf("%v(%v)", e.method, strings.Join(args, ", "))
+}
+
+// Expectations are the calls a GCPClient built with NewExpectClient expects.  Instead of answering from
+// the stateful MockService, each of the client's calls is matched against the expectations in the order
+// they were made, and answered by the first one that matches and hasn't been used up.  Searches and
+// project gets, deletes and undeletes are matched on the arguments given to the GCPClient method, and each
+// page a search asks for is a call of its own.  When the test ends, expectations that weren't met and
+// calls that weren't expected fail it
+type Expectations struct {
+	t CleanupT
+
+	mu         sync.Mutex
+	expected   []*Expectation
+	unexpected []string
+}
+
+// NewExpectClient returns a GCPClient in expectation mode, along with the Expectations to program it with.
+// The expectations are checked when t cleans up
+func NewExpectClient(t CleanupT) (*GCPClient, *Expectations) {
+	service, _ := NewService(context.TODO())
+	expectations := &Expectations{t: t}
+	t.Cleanup(expectations.Finish)
+	return &GCPClient{Service: service, expectations: expectations}, expectations
+}
+
+// Expect adds an expectation for a call to one of the GCPClient's methods, named the way they are on
+// GCPClient (ProjectGetIamPolicy, ProjectsSearch)
+func (e *Expectations) Expect(method string) *Expectation {
+	e.t.Helper()
+	if !expectableMethods[method] {
+		e.t.Errorf("%v can't be given expectations", method)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	expectation := &Expectation{method: method, min: 1, max: 1}
+	e.expected = append(e.expected, expectation)
+	return expectation
+}
+
+// Finish fails the test for every expectation that wasn't met and every call that wasn't expected.  It's
+// run when the test cleans up, and can be called earlier
+func (e *Expectations) Finish() {
+	e.t.Helper()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, expectation := range e.expected {
+		if expectation.calls < expectation.min {
+			e.t.Errorf("missing call to %v: got %v calls want %v", expectation, expectation.calls, expectation.min)
+		}
+	}
+	for _, call := range e.unexpected {
+		e.t.Errorf("unexpected call to %v", call)
+	}
+	e.unexpected = nil
+}
+
+// call returns a call that answers from the expectations when Do() is run
+func (e *Expectations) call(method string, args ...interface{}) PolicyCallItf {
+	return &expectedCall{expectations: e, method: method, args: args}
+}
+
+// answer finds the expectation for a call and returns what it's set to return
+func (e *Expectations) answer(method string, args []interface{}) (*cloudresourcemanager.Policy, error) {
+	expectation, err := e.find(method, args)
+	if err != nil {
+		return nil, err
+	}
+	return copyPolicy(expectation.policy), expectation.err
+}
+
+// find returns the expectation that answers a call, and counts the call against it.  A call no
+// expectation matches is recorded as unexpected, and fails with the error returned
+func (e *Expectations) find(method string, args []interface{}) (*Expectation, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, expectation := range e.expected {
+		if expectation.matches(method, args) {
+			expectation.calls++
+			return expectation, nil
+		}
+	}
+	return nil, e.unexpectedCall(method, args)
+}
+
+// unexpectedCall records a call no expectation matched, and returns the error it fails with.  The caller
+// holds mu
+func (e *Expectations) unexpectedCall(method string, args []interface{}) error {
+	formatted := []string{}
+	for _, arg := range args {
+		formatted = append(formatted, fmt.Sprintf("%v", arg))
+	}
+	description := fmt.Sprintf("%v(%v)", method, strings.Join(formatted, ", "))
+	e.unexpected = append(e.unexpected, description)
+	return &googleapi.Error{Code: 400, Message: "unexpected call to " + description}
+}
+
+// client returns a resource manager client for a GCPClient method served by the mock's Handler.  Each
+// request made through it is answered by the expectation that matches method and args
+func (e *Expectations) client(method string, args ...interface{}) *cloudresourcemanager.Service {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		expectation, err := e.find(method, args)
+		if err == nil {
+			err = expectation.err
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		if expectation.response == nil {
+			w.Write([]byte("{}"))
+			return
+		}
+		json.NewEncoder(w).Encode(expectation.response)
+	})
+	client, _ := cloudresourcemanager.NewService(context.TODO(),
+		option.WithEndpoint("http://mockgcp/"),
+		option.WithHTTPClient(&http.Client{Transport: handlerTransport{handler: handler}}))
+	return client
+}
+
+// expectedCall is the PolicyCallItf a GCPClient in expectation mode returns
+type expectedCall struct {
+	expectations *Expectations
+	method       string
+	args         []interface{}
+}
+
+// Do will be called on expectedCall and return what the matching expectation is set to return
+func (c *expectedCall) Do(opts ...googleapi.CallOption) (*cloudresourcemanager.Policy, error) {
+	return c.expectations.answer(c.method, c.args)
+}
@@ -0,0 +1,106 @@
+package mockgcp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+)
+
+func TestNewExpectClient(t *testing.T) {
+	policy := GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co"))
+
+	t.Run("should return the programmed responses in order", func(t *testing.T) {
+		client, expectations := NewExpectClient(t)
+		expectations.Expect("ProjectGetIamPolicy").With("projects/test-project", Any()).Return(policy, nil)
+		expectations.Expect("ProjectGetIamPolicy").With("projects/test-project").Return(nil, &googleapi.Error{Code: 503})
+
+		got, err := client.ProjectGetIamPolicy("projects/test-project", &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+		if err != nil || !BindingContains(PolicyContains(got, "roles/owner"), "user:alice@testdomain.co") {
+			t.Errorf("got %v %v want the programmed policy", got, err)
+		}
+		_, err = client.ProjectGetIamPolicy("projects/test-project", nil).Do()
+		var apiError *googleapi.Error
+		if !errors.As(err, &apiError) || apiError.Code != 503 {
+			t.Errorf("got %v want a 503", err)
+		}
+	})
+	t.Run("should match requests", func(t *testing.T) {
+		client, expectations := NewExpectClient(t)
+		request := &cloudresourcemanager.SetIamPolicyRequest{Policy: policy}
+		expectations.Expect("FolderSetIamPolicy").With("folders/2", request).Return(policy, nil)
+		expectations.Expect("OrganizationSetIamPolicy").With(Any(), MatchFunc("a policy with an owner", func(x interface{}) bool {
+			r, ok := x.(*cloudresourcemanager.SetIamPolicyRequest)
+			return ok && PolicyContains(r.Policy, "roles/owner") != nil
+		})).Return(policy, nil).AnyTimes()
+
+		if _, err := client.FolderSetIamPolicy("folders/2", &cloudresourcemanager.SetIamPolicyRequest{Policy: policy}).Do(); err != nil {
+			t.Error(err)
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := client.OrganizationSetIamPolicy("organizations/1", request).Do(); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	t.Run("should fail on cleanup for unmet and unexpected calls", func(t *testing.T) {
+		fake := &fakeT{}
+		client, expectations := NewExpectClient(fake)
+		expectations.Expect("ProjectSetIamPolicy").With("projects/test-project").Return(policy, nil).Times(2)
+		client.ProjectSetIamPolicy("projects/test-project", nil).Do()
+		if _, err := client.FolderGetIamPolicy("folders/2", nil).Do(); err == nil {
+			t.Errorf("expected an unexpected call to fail")
+		}
+		if len(fake.errors) != 0 {
+			t.Errorf("got %v want no failures before cleanup", fake.errors)
+		}
+		fake.cleanup()
+		if len(fake.errors) != 2 || !strings.Contains(fake.errors[0], "ProjectSetIamPolicy") || !strings.Contains(fake.errors[1], "FolderGetIamPolicy(folders/2") {
+			t.Errorf("got %v", fake.errors)
+		}
+	})
+	t.Run("should answer calls served by the handler", func(t *testing.T) {
+		client, expectations := NewExpectClient(t)
+		project := &cloudresourcemanager.Project{Name: "projects/123", ProjectId: "test-project"}
+		expectations.Expect("ProjectsGet").With("projects/123").Respond(project, nil)
+		expectations.Expect("ProjectsSearch").Respond(&cloudresourcemanager.SearchProjectsResponse{Projects: []*cloudresourcemanager.Project{project}}, nil)
+		expectations.Expect("ProjectsDelete").With("projects/123").Respond(nil, &googleapi.Error{Code: 403})
+
+		got, err := client.ProjectsGet("projects/123").Do()
+		if err != nil || got.ProjectId != "test-project" {
+			t.Errorf("got %v %v want the programmed project", got, err)
+		}
+		search, err := client.ProjectsSearch().Query("id:test-project").Do()
+		if err != nil || len(search.Projects) != 1 || search.Projects[0].Name != "projects/123" {
+			t.Errorf("got %v %v want the programmed search", search, err)
+		}
+		_, err = client.ProjectsDelete("projects/123").Do()
+		var apiError *googleapi.Error
+		if !errors.As(err, &apiError) || apiError.Code != 403 {
+			t.Errorf("got %v want a 403", err)
+		}
+	})
+	t.Run("should record calls served by the handler that weren't expected", func(t *testing.T) {
+		fake := &fakeT{}
+		client, _ := NewExpectClient(fake)
+		if _, err := client.ProjectsGet("projects/test-project").Do(); err == nil {
+			t.Errorf("expected an unexpected call to fail")
+		}
+		if _, err := client.FoldersSearch().Query("displayName:prod").Do(); err == nil {
+			t.Errorf("expected an unexpected call to fail")
+		}
+		fake.cleanup()
+		if len(fake.errors) != 2 || !strings.Contains(fake.errors[0], "ProjectsGet(projects/test-project)") || !strings.Contains(fake.errors[1], "FoldersSearch()") {
+			t.Errorf("got %v", fake.errors)
+		}
+	})
+	t.Run("should leave the stateful client alone", func(t *testing.T) {
+		client := NewClient()
+		client.Service.Projects.NewProject("projects/test-project", "", policy)
+		if _, err := client.ProjectGetIamPolicy("projects/test-project", nil).Do(); err != nil {
+			t.Error(err)
+		}
+	})
+}
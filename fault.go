@@ -0,0 +1,164 @@
+package mockgcp
+
+import (
+	"math/rand"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	googleapi "google.golang.org/api/googleapi"
+)
+
+// Fault is a scripted failure or delay for calls made through the mock.  Method is the service and method
+// the call was made on, the way it's written in Go (Projects.SetIamPolicy, ServiceAccounts.Keys.Create),
+// and Resource is the resource name the call was made with.  Both are path.Match patterns, so
+// Projects.* matches every project call, and empty matches everything.
+//
+// The fault first fires on the Nth call it matches (the first if Nth is 0), then on every matching call
+// until it has fired Times times.  A fault without Times fires once, and one with a Times of -1 fires
+// forever.  If Percent is set, each call the fault could fire on is only hit that percentage of the time,
+// and Times still counts the hits, so a Percent fault without a Times of -1 fires once and then stops.
+// A fault with a Code returns a googleapi.Error with that code (such as 429 or 503) and Message, and
+// Latency is added to the call before it runs or fails.  Latency waits on the service's Clock, so with a
+// FakeClock the call waits until the clock is advanced (see FakeClock)
+type Fault struct {
+	Method   string
+	Resource string
+	Nth      int
+	Times    int
+	Percent  float64
+	Code     int
+	Message  string
+	Latency  time.Duration
+}
+
+// firings returns how many times the fault is scripted to fire, or -1 if it fires forever
+func (f Fault) firings() int {
+	switch {
+	case f.Times < 0:
+		return -1
+	case f.Times == 0:
+		return 1
+	}
+	return f.Times
+}
+
+// scriptedFault is a fault along with how many calls it has matched and fired on
+type scriptedFault struct {
+	Fault
+	matched int
+	fired   int
+}
+
+// matches returns true if the fault is scripted for a call to method on resource
+func (f *scriptedFault) matches(method, resource string) bool {
+	if f.Method != "" {
+		if ok, _ := path.Match(f.Method, method); !ok {
+			return false
+		}
+	}
+	if f.Resource != "" {
+		if ok, _ := path.Match(f.Resource, resource); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// TestingT is the part of *testing.T the assertion helpers use
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// FaultsService holds the faults scripted on a MockService.  Faults apply to every Do() call, and to
+// requests made through the REST Handler, so retry and backoff logic can be tested either way
+type FaultsService struct {
+	Service *MockService
+
+	mu     sync.Mutex
+	faults []*scriptedFault
+	rand   *rand.Rand
+}
+
+// NewFaultsService returns a FaultsService with no faults scripted
+func NewFaultsService(s *MockService) *FaultsService {
+	rs := &FaultsService{Service: s, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	return rs
+}
+
+// Inject scripts a fault.  Faults are checked in the order they were injected, and the first one to fail
+// a call decides its error
+func (r *FaultsService) Inject(fault Fault) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.faults = append(r.faults, &scriptedFault{Fault: fault})
+}
+
+// Seed seeds the random numbers used by Percent faults, so a test sees the same calls fail on every run
+func (r *FaultsService) Seed(seed int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rand = rand.New(rand.NewSource(seed))
+}
+
+// Clear removes every scripted fault
+func (r *FaultsService) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.faults = nil
+}
+
+// Unconsumed returns the faults that haven't fired as many times as they were scripted to, including
+// faults waiting for their Nth call.  Faults that fire forever are never unconsumed
+func (r *FaultsService) Unconsumed() []Fault {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	unconsumed := []Fault{}
+	for _, fault := range r.faults {
+		if firings := fault.firings(); firings > 0 && fault.fired < firings {
+			unconsumed = append(unconsumed, fault.Fault)
+		}
+	}
+	return unconsumed
+}
+
+// AssertConsumed fails the test for each scripted fault that hasn't fired as many times as it was
+// scripted to
+func (r *FaultsService) AssertConsumed(t TestingT) {
+	t.Helper()
+	for _, fault := range r.Unconsumed() {
+		t.Errorf("fault on %v %v fired fewer than %v times", fault.Method, fault.Resource, fault.firings())
+	}
+}
+
+// check runs the scripted faults against a call to method on resource, and returns the latency to add
+// and the error to fail it with, if any
+func (r *FaultsService) check(method, resource string) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var latency time.Duration
+	for _, fault := range r.faults {
+		if !fault.matches(method, resource) {
+			continue
+		}
+		fault.matched++
+		if firings := fault.firings(); fault.matched < fault.Nth || (firings > 0 && fault.fired >= firings) {
+			continue
+		}
+		if fault.Percent > 0 && r.rand.Float64()*100 >= fault.Percent {
+			continue
+		}
+		fault.fired++
+		latency += fault.Latency
+		if fault.Code != 0 {
+			message := fault.Message
+			if message == "" {
+				message = http.StatusText(fault.Code)
+			}
+			return latency, &googleapi.Error{Code: fault.Code, Message: message}
+		}
+	}
+	return latency, nil
+}
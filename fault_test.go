@@ -0,0 +1,189 @@
+package mockgcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+)
+
+// fakeT records the failures of an assertion helper, and the cleanups it registers
+type fakeT struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+// cleanup runs the registered cleanups, like a test ending
+func (t *fakeT) cleanup() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestFaultsService(t *testing.T) {
+	setPolicy := func(service *MockService, project string) error {
+		_, err := service.Projects.SetIamPolicy(project, &cloudresourcemanager.SetIamPolicyRequest{
+			Policy: GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co")),
+		}).Do()
+		return err
+	}
+	newService := func() *MockService {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+		service.Projects.NewProject("projects/other-project", "", nil)
+		return service
+	}
+
+	t.Run("should fail only the Nth call", func(t *testing.T) {
+		service := newService()
+		service.Faults.Inject(Fault{Method: "Projects.SetIamPolicy", Nth: 2, Times: 1, Code: 429})
+		codes := []int{}
+		for i := 0; i < 3; i++ {
+			code := 0
+			var apiError *googleapi.Error
+			if err := setPolicy(service, "projects/test-project"); errors.As(err, &apiError) {
+				code = apiError.Code
+			}
+			codes = append(codes, code)
+		}
+		if fmt.Sprint(codes) != "[0 429 0]" {
+			t.Errorf("got %v want %v", codes, "[0 429 0]")
+		}
+		if len(service.AuditLogs.Entries) != 2 {
+			t.Errorf("expected the failed call to leave no audit log, got %v entries", len(service.AuditLogs.Entries))
+		}
+	})
+	t.Run("should fire an Nth fault without Times once", func(t *testing.T) {
+		service := newService()
+		service.Faults.Inject(Fault{Method: "Projects.SetIamPolicy", Nth: 2, Code: 429})
+		codes := []int{}
+		for i := 0; i < 4; i++ {
+			code := 0
+			if err := setPolicy(service, "projects/test-project"); err != nil {
+				code = errorCode(err)
+			}
+			codes = append(codes, code)
+		}
+		if fmt.Sprint(codes) != "[0 429 0 0]" {
+			t.Errorf("got %v want %v", codes, "[0 429 0 0]")
+		}
+	})
+	t.Run("should only fail the scripted resource", func(t *testing.T) {
+		service := newService()
+		service.Faults.Inject(Fault{Method: "Projects.*", Resource: "projects/test-project", Times: -1, Code: 503})
+		if err := setPolicy(service, "projects/other-project"); err != nil {
+			t.Errorf("got %v want nil", err)
+		}
+		if err := setPolicy(service, "projects/test-project"); errorCode(err) != 503 {
+			t.Errorf("got %v want a 503", err)
+		}
+		if _, err := service.Projects.GetIamPolicy("projects/test-project", nil).Do(); errorCode(err) != 503 {
+			t.Errorf("got %v want a 503", err)
+		}
+	})
+	t.Run("should fail a percentage of calls", func(t *testing.T) {
+		service := newService()
+		service.Faults.Seed(1)
+		service.Faults.Inject(Fault{Method: "Projects.SetIamPolicy", Percent: 25, Times: -1, Code: 503})
+		failures := 0
+		for i := 0; i < 400; i++ {
+			if setPolicy(service, "projects/test-project") != nil {
+				failures++
+			}
+		}
+		if failures < 60 || failures > 140 {
+			t.Errorf("got %v failures want about 100", failures)
+		}
+	})
+	t.Run("should add latency", func(t *testing.T) {
+		service := newService()
+		service.Faults.Inject(Fault{Method: "Projects.SetIamPolicy", Latency: 50 * time.Millisecond})
+		start := time.Now()
+		if err := setPolicy(service, "projects/test-project"); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("got %v want at least 50ms", elapsed)
+		}
+	})
+	t.Run("should report unconsumed faults", func(t *testing.T) {
+		service := newService()
+		service.Faults.Inject(Fault{Method: "Projects.SetIamPolicy", Times: 2, Code: 429})
+		service.Faults.Inject(Fault{Method: "Projects.Delete", Percent: 50, Times: -1, Code: 503})
+		setPolicy(service, "projects/test-project")
+		fake := &fakeT{}
+		service.Faults.AssertConsumed(fake)
+		if len(fake.errors) != 1 {
+			t.Errorf("got %v want 1 failure", fake.errors)
+		}
+		setPolicy(service, "projects/test-project")
+		fake = &fakeT{}
+		service.Faults.AssertConsumed(fake)
+		if len(fake.errors) != 0 {
+			t.Errorf("got %v want no failures", fake.errors)
+		}
+	})
+	t.Run("should report an Nth fault that hasn't fired", func(t *testing.T) {
+		service := newService()
+		service.Faults.Inject(Fault{Method: "Projects.SetIamPolicy", Nth: 2, Code: 429})
+		setPolicy(service, "projects/test-project")
+		if unconsumed := service.Faults.Unconsumed(); len(unconsumed) != 1 {
+			t.Errorf("got %v want the Nth fault", unconsumed)
+		}
+		setPolicy(service, "projects/test-project")
+		if unconsumed := service.Faults.Unconsumed(); len(unconsumed) != 0 {
+			t.Errorf("got %v want none", unconsumed)
+		}
+	})
+}
+
+func TestMockService_beginCall(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject("projects/test-project", "", nil)
+	service.Buckets.NewBucket("test-project", "test-bucket", nil)
+
+	t.Run("should fail calls with a canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		_, err := service.Projects.GetIamPolicy("projects/test-project", nil).Context(ctx).Do()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v want %v", err, context.Canceled)
+		}
+		if err := service.Buckets.Delete("test-bucket").Context(ctx).Do(); !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v want %v", err, context.Canceled)
+		}
+		if service.Buckets.find("test-bucket") == nil {
+			t.Errorf("expected the bucket to be left alone")
+		}
+	})
+	t.Run("should hit the deadline during injected latency", func(t *testing.T) {
+		service.Faults.Inject(Fault{Method: "Projects.SetIamPolicy", Times: 1, Latency: time.Minute})
+		ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := service.Projects.SetIamPolicy("projects/test-project", &cloudresourcemanager.SetIamPolicyRequest{Policy: GeneratePolicy()}).Context(ctx).Do()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v want %v", err, context.DeadlineExceeded)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("got %v want the call to return at the deadline", elapsed)
+		}
+		if len(service.AuditLogs.Entries) != 0 {
+			t.Errorf("expected the policy to be left alone")
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package mockgcp
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	cloudasset "google.golang.org/api/cloudasset/v1"
+)
+
+// AssetFeedFilter picks which changes an asset feed subscriber gets.  AssetTypes are regular expressions
+// like the ones Cloud Asset feeds take (storage.googleapis.com.*), and NamePrefix matches the start of the
+// full (//storage.googleapis.com/bucket) or short (projects/x) resource name.  Empty fields match everything
+type AssetFeedFilter struct {
+	AssetTypes []string
+	NamePrefix string
+}
+
+// matches returns true if the change to asset passes the filter
+func (f AssetFeedFilter) matches(asset *cloudasset.Asset) bool {
+	if !assetTypeMatches(asset.AssetType, f.AssetTypes) {
+		return false
+	}
+	if f.NamePrefix == "" || strings.HasPrefix(asset.Name, f.NamePrefix) {
+		return true
+	}
+	short := strings.SplitN(strings.TrimPrefix(asset.Name, "//"), "/", 2)
+	return len(short) == 2 && strings.HasPrefix(short[1], f.NamePrefix)
+}
+
+// assetFeeds holds the subscribers to the service's asset changes
+type assetFeeds struct {
+	mu          sync.Mutex
+	next        int
+	subscribers map[int]*assetSubscriber
+}
+
+// assetSubscriber is a single subscription to asset changes
+type assetSubscriber struct {
+	filter AssetFeedFilter
+	notify func(*cloudasset.TemporalAsset)
+}
+
+// OnAssetChange calls fn with a TemporalAsset for every resource or policy change that passes filter: new
+// resources, SetIamPolicy calls, project moves and deletes.  fn is called on the goroutine making the change,
+// after it is made.  Call the returned function to stop the subscription
+func (s *MockService) OnAssetChange(filter AssetFeedFilter, fn func(*cloudasset.TemporalAsset)) (cancel func()) {
+	s.feeds.mu.Lock()
+	defer s.feeds.mu.Unlock()
+	if s.feeds.subscribers == nil {
+		s.feeds.subscribers = map[int]*assetSubscriber{}
+	}
+	id := s.feeds.next
+	s.feeds.next++
+	s.feeds.subscribers[id] = &assetSubscriber{filter: filter, notify: fn}
+	return func() {
+		s.feeds.mu.Lock()
+		defer s.feeds.mu.Unlock()
+		delete(s.feeds.subscribers, id)
+	}
+}
+
+// WatchAssetChanges returns a channel that receives the changes OnAssetChange would pass to its callback.
+// The channel holds up to buffer changes, and after that the change being made blocks until the channel is
+// read or the subscription is stopped, so tests making changes on the same goroutine should size it for
+// them.  Call the returned function to stop the subscription and close the channel
+func (s *MockService) WatchAssetChanges(filter AssetFeedFilter, buffer int) (<-chan *cloudasset.TemporalAsset, func()) {
+	changes := make(chan *cloudasset.TemporalAsset, buffer)
+	done := make(chan struct{})
+	var mu sync.Mutex
+	var sending sync.WaitGroup
+	closed := false
+	cancel := s.OnAssetChange(filter, func(change *cloudasset.TemporalAsset) {
+		mu.Lock()
+		if closed {
+			mu.Unlock()
+			return
+		}
+		sending.Add(1)
+		mu.Unlock()
+		defer sending.Done()
+		select {
+		case changes <- change:
+		case <-done:
+		}
+	})
+	return changes, func() {
+		cancel()
+		mu.Lock()
+		if closed {
+			mu.Unlock()
+			return
+		}
+		closed = true
+		mu.Unlock()
+		close(done)
+		sending.Wait()
+		close(changes)
+	}
+}
+
+// hasAssetSubscribers returns true if anyone is listening for asset changes
+func (s *MockService) hasAssetSubscribers() bool {
+	s.feeds.mu.Lock()
+	defer s.feeds.mu.Unlock()
+	return len(s.feeds.subscribers) > 0
+}
+
+// assetSnapshot returns the Cloud Asset record, with both resource and policy, for the resource holding
+// holder.  It returns nil if the resource isn't in the service, or if nobody is subscribed to changes so
+// we don't pay for it
+func (s *MockService) assetSnapshot(holder *IamPolicyHolder) *cloudasset.Asset {
+	if !s.hasAssetSubscribers() {
+		return nil
+	}
+	nodes := s.resourceNodes()
+	index := newResourceIndex(nodes)
+	for _, node := range nodes {
+		if node.Holder != holder {
+			continue
+		}
+		item := newAssetScope(node, index)
+		asset := &cloudasset.Asset{
+			Name:       node.Name,
+			AssetType:  node.AssetType,
+			Ancestors:  item.ancestors,
+			Resource:   &cloudasset.Resource{Parent: node.Parent},
+			UpdateTime: s.now().UTC().Format(time.RFC3339Nano),
+		}
+		if policy := node.Holder.GetIamPolicy(); hasBindings(policy) {
+			asset.IamPolicy, _ = assetPolicy(policy)
+		}
+		return asset
+	}
+	return nil
+}
+
+// publishAssetChange sends a TemporalAsset to the subscribers whose filter matches.  prior is the asset
+// before the change and asset after it: a nil prior is a new resource and a nil asset a deleted one
+func (s *MockService) publishAssetChange(prior, asset *cloudasset.Asset) {
+	if prior == nil && asset == nil {
+		return
+	}
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	change := &cloudasset.TemporalAsset{
+		Asset:           asset,
+		PriorAsset:      prior,
+		PriorAssetState: "PRESENT",
+		Window:          &cloudasset.TimeWindow{StartTime: now, EndTime: now},
+	}
+	if prior == nil {
+		change.PriorAssetState = "DOES_NOT_EXIST"
+	}
+	if asset == nil {
+		deleted := *prior
+		deleted.UpdateTime = now
+		change.Asset = &deleted
+		change.Deleted = true
+	}
+	s.feeds.mu.Lock()
+	subscribers := []*assetSubscriber{}
+	for i := 0; i < s.feeds.next; i++ {
+		if subscriber, ok := s.feeds.subscribers[i]; ok && subscriber.filter.matches(change.Asset) {
+			subscribers = append(subscribers, subscriber)
+		}
+	}
+	s.feeds.mu.Unlock()
+	for _, subscriber := range subscribers {
+		subscriber.notify(change)
+	}
+}
+
+// publishNewAsset publishes the creation of the resource holding holder
+func (s *MockService) publishNewAsset(holder *IamPolicyHolder) {
+	s.publishAssetChange(nil, s.assetSnapshot(holder))
+}
@@ -0,0 +1,110 @@
+package mockgcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cloudasset "google.golang.org/api/cloudasset/v1"
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+func TestMockService_OnAssetChange(t *testing.T) {
+	t.Run("should publish project creation, policy change, move and delete", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Folders.NewFolder("folders/2", "prod", nil)
+		var changes []*cloudasset.TemporalAsset
+		service.OnAssetChange(AssetFeedFilter{AssetTypes: []string{"cloudresourcemanager.googleapis.com/Project"}}, func(change *cloudasset.TemporalAsset) {
+			changes = append(changes, change)
+		})
+
+		service.Projects.NewProject("projects/test-project", "", nil)
+		service.Projects.SetIamPolicy("projects/test-project", &cloudresourcemanager.SetIamPolicyRequest{
+			Policy: GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co")),
+		}).Do()
+		service.Projects.Move("projects/test-project", &cloudresourcemanager.MoveProjectRequest{DestinationParent: "folders/2"}).Do()
+		service.Projects.Delete("projects/test-project").Do()
+
+		if len(changes) != 4 {
+			t.Fatalf("expected 4 changes but got %v", len(changes))
+		}
+		if changes[0].PriorAssetState != "DOES_NOT_EXIST" {
+			t.Errorf("got %v want %v", changes[0].PriorAssetState, "DOES_NOT_EXIST")
+		}
+		if changes[1].PriorAsset.IamPolicy != nil || changes[1].Asset.IamPolicy.Bindings[0].Role != "roles/owner" {
+			t.Errorf("expected policy change from empty to roles/owner but got %v", changes[1])
+		}
+		if changes[2].Asset.Resource.Parent != "//cloudresourcemanager.googleapis.com/folders/2" {
+			t.Errorf("got %v want %v", changes[2].Asset.Resource.Parent, "//cloudresourcemanager.googleapis.com/folders/2")
+		}
+		if !changes[3].Deleted {
+			t.Errorf("expected last change to be a delete")
+		}
+	})
+	t.Run("should filter by name prefix and stop after cancel", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+		count := 0
+		cancel := service.OnAssetChange(AssetFeedFilter{NamePrefix: "projects/test-project/topics/"}, func(change *cloudasset.TemporalAsset) {
+			count++
+		})
+
+		service.Topics.NewTopic("test-project", "test-topic", nil)
+		service.Subscriptions.NewSubscription("test-project", "test-subscription", "projects/test-project/topics/test-topic", nil)
+		cancel()
+		service.Topics.NewTopic("test-project", "other-topic", nil)
+
+		if count != 1 {
+			t.Errorf("got %v want %v", count, 1)
+		}
+	})
+}
+
+func TestMockService_WatchAssetChanges(t *testing.T) {
+	t.Run("should deliver changes until cancelled", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+		changes, cancel := service.WatchAssetChanges(AssetFeedFilter{AssetTypes: []string{"storage.googleapis.com.*"}}, 10)
+
+		service.Buckets.NewBucket("test-project", "test-bucket", nil)
+		service.Buckets.Delete("test-bucket").Do()
+		cancel()
+
+		var got []bool
+		for change := range changes {
+			got = append(got, change.Deleted)
+		}
+
+		if len(got) != 2 || got[0] || !got[1] {
+			t.Errorf("expected a create then a delete but got %v", got)
+		}
+	})
+	t.Run("should cancel while a change is waiting to be read", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		changes, cancel := service.WatchAssetChanges(AssetFeedFilter{}, 0)
+		finished := make(chan struct{})
+		go func() {
+			service.Projects.NewProject("projects/first-project", "", nil)
+			service.Projects.NewProject("projects/second-project", "", nil)
+			close(finished)
+		}()
+
+		<-changes
+		cancelled := make(chan struct{})
+		go func() {
+			cancel()
+			close(cancelled)
+		}()
+
+		select {
+		case <-cancelled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("cancel blocked on the pending change")
+		}
+		select {
+		case <-finished:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the change blocked after cancel")
+		}
+	})
+}
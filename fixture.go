@@ -0,0 +1,398 @@
+package mockgcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is a declarative description of everything in a MockService, so large test worlds can live in
+// testdata/ as YAML or JSON instead of being built up with NewX calls.  The org tree is flat: folders and
+// projects name their parent (organizations/1, folders/2), and everything else sits under its project.
+// Names use the same forms as the rest of the package (projects/x, folders/2, organizations/1)
+type Fixture struct {
+	Organizations []*OrganizationFixture `json:"organizations,omitempty" yaml:"organizations,omitempty"`
+	Folders       []*FolderFixture       `json:"folders,omitempty" yaml:"folders,omitempty"`
+	Projects      []*ProjectFixture      `json:"projects,omitempty" yaml:"projects,omitempty"`
+	Groups        []*GroupFixture        `json:"groups,omitempty" yaml:"groups,omitempty"`
+}
+
+// BindingFixture is a binding in a resource's policy
+type BindingFixture struct {
+	Role      string            `json:"role" yaml:"role"`
+	Members   []string          `json:"members" yaml:"members,flow"`
+	Condition *ConditionFixture `json:"condition,omitempty" yaml:"condition,omitempty"`
+}
+
+// ConditionFixture is the condition of a binding
+type ConditionFixture struct {
+	Title       string `json:"title,omitempty" yaml:"title,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Expression  string `json:"expression" yaml:"expression"`
+}
+
+// OrganizationFixture describes an organization
+type OrganizationFixture struct {
+	Name     string            `json:"name" yaml:"name"`
+	Domain   string            `json:"domain,omitempty" yaml:"domain,omitempty"`
+	Bindings []*BindingFixture `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+}
+
+// FolderFixture describes a folder
+type FolderFixture struct {
+	Name        string            `json:"name" yaml:"name"`
+	DisplayName string            `json:"displayName,omitempty" yaml:"displayName,omitempty"`
+	Parent      string            `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Bindings    []*BindingFixture `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+}
+
+// ProjectFixture describes a project and the resources in it
+type ProjectFixture struct {
+	Name            string                   `json:"name" yaml:"name"`
+	DisplayName     string                   `json:"displayName,omitempty" yaml:"displayName,omitempty"`
+	Parent          string                   `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Bindings        []*BindingFixture        `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+	ServiceAccounts []*ServiceAccountFixture `json:"serviceAccounts,omitempty" yaml:"serviceAccounts,omitempty"`
+	Buckets         []*BucketFixture         `json:"buckets,omitempty" yaml:"buckets,omitempty"`
+	Topics          []*TopicFixture          `json:"topics,omitempty" yaml:"topics,omitempty"`
+	Subscriptions   []*SubscriptionFixture   `json:"subscriptions,omitempty" yaml:"subscriptions,omitempty"`
+	Secrets         []*SecretFixture         `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+	KeyRings        []*KeyRingFixture        `json:"keyRings,omitempty" yaml:"keyRings,omitempty"`
+}
+
+// ServiceAccountFixture describes a service account by its account ID (the part of the email before the @)
+type ServiceAccountFixture struct {
+	AccountID   string            `json:"accountId" yaml:"accountId"`
+	DisplayName string            `json:"displayName,omitempty" yaml:"displayName,omitempty"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Disabled    bool              `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Bindings    []*BindingFixture `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+}
+
+// BucketFixture describes a bucket.  Leaving out Bindings gives the bucket the default policy google gives
+// new buckets, and an empty list gives it an empty policy
+type BucketFixture struct {
+	Name                     string            `json:"name" yaml:"name"`
+	Location                 string            `json:"location,omitempty" yaml:"location,omitempty"`
+	StorageClass             string            `json:"storageClass,omitempty" yaml:"storageClass,omitempty"`
+	Labels                   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	UniformBucketLevelAccess bool              `json:"uniformBucketLevelAccess,omitempty" yaml:"uniformBucketLevelAccess,omitempty"`
+	Bindings                 []*BindingFixture `json:"bindings" yaml:"bindings"`
+}
+
+// TopicFixture describes a topic by its ID
+type TopicFixture struct {
+	ID       string            `json:"id" yaml:"id"`
+	Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Bindings []*BindingFixture `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+}
+
+// SubscriptionFixture describes a subscription by its ID.  Topic is the full topic resource name
+type SubscriptionFixture struct {
+	ID                 string            `json:"id" yaml:"id"`
+	Topic              string            `json:"topic" yaml:"topic"`
+	AckDeadlineSeconds int64             `json:"ackDeadlineSeconds,omitempty" yaml:"ackDeadlineSeconds,omitempty"`
+	Labels             map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Bindings           []*BindingFixture `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+}
+
+// SecretFixture describes a secret by its ID, with its versions in order
+type SecretFixture struct {
+	ID       string                  `json:"id" yaml:"id"`
+	Labels   map[string]string       `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Versions []*SecretVersionFixture `json:"versions,omitempty" yaml:"versions,omitempty"`
+	Bindings []*BindingFixture       `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+}
+
+// SecretVersionFixture describes a secret version.  Data is base64 encoded, and State defaults to ENABLED
+type SecretVersionFixture struct {
+	Data  string `json:"data,omitempty" yaml:"data,omitempty"`
+	State string `json:"state,omitempty" yaml:"state,omitempty"`
+}
+
+// KeyRingFixture describes a key ring by its location and ID, with its crypto keys
+type KeyRingFixture struct {
+	Location   string              `json:"location" yaml:"location"`
+	ID         string              `json:"id" yaml:"id"`
+	Bindings   []*BindingFixture   `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+	CryptoKeys []*CryptoKeyFixture `json:"cryptoKeys,omitempty" yaml:"cryptoKeys,omitempty"`
+}
+
+// CryptoKeyFixture describes a crypto key by its ID
+type CryptoKeyFixture struct {
+	ID       string            `json:"id" yaml:"id"`
+	Purpose  string            `json:"purpose,omitempty" yaml:"purpose,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Bindings []*BindingFixture `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+}
+
+// GroupFixture describes a Cloud Identity group by its email
+type GroupFixture struct {
+	Email       string           `json:"email" yaml:"email"`
+	DisplayName string           `json:"displayName,omitempty" yaml:"displayName,omitempty"`
+	Description string           `json:"description,omitempty" yaml:"description,omitempty"`
+	Members     []*MemberFixture `json:"members,omitempty" yaml:"members,omitempty"`
+}
+
+// MemberFixture is a member of a group in the IAM form (user:alice@example.com).  Roles defaults to MEMBER
+type MemberFixture struct {
+	Member string   `json:"member" yaml:"member"`
+	Roles  []string `json:"roles,omitempty" yaml:"roles,omitempty,flow"`
+}
+
+// fixturePolicy builds the policy for a list of bindings, at version 3 if any of them have a condition
+func fixturePolicy(bindings []*BindingFixture) *cloudresourcemanager.Policy {
+	policy := &cloudresourcemanager.Policy{}
+	for _, binding := range bindings {
+		b := NewBinding(binding.Role, binding.Members...)
+		if binding.Condition != nil {
+			b.Condition = &cloudresourcemanager.Expr{
+				Title:       binding.Condition.Title,
+				Description: binding.Condition.Description,
+				Expression:  binding.Condition.Expression,
+			}
+			policy.Version = 3
+		}
+		policy.Bindings = append(policy.Bindings, b)
+	}
+	return policy
+}
+
+// bindingFixtures returns the bindings held by holder
+func bindingFixtures(holder *IamPolicyHolder) []*BindingFixture {
+	bindings := []*BindingFixture{}
+	for _, binding := range holder.GetIamPolicy().Bindings {
+		if binding == nil {
+			continue
+		}
+		b := &BindingFixture{Role: binding.Role, Members: binding.Members}
+		if c := binding.Condition; c != nil {
+			b.Condition = &ConditionFixture{Title: c.Title, Description: c.Description, Expression: c.Expression}
+		}
+		bindings = append(bindings, b)
+	}
+	return bindings
+}
+
+// LoadFixtureFile reads a fixture from a YAML or JSON file (picked by a .json extension) into the service
+func (s *MockService) LoadFixtureFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fixture := &Fixture{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, fixture)
+	} else {
+		err = yaml.Unmarshal(data, fixture)
+	}
+	if err != nil {
+		return fmt.Errorf("fixture %v invalid: %v", path, err)
+	}
+	return s.LoadFixture(fixture)
+}
+
+// LoadFixture adds everything in fixture to the service.  It returns an error if a resource already exists,
+// or a subscription's topic doesn't
+func (s *MockService) LoadFixture(fixture *Fixture) error {
+	for _, o := range fixture.Organizations {
+		if s.Organizations.policyHolder(o.Name) != nil {
+			return fmt.Errorf("%v: %v", resourceAlreadyExistsError, o.Name)
+		}
+		s.Organizations.NewOrganization(o.Name, o.Domain, fixturePolicy(o.Bindings))
+	}
+	for _, f := range fixture.Folders {
+		if s.Folders.policyHolder(f.Name) != nil {
+			return fmt.Errorf("%v: %v", resourceAlreadyExistsError, f.Name)
+		}
+		folder := s.Folders.NewFolder(f.Name, f.DisplayName, fixturePolicy(f.Bindings))
+		folder.Parent = f.Parent
+	}
+	for _, p := range fixture.Projects {
+		if s.Projects.get(projectResourceName(p.Name)) != nil {
+			return fmt.Errorf("%v: %v", resourceAlreadyExistsError, p.Name)
+		}
+		project := s.Projects.NewProject(projectResourceName(p.Name), p.DisplayName, fixturePolicy(p.Bindings))
+		project.Parent = p.Parent
+		if err := s.loadProjectResources(project, p); err != nil {
+			return err
+		}
+	}
+	for _, g := range fixture.Groups {
+		if s.Groups.findByEmail(g.Email) != nil {
+			return fmt.Errorf("%v: %v", resourceAlreadyExistsError, g.Email)
+		}
+		group := s.Groups.NewGroup(g.Email, g.DisplayName)
+		group.Description = g.Description
+		for _, m := range g.Members {
+			group.AddMember(m.Member, m.Roles...)
+		}
+	}
+	return nil
+}
+
+// loadProjectResources adds the resources of a project fixture to project
+func (s *MockService) loadProjectResources(project *Project, p *ProjectFixture) error {
+	for _, a := range p.ServiceAccounts {
+		account := s.ServiceAccounts.NewServiceAccount(project.ProjectID, a.AccountID, a.DisplayName)
+		account.Description = a.Description
+		account.Disabled = a.Disabled
+		account.SetIamPolicy(fixturePolicy(a.Bindings))
+	}
+	for _, b := range p.Buckets {
+		if s.Buckets.find(b.Name) != nil {
+			return fmt.Errorf("%v: %v", resourceAlreadyExistsError, b.Name)
+		}
+		var policy *cloudresourcemanager.Policy
+		if b.Bindings != nil {
+			policy = fixturePolicy(b.Bindings)
+		}
+		bucket := s.Buckets.NewBucket(project.ProjectID, b.Name, policy)
+		if b.Location != "" {
+			bucket.Location = b.Location
+		}
+		if b.StorageClass != "" {
+			bucket.StorageClass = b.StorageClass
+		}
+		bucket.Labels = copyLabels(b.Labels)
+		bucket.UniformBucketLevelAccess = b.UniformBucketLevelAccess
+	}
+	for _, t := range p.Topics {
+		topic := s.Topics.NewTopic(project.ProjectID, t.ID, fixturePolicy(t.Bindings))
+		topic.Labels = copyLabels(t.Labels)
+	}
+	for _, sub := range p.Subscriptions {
+		if s.Topics.find(sub.Topic) == nil {
+			return fmt.Errorf("%v: %v", resourceNotFoundError, sub.Topic)
+		}
+		subscription := s.Subscriptions.NewSubscription(project.ProjectID, sub.ID, sub.Topic, fixturePolicy(sub.Bindings))
+		if sub.AckDeadlineSeconds != 0 {
+			subscription.AckDeadlineSeconds = sub.AckDeadlineSeconds
+		}
+		subscription.Labels = copyLabels(sub.Labels)
+	}
+	for _, sec := range p.Secrets {
+		secret := s.Secrets.NewSecret(project.ProjectID, sec.ID, fixturePolicy(sec.Bindings))
+		secret.Labels = copyLabels(sec.Labels)
+		for _, v := range sec.Versions {
+			version := secret.AddVersion(v.Data)
+			if v.State != "" {
+				version.State = v.State
+			}
+		}
+	}
+	for _, k := range p.KeyRings {
+		keyRing := s.KeyRings.NewKeyRing(project.ProjectID, k.Location, k.ID, fixturePolicy(k.Bindings))
+		for _, c := range k.CryptoKeys {
+			cryptoKey := keyRing.NewCryptoKey(c.ID, c.Purpose, fixturePolicy(c.Bindings))
+			cryptoKey.Labels = copyLabels(c.Labels)
+		}
+	}
+	return nil
+}
+
+// DumpFixtureFile writes the service's current state to a YAML or JSON file (picked by a .json extension),
+// which is handy for snapshotting the world when a test fails
+func (s *MockService) DumpFixtureFile(path string) error {
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err = json.MarshalIndent(s.Fixture(), "", "  ")
+	} else {
+		data, err = yaml.Marshal(s.Fixture())
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Fixture returns the service's current state as a Fixture, which LoadFixture turns back into the same
+// world.  Deleted service accounts, service account keys, and the generated IDs and timestamps aren't kept
+func (s *MockService) Fixture() *Fixture {
+	fixture := &Fixture{}
+	for _, o := range s.Organizations.OrganizationList {
+		fixture.Organizations = append(fixture.Organizations, &OrganizationFixture{
+			Name: o.OrganizationID, Domain: o.Domain, Bindings: bindingFixtures(&o.IamPolicyHolder),
+		})
+	}
+	for _, f := range s.Folders.FolderList {
+		fixture.Folders = append(fixture.Folders, &FolderFixture{
+			Name: f.FolderID, DisplayName: f.DisplayName, Parent: f.Parent, Bindings: bindingFixtures(&f.IamPolicyHolder),
+		})
+	}
+	for _, p := range s.Projects.ProjectList {
+		fixture.Projects = append(fixture.Projects, projectFixture(p))
+	}
+	for _, g := range s.Groups.GroupList {
+		group := &GroupFixture{Email: g.Email, DisplayName: g.DisplayName, Description: g.Description}
+		for _, m := range g.Memberships {
+			group.Members = append(group.Members, &MemberFixture{Member: m.Member, Roles: copyStrings(m.Roles)})
+		}
+		fixture.Groups = append(fixture.Groups, group)
+	}
+	return fixture
+}
+
+// projectFixture returns the fixture for a project and the resources in it
+func projectFixture(p *Project) *ProjectFixture {
+	project := &ProjectFixture{Name: p.ProjectID, DisplayName: p.DisplayName, Parent: p.Parent, Bindings: bindingFixtures(&p.IamPolicyHolder)}
+	for _, a := range p.ServiceAccounts {
+		if a.Deleted {
+			continue
+		}
+		project.ServiceAccounts = append(project.ServiceAccounts, &ServiceAccountFixture{
+			AccountID:   strings.SplitN(a.Email, "@", 2)[0],
+			DisplayName: a.DisplayName,
+			Description: a.Description,
+			Disabled:    a.Disabled,
+			Bindings:    bindingFixtures(&a.IamPolicyHolder),
+		})
+	}
+	for _, b := range p.Buckets {
+		project.Buckets = append(project.Buckets, &BucketFixture{
+			Name:                     b.Name,
+			Location:                 b.Location,
+			StorageClass:             b.StorageClass,
+			Labels:                   copyLabels(b.Labels),
+			UniformBucketLevelAccess: b.UniformBucketLevelAccess,
+			Bindings:                 bindingFixtures(&b.IamPolicyHolder),
+		})
+	}
+	for _, t := range p.Topics {
+		project.Topics = append(project.Topics, &TopicFixture{
+			ID: t.Name[strings.LastIndex(t.Name, "/")+1:], Labels: copyLabels(t.Labels), Bindings: bindingFixtures(&t.IamPolicyHolder),
+		})
+	}
+	for _, sub := range p.Subscriptions {
+		project.Subscriptions = append(project.Subscriptions, &SubscriptionFixture{
+			ID:                 sub.Name[strings.LastIndex(sub.Name, "/")+1:],
+			Topic:              sub.Topic,
+			AckDeadlineSeconds: sub.AckDeadlineSeconds,
+			Labels:             copyLabels(sub.Labels),
+			Bindings:           bindingFixtures(&sub.IamPolicyHolder),
+		})
+	}
+	for _, sec := range p.Secrets {
+		secret := &SecretFixture{ID: sec.Name[strings.LastIndex(sec.Name, "/")+1:], Labels: copyLabels(sec.Labels), Bindings: bindingFixtures(&sec.IamPolicyHolder)}
+		for _, v := range sec.Versions {
+			secret.Versions = append(secret.Versions, &SecretVersionFixture{Data: v.Data, State: v.State})
+		}
+		project.Secrets = append(project.Secrets, secret)
+	}
+	for _, k := range p.KeyRings {
+		keyRing := &KeyRingFixture{Location: k.Location, ID: k.Name[strings.LastIndex(k.Name, "/")+1:], Bindings: bindingFixtures(&k.IamPolicyHolder)}
+		for _, c := range k.CryptoKeys {
+			keyRing.CryptoKeys = append(keyRing.CryptoKeys, &CryptoKeyFixture{
+				ID: c.Name[strings.LastIndex(c.Name, "/")+1:], Purpose: c.Purpose, Labels: copyLabels(c.Labels), Bindings: bindingFixtures(&c.IamPolicyHolder),
+			})
+		}
+		project.KeyRings = append(project.KeyRings, keyRing)
+	}
+	return project
+}
@@ -0,0 +1,101 @@
+package mockgcp
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMockService_LoadFixtureFile(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	if err := service.LoadFixtureFile("testdata/org.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should build the org tree with policies", func(t *testing.T) {
+		project := service.Projects.get("projects/prod-project")
+		if project == nil {
+			t.Fatalf("expected projects/prod-project to be loaded")
+		}
+		if project.Parent != "folders/2" || project.DisplayName != "Prod Project" {
+			t.Errorf("got %v %v", project.Parent, project.DisplayName)
+		}
+		policy := project.GetIamPolicy()
+		if policy.Version != 3 {
+			t.Errorf("got version %v want %v", policy.Version, 3)
+		}
+		editor := PolicyContains(policy, "roles/editor")
+		if editor == nil || editor.Condition == nil || editor.Condition.Title != "expires" {
+			t.Errorf("expected a conditional editor binding but got %v", editor)
+		}
+		if service.Folders.policyHolder("folders/2") == nil || service.Organizations.policyHolder("organizations/1") == nil {
+			t.Errorf("expected the folder and organization to be loaded")
+		}
+	})
+	t.Run("should load the project's resources", func(t *testing.T) {
+		bucket := service.Buckets.find("prod-logs")
+		if bucket == nil || bucket.Location != "EU" || bucket.Labels["env"] != "prod" || !bucket.UniformBucketLevelAccess {
+			t.Errorf("got %v", bucket)
+		}
+		if PolicyContains(service.Buckets.find("prod-assets").GetIamPolicy(), "roles/storage.legacyBucketOwner") == nil {
+			t.Errorf("expected a bucket without bindings to get the default policy")
+		}
+		if service.ServiceAccounts.find("projects/prod-project/serviceAccounts/deployer@prod-project.iam.gserviceaccount.com", false) == nil {
+			t.Errorf("expected the service account to be loaded")
+		}
+		if subscription := service.Subscriptions.find("projects/prod-project/subscriptions/events-sub"); subscription == nil || subscription.AckDeadlineSeconds != 30 {
+			t.Errorf("got %v", subscription)
+		}
+		secret := service.Secrets.find("projects/prod-project/secrets/api-key")
+		if secret == nil || len(secret.Versions) != 2 || secret.Versions[1].State != "DISABLED" {
+			t.Errorf("got %v", secret)
+		}
+		if service.KeyRings.CryptoKeys.find("projects/prod-project/locations/global/keyRings/prod-ring/cryptoKeys/prod-key") == nil {
+			t.Errorf("expected the crypto key to be loaded")
+		}
+	})
+	t.Run("should load groups for policy analysis", func(t *testing.T) {
+		members := service.Groups.TransitiveMembers("auditors@testdomain.co")
+		if len(members) != 2 {
+			t.Errorf("got %v want 2 members", members)
+		}
+	})
+	t.Run("should error loading the same world twice", func(t *testing.T) {
+		if err := service.LoadFixtureFile("testdata/org.yaml"); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+func TestMockService_DumpFixtureFile(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	if err := service.LoadFixtureFile("testdata/org.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"world.yaml", "world.json"} {
+		t.Run("should round trip through "+filepath.Ext(name), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := service.DumpFixtureFile(path); err != nil {
+				t.Fatal(err)
+			}
+			loaded, _ := NewService(context.TODO())
+			if err := loaded.LoadFixtureFile(path); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := loaded.Fixture(), service.Fixture(); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v want %+v", got, want)
+			}
+		})
+	}
+	t.Run("should error on a subscription to a missing topic", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		err := service.LoadFixture(&Fixture{Projects: []*ProjectFixture{{
+			Name:          "projects/test-project",
+			Subscriptions: []*SubscriptionFixture{{ID: "test-subscription", Topic: "projects/test-project/topics/missing"}},
+		}}})
+		if err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
@@ -0,0 +1,443 @@
+package mockgcp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"cloud.google.com/go/resourcemanager/apiv3/resourcemanagerpb"
+	"google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/genproto/googleapis/type/expr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcBufferSize is the size of the in-process buffer DialGRPC serves over
+const grpcBufferSize = 1024 * 1024
+
+// grpcCodes is the gRPC code GCP answers with for each HTTP status the mock's errors map to
+var grpcCodes = map[int]codes.Code{
+	statusClientClosedRequest:      codes.Canceled,
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusInternalServerError: codes.Internal,
+	http.StatusNotImplemented:      codes.Unimplemented,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+	http.StatusGatewayTimeout:      codes.DeadlineExceeded,
+}
+
+// NewGRPCServer returns a grpc.Server serving the resource manager v3 Projects, Folders and Organizations
+// APIs from the service's state, so the cloud.google.com/go/resourcemanager/apiv3 clients can be pointed
+// at the mock.  It serves the same calls as the REST Handler, through the same Do() calls, so faults,
+// quotas and call records apply to it too.  Serve it on any listener, or use DialGRPC to serve it in
+// process
+func NewGRPCServer(s *MockService, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	resourcemanagerpb.RegisterProjectsServer(server, &grpcProjectsServer{service: s})
+	resourcemanagerpb.RegisterFoldersServer(server, &grpcFoldersServer{service: s})
+	resourcemanagerpb.RegisterOrganizationsServer(server, &grpcOrganizationsServer{service: s})
+	return server
+}
+
+// DialGRPC serves the gRPC API over an in-process bufconn listener, and returns a connection to it that
+// clients can use with option.WithGRPCConn.  Closing the connection stops the server
+func (s *MockService) DialGRPC(ctx context.Context) (*grpc.ClientConn, error) {
+	listener := bufconn.Listen(grpcBufferSize)
+	server := NewGRPCServer(s)
+	go server.Serve(listener)
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		server.Stop()
+		return nil, err
+	}
+	go func() {
+		for conn.WaitForStateChange(context.Background(), conn.GetState()) {
+			if conn.GetState() == connectivity.Shutdown {
+				server.Stop()
+				return
+			}
+		}
+	}()
+	return conn, nil
+}
+
+// grpcError returns an error from a Do() call as the gRPC status GCP would answer with
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	code, ok := grpcCodes[errorCode(err)]
+	if !ok {
+		code = codes.Unknown
+	}
+	return status.Error(code, err.Error())
+}
+
+// grpcOperation returns a finished long running operation, named from its id, with result as its response
+func grpcOperation(result proto.Message, id uint64) (*longrunningpb.Operation, error) {
+	response, err := anypb.New(result)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &longrunningpb.Operation{
+		Name:   fmt.Sprintf("operations/mockgcp.%v", id),
+		Done:   true,
+		Result: &longrunningpb.Operation_Response{Response: response},
+	}, nil
+}
+
+// grpcTime returns a resource manager RFC3339 time as a timestamp, or nil if it isn't set
+func grpcTime(t string) *timestamppb.Timestamp {
+	parsed, err := time.Parse(time.RFC3339Nano, t)
+	if err != nil {
+		return nil
+	}
+	return timestamppb.New(parsed)
+}
+
+// grpcProject returns a project in its gRPC form
+func grpcProject(project *cloudresourcemanager.Project) *resourcemanagerpb.Project {
+	return &resourcemanagerpb.Project{
+		Name:        project.Name,
+		Parent:      project.Parent,
+		ProjectId:   project.ProjectId,
+		State:       resourcemanagerpb.Project_State(resourcemanagerpb.Project_State_value[project.State]),
+		DisplayName: project.DisplayName,
+		DeleteTime:  grpcTime(project.DeleteTime),
+	}
+}
+
+// grpcFolder returns a folder in its gRPC form
+func grpcFolder(folder *cloudresourcemanager.Folder) *resourcemanagerpb.Folder {
+	return &resourcemanagerpb.Folder{
+		Name:        folder.Name,
+		Parent:      folder.Parent,
+		DisplayName: folder.DisplayName,
+		State:       resourcemanagerpb.Folder_State(resourcemanagerpb.Folder_State_value[folder.State]),
+	}
+}
+
+// grpcOrganization returns an organization in its gRPC form
+func grpcOrganization(organization *cloudresourcemanager.Organization) *resourcemanagerpb.Organization {
+	return &resourcemanagerpb.Organization{
+		Name:        organization.Name,
+		DisplayName: organization.DisplayName,
+		State:       resourcemanagerpb.Organization_State(resourcemanagerpb.Organization_State_value[organization.State]),
+	}
+}
+
+// grpcPolicy returns a policy in its gRPC form
+func grpcPolicy(policy *cloudresourcemanager.Policy) *iampb.Policy {
+	if policy == nil {
+		return nil
+	}
+	etag, err := base64.StdEncoding.DecodeString(policy.Etag)
+	if err != nil {
+		etag = []byte(policy.Etag)
+	}
+	result := &iampb.Policy{Version: int32(policy.Version), Etag: etag}
+	for _, binding := range policy.Bindings {
+		converted := &iampb.Binding{Role: binding.Role, Members: copyStrings(binding.Members)}
+		if binding.Condition != nil {
+			converted.Condition = &expr.Expr{
+				Expression:  binding.Condition.Expression,
+				Title:       binding.Condition.Title,
+				Description: binding.Condition.Description,
+				Location:    binding.Condition.Location,
+			}
+		}
+		result.Bindings = append(result.Bindings, converted)
+	}
+	for _, auditConfig := range policy.AuditConfigs {
+		converted := &iampb.AuditConfig{Service: auditConfig.Service}
+		for _, logConfig := range auditConfig.AuditLogConfigs {
+			converted.AuditLogConfigs = append(converted.AuditLogConfigs, &iampb.AuditLogConfig{
+				LogType:         iampb.AuditLogConfig_LogType(iampb.AuditLogConfig_LogType_value[logConfig.LogType]),
+				ExemptedMembers: copyStrings(logConfig.ExemptedMembers),
+			})
+		}
+		result.AuditConfigs = append(result.AuditConfigs, converted)
+	}
+	return result
+}
+
+// restPolicy returns a gRPC policy in its resource manager form
+func restPolicy(policy *iampb.Policy) *cloudresourcemanager.Policy {
+	if policy == nil {
+		return nil
+	}
+	result := &cloudresourcemanager.Policy{Version: int64(policy.Version)}
+	if len(policy.Etag) > 0 {
+		result.Etag = base64.StdEncoding.EncodeToString(policy.Etag)
+	}
+	for _, binding := range policy.Bindings {
+		converted := &cloudresourcemanager.Binding{Role: binding.Role, Members: copyStrings(binding.Members)}
+		if binding.Condition != nil {
+			converted.Condition = &cloudresourcemanager.Expr{
+				Expression:  binding.Condition.Expression,
+				Title:       binding.Condition.Title,
+				Description: binding.Condition.Description,
+				Location:    binding.Condition.Location,
+			}
+		}
+		result.Bindings = append(result.Bindings, converted)
+	}
+	for _, auditConfig := range policy.AuditConfigs {
+		converted := &cloudresourcemanager.AuditConfig{Service: auditConfig.Service}
+		for _, logConfig := range auditConfig.AuditLogConfigs {
+			converted.AuditLogConfigs = append(converted.AuditLogConfigs, &cloudresourcemanager.AuditLogConfig{
+				LogType:         logConfig.LogType.String(),
+				ExemptedMembers: copyStrings(logConfig.ExemptedMembers),
+			})
+		}
+		result.AuditConfigs = append(result.AuditConfigs, converted)
+	}
+	return result
+}
+
+// grpcGetIamPolicy serves GetIamPolicy for a collection
+func (s *MockService) grpcGetIamPolicy(ctx context.Context, collection string, request *iampb.GetIamPolicyRequest) (*iampb.Policy, error) {
+	getiampolicyrequest := &cloudresourcemanager.GetIamPolicyRequest{}
+	if request.Options != nil {
+		getiampolicyrequest.Options = &cloudresourcemanager.GetPolicyOptions{RequestedPolicyVersion: int64(request.Options.RequestedPolicyVersion)}
+	}
+	policy, err := s.crmGetIamPolicy(ctx, collection, request.Resource, getiampolicyrequest)
+	return grpcPolicy(policy), grpcError(err)
+}
+
+// grpcSetIamPolicy serves SetIamPolicy for a collection
+func (s *MockService) grpcSetIamPolicy(ctx context.Context, collection string, request *iampb.SetIamPolicyRequest) (*iampb.Policy, error) {
+	setiampolicyrequest := &cloudresourcemanager.SetIamPolicyRequest{Policy: restPolicy(request.Policy)}
+	if request.UpdateMask != nil {
+		setiampolicyrequest.UpdateMask = strings.Join(request.UpdateMask.Paths, ",")
+	}
+	policy, err := s.crmSetIamPolicy(ctx, collection, request.Resource, setiampolicyrequest)
+	return grpcPolicy(policy), grpcError(err)
+}
+
+// grpcTestIamPermissions serves TestIamPermissions for a collection
+func (s *MockService) grpcTestIamPermissions(ctx context.Context, collection string, request *iampb.TestIamPermissionsRequest) (*iampb.TestIamPermissionsResponse, error) {
+	response, err := s.crmTestIamPermissions(ctx, collection, request.Resource, &cloudresourcemanager.TestIamPermissionsRequest{Permissions: request.Permissions})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &iampb.TestIamPermissionsResponse{Permissions: response.Permissions}, nil
+}
+
+// grpcProjectsServer serves the Projects gRPC API.  Creating and updating projects isn't served
+type grpcProjectsServer struct {
+	resourcemanagerpb.UnimplementedProjectsServer
+	service *MockService
+}
+
+// GetProject returns a project, including one that's been deleted but not purged
+func (g *grpcProjectsServer) GetProject(ctx context.Context, request *resourcemanagerpb.GetProjectRequest) (*resourcemanagerpb.Project, error) {
+	project, err := g.service.crmGet(ctx, "projects", request.Name)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return grpcProject(project.(*cloudresourcemanager.Project)), nil
+}
+
+// ListProjects returns the projects directly under a folder or organization.  Deleted projects are only
+// listed if ShowDeleted is set
+func (g *grpcProjectsServer) ListProjects(ctx context.Context, request *resourcemanagerpb.ListProjectsRequest) (*resourcemanagerpb.ListProjectsResponse, error) {
+	query := "parent:" + request.Parent
+	if !request.ShowDeleted {
+		query += " state:ACTIVE"
+	}
+	found, err := g.service.crmSearch(ctx, "projects", query)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	response := &resourcemanagerpb.ListProjectsResponse{}
+	for _, project := range found.(*cloudresourcemanager.SearchProjectsResponse).Projects {
+		response.Projects = append(response.Projects, grpcProject(project))
+	}
+	return response, nil
+}
+
+// SearchProjects returns the projects matching a query, written the way the REST Handler takes it
+func (g *grpcProjectsServer) SearchProjects(ctx context.Context, request *resourcemanagerpb.SearchProjectsRequest) (*resourcemanagerpb.SearchProjectsResponse, error) {
+	found, err := g.service.crmSearch(ctx, "projects", request.Query)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	response := &resourcemanagerpb.SearchProjectsResponse{}
+	for _, project := range found.(*cloudresourcemanager.SearchProjectsResponse).Projects {
+		response.Projects = append(response.Projects, grpcProject(project))
+	}
+	return response, nil
+}
+
+// MoveProject moves a project, and returns a finished operation with the moved project
+func (g *grpcProjectsServer) MoveProject(ctx context.Context, request *resourcemanagerpb.MoveProjectRequest) (*longrunningpb.Operation, error) {
+	moveprojectrequest := &cloudresourcemanager.MoveProjectRequest{DestinationParent: request.DestinationParent}
+	if _, err := g.service.Projects.Move(request.Name, moveprojectrequest).Context(ctx).Do(); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.projectOperation(ctx, request.Name)
+}
+
+// DeleteProject deletes a project, and returns a finished operation with the project in DELETE_REQUESTED
+func (g *grpcProjectsServer) DeleteProject(ctx context.Context, request *resourcemanagerpb.DeleteProjectRequest) (*longrunningpb.Operation, error) {
+	if _, err := g.service.Projects.Delete(request.Name).Context(ctx).Do(); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.projectOperation(ctx, request.Name)
+}
+
+// UndeleteProject undeletes a project, and returns a finished operation with the restored project
+func (g *grpcProjectsServer) UndeleteProject(ctx context.Context, request *resourcemanagerpb.UndeleteProjectRequest) (*longrunningpb.Operation, error) {
+	if _, err := g.service.Projects.Undelete(request.Name, &cloudresourcemanager.UndeleteProjectRequest{}).Context(ctx).Do(); err != nil {
+		return nil, grpcError(err)
+	}
+	return g.projectOperation(ctx, request.Name)
+}
+
+// projectOperation returns a finished operation with the project called name as its response
+func (g *grpcProjectsServer) projectOperation(ctx context.Context, name string) (*longrunningpb.Operation, error) {
+	project, err := g.GetProject(ctx, &resourcemanagerpb.GetProjectRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	return grpcOperation(project, atomic.AddUint64(&g.service.operations, 1))
+}
+
+// GetIamPolicy returns a project's policy
+func (g *grpcProjectsServer) GetIamPolicy(ctx context.Context, request *iampb.GetIamPolicyRequest) (*iampb.Policy, error) {
+	return g.service.grpcGetIamPolicy(ctx, "projects", request)
+}
+
+// SetIamPolicy sets a project's policy
+func (g *grpcProjectsServer) SetIamPolicy(ctx context.Context, request *iampb.SetIamPolicyRequest) (*iampb.Policy, error) {
+	return g.service.grpcSetIamPolicy(ctx, "projects", request)
+}
+
+// TestIamPermissions returns the permissions the Caller has on a project
+func (g *grpcProjectsServer) TestIamPermissions(ctx context.Context, request *iampb.TestIamPermissionsRequest) (*iampb.TestIamPermissionsResponse, error) {
+	return g.service.grpcTestIamPermissions(ctx, "projects", request)
+}
+
+// grpcFoldersServer serves the Folders gRPC API.  Folders can't be created, changed or deleted through it
+type grpcFoldersServer struct {
+	resourcemanagerpb.UnimplementedFoldersServer
+	service *MockService
+}
+
+// GetFolder returns a folder
+func (g *grpcFoldersServer) GetFolder(ctx context.Context, request *resourcemanagerpb.GetFolderRequest) (*resourcemanagerpb.Folder, error) {
+	folder, err := g.service.crmGet(ctx, "folders", request.Name)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return grpcFolder(folder.(*cloudresourcemanager.Folder)), nil
+}
+
+// ListFolders returns the folders directly under a folder or organization
+func (g *grpcFoldersServer) ListFolders(ctx context.Context, request *resourcemanagerpb.ListFoldersRequest) (*resourcemanagerpb.ListFoldersResponse, error) {
+	found, err := g.service.crmSearch(ctx, "folders", "parent:"+request.Parent)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	response := &resourcemanagerpb.ListFoldersResponse{}
+	for _, folder := range found.(*cloudresourcemanager.SearchFoldersResponse).Folders {
+		response.Folders = append(response.Folders, grpcFolder(folder))
+	}
+	return response, nil
+}
+
+// SearchFolders returns the folders matching a query
+func (g *grpcFoldersServer) SearchFolders(ctx context.Context, request *resourcemanagerpb.SearchFoldersRequest) (*resourcemanagerpb.SearchFoldersResponse, error) {
+	found, err := g.service.crmSearch(ctx, "folders", request.Query)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	response := &resourcemanagerpb.SearchFoldersResponse{}
+	for _, folder := range found.(*cloudresourcemanager.SearchFoldersResponse).Folders {
+		response.Folders = append(response.Folders, grpcFolder(folder))
+	}
+	return response, nil
+}
+
+// GetIamPolicy returns a folder's policy
+func (g *grpcFoldersServer) GetIamPolicy(ctx context.Context, request *iampb.GetIamPolicyRequest) (*iampb.Policy, error) {
+	return g.service.grpcGetIamPolicy(ctx, "folders", request)
+}
+
+// SetIamPolicy sets a folder's policy
+func (g *grpcFoldersServer) SetIamPolicy(ctx context.Context, request *iampb.SetIamPolicyRequest) (*iampb.Policy, error) {
+	return g.service.grpcSetIamPolicy(ctx, "folders", request)
+}
+
+// TestIamPermissions returns the permissions the Caller has on a folder
+func (g *grpcFoldersServer) TestIamPermissions(ctx context.Context, request *iampb.TestIamPermissionsRequest) (*iampb.TestIamPermissionsResponse, error) {
+	return g.service.grpcTestIamPermissions(ctx, "folders", request)
+}
+
+// grpcOrganizationsServer serves the Organizations gRPC API
+type grpcOrganizationsServer struct {
+	resourcemanagerpb.UnimplementedOrganizationsServer
+	service *MockService
+}
+
+// GetOrganization returns an organization
+func (g *grpcOrganizationsServer) GetOrganization(ctx context.Context, request *resourcemanagerpb.GetOrganizationRequest) (*resourcemanagerpb.Organization, error) {
+	organization, err := g.service.crmGet(ctx, "organizations", request.Name)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return grpcOrganization(organization.(*cloudresourcemanager.Organization)), nil
+}
+
+// SearchOrganizations returns the organizations matching a query
+func (g *grpcOrganizationsServer) SearchOrganizations(ctx context.Context, request *resourcemanagerpb.SearchOrganizationsRequest) (*resourcemanagerpb.SearchOrganizationsResponse, error) {
+	found, err := g.service.crmSearch(ctx, "organizations", request.Query)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	response := &resourcemanagerpb.SearchOrganizationsResponse{}
+	for _, organization := range found.(*cloudresourcemanager.SearchOrganizationsResponse).Organizations {
+		response.Organizations = append(response.Organizations, grpcOrganization(organization))
+	}
+	return response, nil
+}
+
+// GetIamPolicy returns an organization's policy
+func (g *grpcOrganizationsServer) GetIamPolicy(ctx context.Context, request *iampb.GetIamPolicyRequest) (*iampb.Policy, error) {
+	return g.service.grpcGetIamPolicy(ctx, "organizations", request)
+}
+
+// SetIamPolicy sets an organization's policy
+func (g *grpcOrganizationsServer) SetIamPolicy(ctx context.Context, request *iampb.SetIamPolicyRequest) (*iampb.Policy, error) {
+	return g.service.grpcSetIamPolicy(ctx, "organizations", request)
+}
+
+// TestIamPermissions returns the permissions the Caller has on an organization
+func (g *grpcOrganizationsServer) TestIamPermissions(ctx context.Context, request *iampb.TestIamPermissionsRequest) (*iampb.TestIamPermissionsResponse, error) {
+	return g.service.grpcTestIamPermissions(ctx, "organizations", request)
+}
@@ -0,0 +1,118 @@
+package mockgcp
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
+	"cloud.google.com/go/resourcemanager/apiv3/resourcemanagerpb"
+	"google.golang.org/api/iterator"
+	option "google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMockService_DialGRPC(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Organizations.NewOrganization("organizations/1", "testdomain.co", nil)
+	service.Folders.NewFolder("folders/2", "Test Folder", GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co"))).Parent = "organizations/1"
+	service.Projects.NewProject("projects/test-project", "Test Project", nil).Parent = "folders/2"
+	conn, err := service.DialGRPC(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	projects, err := resourcemanager.NewProjectsClient(context.TODO(), option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	folders, err := resourcemanager.NewFoldersClient(context.TODO(), option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	organizations, err := resourcemanager.NewOrganizationsClient(context.TODO(), option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should get a project", func(t *testing.T) {
+		project, err := projects.GetProject(context.TODO(), &resourcemanagerpb.GetProjectRequest{Name: "projects/test-project"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.ProjectId != "test-project" || project.Parent != "folders/2" || project.State != resourcemanagerpb.Project_ACTIVE {
+			t.Errorf("got %v", project)
+		}
+	})
+	t.Run("should search and list", func(t *testing.T) {
+		found := projects.SearchProjects(context.TODO(), &resourcemanagerpb.SearchProjectsRequest{Query: "displayName:test*"})
+		project, err := found.Next()
+		if err != nil || project.Name != "projects/test-project" {
+			t.Errorf("got %v %v want projects/test-project", project, err)
+		}
+		if _, err := found.Next(); err != iterator.Done {
+			t.Errorf("got %v want %v", err, iterator.Done)
+		}
+		folder, err := folders.ListFolders(context.TODO(), &resourcemanagerpb.ListFoldersRequest{Parent: "organizations/1"}).Next()
+		if err != nil || folder.DisplayName != "Test Folder" {
+			t.Errorf("got %v %v want folders/2", folder, err)
+		}
+		organization, err := organizations.SearchOrganizations(context.TODO(), &resourcemanagerpb.SearchOrganizationsRequest{Query: "domain:testdomain.co"}).Next()
+		if err != nil || organization.Name != "organizations/1" {
+			t.Errorf("got %v %v want organizations/1", organization, err)
+		}
+	})
+	t.Run("should get and set policies", func(t *testing.T) {
+		policy, err := folders.GetIamPolicy(context.TODO(), &iampb.GetIamPolicyRequest{Resource: "folders/2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(policy.Bindings) != 1 || policy.Bindings[0].Role != "roles/owner" {
+			t.Errorf("got %v want alice as owner", policy.Bindings)
+		}
+		policy.Bindings = append(policy.Bindings, &iampb.Binding{Role: "roles/viewer", Members: []string{"user:bob@testdomain.co"}})
+		if _, err := folders.SetIamPolicy(context.TODO(), &iampb.SetIamPolicyRequest{Resource: "folders/2", Policy: policy}); err != nil {
+			t.Fatal(err)
+		}
+		stored, _ := service.Folders.GetIamPolicy("folders/2", nil).Do()
+		if !hasMember(stored, "roles/viewer", "user:bob@testdomain.co") {
+			t.Errorf("got %v want bob as viewer", stored.Bindings)
+		}
+	})
+	t.Run("should delete and undelete projects", func(t *testing.T) {
+		operation, err := projects.DeleteProject(context.TODO(), &resourcemanagerpb.DeleteProjectRequest{Name: "projects/test-project"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		project, err := operation.Wait(context.TODO())
+		if err != nil || project.State != resourcemanagerpb.Project_DELETE_REQUESTED || project.DeleteTime == nil {
+			t.Errorf("got %v %v want a project in DELETE_REQUESTED", project, err)
+		}
+		undeleted, err := projects.UndeleteProject(context.TODO(), &resourcemanagerpb.UndeleteProjectRequest{Name: "projects/test-project"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project, err := undeleted.Wait(context.TODO()); err != nil || project.State != resourcemanagerpb.Project_ACTIVE {
+			t.Errorf("got %v %v want an active project", project, err)
+		}
+		if operation.Name() == undeleted.Name() {
+			t.Errorf("got %v for both operations want different names", operation.Name())
+		}
+	})
+	t.Run("should return gRPC status codes", func(t *testing.T) {
+		_, err := projects.GetProject(context.TODO(), &resourcemanagerpb.GetProjectRequest{Name: "projects/missing-project"})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("got %v want %v", err, codes.NotFound)
+		}
+		service.Faults.Inject(Fault{Method: "Organizations.GetIamPolicy", Times: 1, Code: 429})
+		_, err = organizations.GetIamPolicy(context.TODO(), &iampb.GetIamPolicyRequest{Resource: "organizations/1"})
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("got %v want %v", err, codes.ResourceExhausted)
+		}
+		_, err = folders.CreateFolder(context.TODO(), &resourcemanagerpb.CreateFolderRequest{Folder: &resourcemanagerpb.Folder{Parent: "organizations/1"}})
+		if status.Code(err) != codes.Unimplemented {
+			t.Errorf("got %v want %v", err, codes.Unimplemented)
+		}
+	})
+}
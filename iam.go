@@ -0,0 +1,738 @@
+package mockgcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+	iam "google.golang.org/api/iam/v1"
+)
+
+const (
+	serviceAccountDomain = "iam.gserviceaccount.com"
+)
+
+var (
+	serviceAccountIDFormat   = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])$`)
+	serviceAccountNameFormat = regexp.MustCompile(`^projects/[^/]+/serviceAccounts/[^/]+$`)
+)
+
+// ServiceAccount is a mock of a google cloud IAM Service Account.  Service accounts belong
+// to a Project, and carry their own IAM policy (which is where roles like
+// roles/iam.serviceAccountUser get granted)
+type ServiceAccount struct {
+	IamPolicyHolder
+	Name        string
+	ProjectID   string
+	UniqueID    string
+	Email       string
+	DisplayName string
+	Description string
+	Disabled    bool
+	Deleted     bool
+	DeleteTime  time.Time
+	Keys        []*ServiceAccountKey
+}
+
+// ServiceAccountKey is a mock of a key on a google cloud IAM Service Account
+type ServiceAccountKey struct {
+	Name            string
+	KeyAlgorithm    string
+	KeyType         string
+	PrivateKeyType  string
+	ValidAfterTime  time.Time
+	ValidBeforeTime time.Time
+}
+
+// toAPI converts the mock service account to the type returned by the IAM API
+func (sa *ServiceAccount) toAPI() *iam.ServiceAccount {
+	return &iam.ServiceAccount{
+		Name:           sa.Name,
+		ProjectId:      strings.TrimPrefix(sa.ProjectID, "projects/"),
+		UniqueId:       sa.UniqueID,
+		Email:          sa.Email,
+		DisplayName:    sa.DisplayName,
+		Description:    sa.Description,
+		Disabled:       sa.Disabled,
+		Oauth2ClientId: sa.UniqueID,
+	}
+}
+
+// toAPI converts the mock key to the type returned by the IAM API.  Private key data is only
+// returned by the Create call, so it's left off here
+func (k *ServiceAccountKey) toAPI() *iam.ServiceAccountKey {
+	return &iam.ServiceAccountKey{
+		Name:            k.Name,
+		KeyAlgorithm:    k.KeyAlgorithm,
+		KeyType:         k.KeyType,
+		KeyOrigin:       "GOOGLE_PROVIDED",
+		PrivateKeyType:  k.PrivateKeyType,
+		ValidAfterTime:  k.ValidAfterTime.Format(time.RFC3339),
+		ValidBeforeTime: k.ValidBeforeTime.Format(time.RFC3339),
+	}
+}
+
+// ServiceAccountsService is a mock of google Cloud's IAM projects.serviceAccounts Service
+type ServiceAccountsService struct {
+	Service *MockService
+	Keys    *ServiceAccountsKeysService
+}
+
+// NewServiceAccountsService will return a new Service Accounts Service
+func NewServiceAccountsService(s *MockService) *ServiceAccountsService {
+	rs := &ServiceAccountsService{Service: s}
+	rs.Keys = NewServiceAccountsKeysService(s)
+	return rs
+}
+
+// NewServiceAccount creates a new service account with the specified account ID on the project
+// and returns a pointer to it.  It returns nil if the project doesn't exist in the Projects Service
+func (r *ServiceAccountsService) NewServiceAccount(projectID, accountID, displayName string) *ServiceAccount {
+	project := r.Service.Projects.get(projectID)
+	if project == nil {
+		return nil
+	}
+	email := fmt.Sprintf("%v@%v.%v", accountID, strings.TrimPrefix(projectID, "projects/"), serviceAccountDomain)
+	serviceAccount := &ServiceAccount{
+		Name:        fmt.Sprintf("%v/serviceAccounts/%v", projectID, email),
+		ProjectID:   projectID,
+		UniqueID:    generateUniqueID(),
+		Email:       email,
+		DisplayName: displayName,
+	}
+	project.ServiceAccounts = append(project.ServiceAccounts, serviceAccount)
+	r.Service.publishNewAsset(&serviceAccount.IamPolicyHolder)
+	return serviceAccount
+}
+
+// find looks up a service account by resource name.  The name can be in the form of
+// projects/{project}/serviceAccounts/{email or unique ID}, and the project can be the
+// "-" wildcard the same as the real API.  Deleted service accounts are only returned
+// if includeDeleted is set
+func (r *ServiceAccountsService) find(name string, includeDeleted bool) *ServiceAccount {
+	if !serviceAccountNameFormat.MatchString(name) {
+		return nil
+	}
+	parts := strings.Split(name, "/")
+	projectID, account := "projects/"+parts[1], parts[3]
+	for _, project := range r.Service.Projects.ProjectList {
+		if parts[1] != "-" && project.ProjectID != projectID {
+			continue
+		}
+		for _, serviceAccount := range project.ServiceAccounts {
+			if serviceAccount.Deleted && !includeDeleted {
+				continue
+			}
+			if serviceAccount.Email == account || serviceAccount.UniqueID == account {
+				return serviceAccount
+			}
+		}
+	}
+	return nil
+}
+
+// Create will take a project resource name and a createserviceaccountrequest and returns
+// a Create Call, so we can run a Do() method on it.
+func (r *ServiceAccountsService) Create(name string, createserviceaccountrequest *iam.CreateServiceAccountRequest) *ServiceAccountsCreateCall {
+	c := &ServiceAccountsCreateCall{Service: r.Service}
+	c.Name = name
+	c.Createserviceaccountrequest = createserviceaccountrequest
+	return c
+}
+
+// ServiceAccountsCreateCall is a structure that is returned by ServiceAccounts.Create.  Then we
+// call Do() on it to create the service account
+type ServiceAccountsCreateCall struct {
+	Service                     *MockService
+	Name                        string
+	Createserviceaccountrequest *iam.CreateServiceAccountRequest
+	ctx                         context.Context
+}
+
+// Do will be called on ServiceAccountsCreateCall to create the service account and return it
+func (c *ServiceAccountsCreateCall) Do(opts ...googleapi.CallOption) (result *iam.ServiceAccount, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.Create", c.Name, c.Createserviceaccountrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if c.Service.Projects.get(c.Name) == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	if c.Createserviceaccountrequest == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+	accountID := c.Createserviceaccountrequest.AccountId
+	if len(accountID) < 6 || len(accountID) > 30 || !serviceAccountIDFormat.MatchString(accountID) {
+		return nil, fmt.Errorf("invalid account id: %v", accountID)
+	}
+	email := fmt.Sprintf("%v@%v.%v", accountID, strings.TrimPrefix(c.Name, "projects/"), serviceAccountDomain)
+	if c.Service.ServiceAccounts.find(fmt.Sprintf("%v/serviceAccounts/%v", c.Name, email), false) != nil {
+		return nil, fmt.Errorf("%v: %v", resourceAlreadyExistsError, email)
+	}
+
+	serviceAccount := c.Service.ServiceAccounts.NewServiceAccount(c.Name, accountID, "")
+	if sa := c.Createserviceaccountrequest.ServiceAccount; sa != nil {
+		serviceAccount.DisplayName = sa.DisplayName
+		serviceAccount.Description = sa.Description
+	}
+	c.Service.AuditLogs.recordMutation("iam.googleapis.com", "google.iam.admin.v1.CreateServiceAccount", serviceAccount.Name)
+	return serviceAccount.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsCreateCall) Context(ctx context.Context) *ServiceAccountsCreateCall {
+	c.ctx = ctx
+	return c
+}
+
+// Get will take a service account resource name and returns a Get Call, so we can run a Do() method on it.
+func (r *ServiceAccountsService) Get(name string) *ServiceAccountsGetCall {
+	return &ServiceAccountsGetCall{Service: r.Service, Name: name}
+}
+
+// ServiceAccountsGetCall is a structure that is returned by ServiceAccounts.Get.  Then we
+// call Do() on it to return the service account
+type ServiceAccountsGetCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on ServiceAccountsGetCall and return the service account found
+func (c *ServiceAccountsGetCall) Do(opts ...googleapi.CallOption) (result *iam.ServiceAccount, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.Get", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	serviceAccount := c.Service.ServiceAccounts.find(c.Name, false)
+	if serviceAccount == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	return serviceAccount.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsGetCall) Context(ctx context.Context) *ServiceAccountsGetCall {
+	c.ctx = ctx
+	return c
+}
+
+// List will take a project resource name and returns a List Call, so we can run a Do() method on it.
+func (r *ServiceAccountsService) List(name string) *ServiceAccountsListCall {
+	return &ServiceAccountsListCall{Service: r.Service, Name: name}
+}
+
+// ServiceAccountsListCall is a structure that is returned by ServiceAccounts.List.  Then we
+// call Do() on it to return the project's service accounts
+type ServiceAccountsListCall struct {
+	Service   *MockService
+	Name      string
+	pageSize  int64
+	pageToken string
+	ctx       context.Context
+}
+
+// PageSize sets the maximum number of service accounts returned in one response
+func (c *ServiceAccountsListCall) PageSize(pageSize int64) *ServiceAccountsListCall {
+	c.pageSize = pageSize
+	return c
+}
+
+// PageToken sets the token from a previous response's NextPageToken to continue listing
+func (c *ServiceAccountsListCall) PageToken(pageToken string) *ServiceAccountsListCall {
+	c.pageToken = pageToken
+	return c
+}
+
+// Do will be called on ServiceAccountsListCall and return the service accounts for the project
+func (c *ServiceAccountsListCall) Do(opts ...googleapi.CallOption) (result *iam.ListServiceAccountsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.List", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	project := c.Service.Projects.get(c.Name)
+	if project == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	var accounts []*iam.ServiceAccount
+	for _, serviceAccount := range project.ServiceAccounts {
+		if !serviceAccount.Deleted {
+			accounts = append(accounts, serviceAccount.toAPI())
+		}
+	}
+
+	start := 0
+	if c.pageToken != "" {
+		var err error
+		if start, err = strconv.Atoi(c.pageToken); err != nil || start > len(accounts) {
+			return nil, fmt.Errorf("invalid page token: %v", c.pageToken)
+		}
+	}
+	response := &iam.ListServiceAccountsResponse{Accounts: accounts[start:]}
+	if c.pageSize > 0 && int64(len(response.Accounts)) > c.pageSize {
+		response.Accounts = response.Accounts[:c.pageSize]
+		response.NextPageToken = strconv.Itoa(start + int(c.pageSize))
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsListCall) Context(ctx context.Context) *ServiceAccountsListCall {
+	c.ctx = ctx
+	return c
+}
+
+// Disable will take a service account resource name and a disableserviceaccountrequest and returns
+// a Disable Call, so we can run a Do() method on it.
+func (r *ServiceAccountsService) Disable(name string, disableserviceaccountrequest *iam.DisableServiceAccountRequest) *ServiceAccountsDisableCall {
+	return &ServiceAccountsDisableCall{Service: r.Service, Name: name, Disableserviceaccountrequest: disableserviceaccountrequest}
+}
+
+// ServiceAccountsDisableCall is a structure that is returned by ServiceAccounts.Disable.  Then we
+// call Do() on it to disable the service account
+type ServiceAccountsDisableCall struct {
+	Service                      *MockService
+	Name                         string
+	Disableserviceaccountrequest *iam.DisableServiceAccountRequest
+	ctx                          context.Context
+}
+
+// Do will be called on ServiceAccountsDisableCall to disable the service account
+func (c *ServiceAccountsDisableCall) Do(opts ...googleapi.CallOption) (result *iam.Empty, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.Disable", c.Name, c.Disableserviceaccountrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	serviceAccount := c.Service.ServiceAccounts.find(c.Name, false)
+	if serviceAccount == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	serviceAccount.Disabled = true
+	c.Service.AuditLogs.recordMutation("iam.googleapis.com", "google.iam.admin.v1.DisableServiceAccount", serviceAccount.Name)
+	return &iam.Empty{}, nil
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsDisableCall) Context(ctx context.Context) *ServiceAccountsDisableCall {
+	c.ctx = ctx
+	return c
+}
+
+// Enable will take a service account resource name and an enableserviceaccountrequest and returns
+// an Enable Call, so we can run a Do() method on it.
+func (r *ServiceAccountsService) Enable(name string, enableserviceaccountrequest *iam.EnableServiceAccountRequest) *ServiceAccountsEnableCall {
+	return &ServiceAccountsEnableCall{Service: r.Service, Name: name, Enableserviceaccountrequest: enableserviceaccountrequest}
+}
+
+// ServiceAccountsEnableCall is a structure that is returned by ServiceAccounts.Enable.  Then we
+// call Do() on it to enable the service account
+type ServiceAccountsEnableCall struct {
+	Service                     *MockService
+	Name                        string
+	Enableserviceaccountrequest *iam.EnableServiceAccountRequest
+	ctx                         context.Context
+}
+
+// Do will be called on ServiceAccountsEnableCall to enable the service account
+func (c *ServiceAccountsEnableCall) Do(opts ...googleapi.CallOption) (result *iam.Empty, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.Enable", c.Name, c.Enableserviceaccountrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	serviceAccount := c.Service.ServiceAccounts.find(c.Name, false)
+	if serviceAccount == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	serviceAccount.Disabled = false
+	c.Service.AuditLogs.recordMutation("iam.googleapis.com", "google.iam.admin.v1.EnableServiceAccount", serviceAccount.Name)
+	return &iam.Empty{}, nil
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsEnableCall) Context(ctx context.Context) *ServiceAccountsEnableCall {
+	c.ctx = ctx
+	return c
+}
+
+// Delete will take a service account resource name and returns a Delete Call, so we can run a Do() method on it.
+func (r *ServiceAccountsService) Delete(name string) *ServiceAccountsDeleteCall {
+	return &ServiceAccountsDeleteCall{Service: r.Service, Name: name}
+}
+
+// ServiceAccountsDeleteCall is a structure that is returned by ServiceAccounts.Delete.  Then we
+// call Do() on it to delete the service account
+type ServiceAccountsDeleteCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on ServiceAccountsDeleteCall to delete the service account.  Like the real
+// API the account is kept around so it can be undeleted by its unique ID
+func (c *ServiceAccountsDeleteCall) Do(opts ...googleapi.CallOption) (result *iam.Empty, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.Delete", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	serviceAccount := c.Service.ServiceAccounts.find(c.Name, false)
+	if serviceAccount == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	prior := c.Service.assetSnapshot(&serviceAccount.IamPolicyHolder)
+	serviceAccount.Deleted = true
+	serviceAccount.DeleteTime = c.Service.now().UTC()
+	c.Service.AuditLogs.recordMutation("iam.googleapis.com", "google.iam.admin.v1.DeleteServiceAccount", serviceAccount.Name)
+	c.Service.publishAssetChange(prior, nil)
+	return &iam.Empty{}, nil
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsDeleteCall) Context(ctx context.Context) *ServiceAccountsDeleteCall {
+	c.ctx = ctx
+	return c
+}
+
+// Undelete will take a service account resource name (projects/-/serviceAccounts/{unique ID}) and an
+// undeleteserviceaccountrequest and returns an Undelete Call, so we can run a Do() method on it.
+func (r *ServiceAccountsService) Undelete(name string, undeleteserviceaccountrequest *iam.UndeleteServiceAccountRequest) *ServiceAccountsUndeleteCall {
+	return &ServiceAccountsUndeleteCall{Service: r.Service, Name: name, Undeleteserviceaccountrequest: undeleteserviceaccountrequest}
+}
+
+// ServiceAccountsUndeleteCall is a structure that is returned by ServiceAccounts.Undelete.  Then we
+// call Do() on it to restore the service account
+type ServiceAccountsUndeleteCall struct {
+	Service                       *MockService
+	Name                          string
+	Undeleteserviceaccountrequest *iam.UndeleteServiceAccountRequest
+	ctx                           context.Context
+}
+
+// Do will be called on ServiceAccountsUndeleteCall to restore a deleted service account.  It fails
+// if the account isn't deleted, if it was deleted more than 30 days ago, or if another account has been
+// created with the same email since
+func (c *ServiceAccountsUndeleteCall) Do(opts ...googleapi.CallOption) (result *iam.UndeleteServiceAccountResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.Undelete", c.Name, c.Undeleteserviceaccountrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	serviceAccount := c.Service.ServiceAccounts.find(c.Name, true)
+	if serviceAccount == nil || !serviceAccount.Deleted || !c.Service.now().Before(serviceAccount.DeleteTime.Add(purgeAfter)) {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	if c.Service.ServiceAccounts.find(serviceAccount.Name, false) != nil {
+		return nil, fmt.Errorf("%v: %v", resourceAlreadyExistsError, serviceAccount.Email)
+	}
+	serviceAccount.Deleted = false
+	serviceAccount.DeleteTime = time.Time{}
+	c.Service.AuditLogs.recordMutation("iam.googleapis.com", "google.iam.admin.v1.UndeleteServiceAccount", serviceAccount.Name)
+	c.Service.publishNewAsset(&serviceAccount.IamPolicyHolder)
+	return &iam.UndeleteServiceAccountResponse{RestoredAccount: serviceAccount.toAPI()}, nil
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsUndeleteCall) Context(ctx context.Context) *ServiceAccountsUndeleteCall {
+	c.ctx = ctx
+	return c
+}
+
+// GetIamPolicy will take a service account resource name, and a getiampolicyrequest
+// and returns a GetIamPolicy Call, so we can run a Do() method on it.  The policy uses the
+// cloudresourcemanager types so the policy helpers in this package work on it
+func (r *ServiceAccountsService) GetIamPolicy(resource string, getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest) *ServiceAccountsGetIamPolicyCall {
+	c := &ServiceAccountsGetIamPolicyCall{Service: r.Service}
+	c.Resource = resource
+	c.Getiampolicyrequest = getiampolicyrequest
+	return c
+}
+
+// SetIamPolicy will take a service account resource name, and a setiampolicyrequest
+// and returns a SetIamPolicy Call, so we can run a Do() method on it.
+func (r *ServiceAccountsService) SetIamPolicy(resource string, setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest) *ServiceAccountsSetIamPolicyCall {
+	c := &ServiceAccountsSetIamPolicyCall{Service: r.Service}
+	c.Resource = resource
+	c.Setiampolicyrequest = setiampolicyrequest
+	return c
+}
+
+// TestIamPermissions will take a service account resource name, and a testiampermissionsrequest
+// and returns a TestIamPermissions Call, so we can run a Do() method on it.
+func (r *ServiceAccountsService) TestIamPermissions(resource string, testiampermissionsrequest *cloudresourcemanager.TestIamPermissionsRequest) *ServiceAccountsTestIamPermissionsCall {
+	c := &ServiceAccountsTestIamPermissionsCall{Service: r.Service}
+	c.Resource = resource
+	c.Testiampermissionsrequest = testiampermissionsrequest
+	return c
+}
+
+// policyHolder returns the policy holder for the (not deleted) service account, or nil if there isn't one
+func (r *ServiceAccountsService) policyHolder(resource string) *IamPolicyHolder {
+	serviceAccount := r.find(resource, false)
+	if serviceAccount == nil {
+		return nil
+	}
+	return &serviceAccount.IamPolicyHolder
+}
+
+// ServiceAccountsGetIamPolicyCall is a structure that is returned by ServiceAccounts.GetIamPolicy which contains the Request
+// to get a policy.  Then we call Do() on it to actually return the policy
+type ServiceAccountsGetIamPolicyCall struct {
+	Service             *MockService
+	Resource            string
+	Getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest
+	ctx                 context.Context
+}
+
+// Do will be called on ServiceAccountsGetIamPolicyCall and return the policy found
+func (c *ServiceAccountsGetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.GetIamPolicy", c.Resource, c.Getiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	return getIamPolicy(c.Resource, serviceAccountNameFormat, c.Service.ServiceAccounts.policyHolder)
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsGetIamPolicyCall) Context(ctx context.Context) *ServiceAccountsGetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// ServiceAccountsSetIamPolicyCall is a structure that is returned by ServiceAccounts.SetIamPolicy which contains the Request
+// to set a policy.  Then we call Do() on in it to Set the Service Account Policy
+type ServiceAccountsSetIamPolicyCall struct {
+	Service             *MockService
+	Resource            string
+	Setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest
+	ctx                 context.Context
+}
+
+// Do will be called on ServiceAccountsSetIamPolicyCall to process the policy change and returns the policy it sets
+func (c *ServiceAccountsSetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.SetIamPolicy", c.Resource, c.Setiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	return setIamPolicy(c.Service, c.Resource, serviceAccountNameFormat, c.Service.ServiceAccounts.policyHolder, c.Setiampolicyrequest)
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsSetIamPolicyCall) Context(ctx context.Context) *ServiceAccountsSetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// ServiceAccountsTestIamPermissionsCall is a structure that is returned by ServiceAccounts.TestIamPermissions which contains
+// the Request to test permissions.  Then we call Do() on it to find which of the permissions the Caller has
+type ServiceAccountsTestIamPermissionsCall struct {
+	Service                   *MockService
+	Resource                  string
+	Testiampermissionsrequest *cloudresourcemanager.TestIamPermissionsRequest
+	ctx                       context.Context
+}
+
+// Do will be called on ServiceAccountsTestIamPermissionsCall and return the permissions the Caller has on the service account
+func (c *ServiceAccountsTestIamPermissionsCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.TestIamPermissionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.TestIamPermissions", c.Resource, c.Testiampermissionsrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	return testIamPermissions(c.Service, c.Resource, serviceAccountNameFormat, c.Service.ServiceAccounts.policyHolder, c.Testiampermissionsrequest)
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsTestIamPermissionsCall) Context(ctx context.Context) *ServiceAccountsTestIamPermissionsCall {
+	c.ctx = ctx
+	return c
+}
+
+// ServiceAccountsKeysService is a mock of google Cloud's IAM projects.serviceAccounts.keys Service
+type ServiceAccountsKeysService struct {
+	Service *MockService
+}
+
+// NewServiceAccountsKeysService will return a new Service Account Keys Service
+func NewServiceAccountsKeysService(s *MockService) *ServiceAccountsKeysService {
+	rs := &ServiceAccountsKeysService{Service: s}
+	return rs
+}
+
+// Create will take a service account resource name and a createserviceaccountkeyrequest and returns
+// a Create Call, so we can run a Do() method on it.
+func (r *ServiceAccountsKeysService) Create(name string, createserviceaccountkeyrequest *iam.CreateServiceAccountKeyRequest) *ServiceAccountsKeysCreateCall {
+	return &ServiceAccountsKeysCreateCall{Service: r.Service, Name: name, Createserviceaccountkeyrequest: createserviceaccountkeyrequest}
+}
+
+// ServiceAccountsKeysCreateCall is a structure that is returned by ServiceAccounts.Keys.Create.  Then we
+// call Do() on it to create the key
+type ServiceAccountsKeysCreateCall struct {
+	Service                        *MockService
+	Name                           string
+	Createserviceaccountkeyrequest *iam.CreateServiceAccountKeyRequest
+	ctx                            context.Context
+}
+
+// Do will be called on ServiceAccountsKeysCreateCall to create a user managed key and return it,
+// including some (fake) private key data
+func (c *ServiceAccountsKeysCreateCall) Do(opts ...googleapi.CallOption) (result *iam.ServiceAccountKey, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.Keys.Create", c.Name, c.Createserviceaccountkeyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	serviceAccount := c.Service.ServiceAccounts.find(c.Name, false)
+	if serviceAccount == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	key := &ServiceAccountKey{
+		Name:            fmt.Sprintf("%v/keys/%x", serviceAccount.Name, rand.Uint64()),
+		KeyAlgorithm:    "KEY_ALG_RSA_2048",
+		KeyType:         "USER_MANAGED",
+		PrivateKeyType:  "TYPE_GOOGLE_CREDENTIALS_FILE",
+		ValidAfterTime:  c.Service.now().UTC(),
+		ValidBeforeTime: time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+	}
+	if request := c.Createserviceaccountkeyrequest; request != nil {
+		if request.KeyAlgorithm != "" {
+			key.KeyAlgorithm = request.KeyAlgorithm
+		}
+		if request.PrivateKeyType != "" {
+			key.PrivateKeyType = request.PrivateKeyType
+		}
+	}
+	serviceAccount.Keys = append(serviceAccount.Keys, key)
+	c.Service.AuditLogs.recordMutation("iam.googleapis.com", "google.iam.admin.v1.CreateServiceAccountKey", key.Name)
+
+	response := key.toAPI()
+	response.PrivateKeyData = base64.StdEncoding.EncodeToString([]byte(StringGenerator()))
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsKeysCreateCall) Context(ctx context.Context) *ServiceAccountsKeysCreateCall {
+	c.ctx = ctx
+	return c
+}
+
+// List will take a service account resource name and returns a List Call, so we can run a Do() method on it.
+func (r *ServiceAccountsKeysService) List(name string) *ServiceAccountsKeysListCall {
+	return &ServiceAccountsKeysListCall{Service: r.Service, Name: name}
+}
+
+// ServiceAccountsKeysListCall is a structure that is returned by ServiceAccounts.Keys.List.  Then we
+// call Do() on it to return the service account's keys
+type ServiceAccountsKeysListCall struct {
+	Service  *MockService
+	Name     string
+	keyTypes []string
+	ctx      context.Context
+}
+
+// KeyTypes filters the keys returned to the given key types (USER_MANAGED or SYSTEM_MANAGED)
+func (c *ServiceAccountsKeysListCall) KeyTypes(keyTypes ...string) *ServiceAccountsKeysListCall {
+	c.keyTypes = append([]string{}, keyTypes...)
+	return c
+}
+
+// Do will be called on ServiceAccountsKeysListCall and return the keys found
+func (c *ServiceAccountsKeysListCall) Do(opts ...googleapi.CallOption) (result *iam.ListServiceAccountKeysResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.Keys.List", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	serviceAccount := c.Service.ServiceAccounts.find(c.Name, false)
+	if serviceAccount == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	response := &iam.ListServiceAccountKeysResponse{}
+	for _, key := range serviceAccount.Keys {
+		if len(c.keyTypes) > 0 && !stringInSlice(key.KeyType, c.keyTypes) {
+			continue
+		}
+		response.Keys = append(response.Keys, key.toAPI())
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsKeysListCall) Context(ctx context.Context) *ServiceAccountsKeysListCall {
+	c.ctx = ctx
+	return c
+}
+
+// Delete will take a key resource name and returns a Delete Call, so we can run a Do() method on it.
+func (r *ServiceAccountsKeysService) Delete(name string) *ServiceAccountsKeysDeleteCall {
+	return &ServiceAccountsKeysDeleteCall{Service: r.Service, Name: name}
+}
+
+// ServiceAccountsKeysDeleteCall is a structure that is returned by ServiceAccounts.Keys.Delete.  Then we
+// call Do() on it to delete the key
+type ServiceAccountsKeysDeleteCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on ServiceAccountsKeysDeleteCall to delete the key
+func (c *ServiceAccountsKeysDeleteCall) Do(opts ...googleapi.CallOption) (result *iam.Empty, err error) {
+	call, err := c.Service.beginCall(c.ctx, "ServiceAccounts.Keys.Delete", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	index := strings.Index(c.Name, "/keys/")
+	if index < 0 {
+		return nil, fmt.Errorf("resource format invalid")
+	}
+	serviceAccount := c.Service.ServiceAccounts.find(c.Name[:index], false)
+	if serviceAccount == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	keyID := c.Name[index:]
+	for i, key := range serviceAccount.Keys {
+		if strings.HasSuffix(key.Name, keyID) {
+			serviceAccount.Keys = append(serviceAccount.Keys[:i], serviceAccount.Keys[i+1:]...)
+			c.Service.AuditLogs.recordMutation("iam.googleapis.com", "google.iam.admin.v1.DeleteServiceAccountKey", key.Name)
+			return &iam.Empty{}, nil
+		}
+	}
+	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+}
+
+// Context sets the context used by Do
+func (c *ServiceAccountsKeysDeleteCall) Context(ctx context.Context) *ServiceAccountsKeysDeleteCall {
+	c.ctx = ctx
+	return c
+}
+
+// generateUniqueID returns a random 21 digit numeric ID, the same shape as the ones google
+// hands out for service accounts
+func generateUniqueID() string {
+	return fmt.Sprintf("1%010d%010d", rand.Int63n(1e10), rand.Int63n(1e10))
+}
+
+// stringInSlice returns true if s is one of the strings in list
+func stringInSlice(s string, list []string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package mockgcp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	iam "google.golang.org/api/iam/v1"
+)
+
+func TestServiceAccounts_Create_Do(t *testing.T) {
+	t.Run("should create service account on project", func(t *testing.T) {
+		projectID := "projects/TestProject"
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject(projectID, "", nil)
+
+		request := &iam.CreateServiceAccountRequest{
+			AccountId:      "test-account",
+			ServiceAccount: &iam.ServiceAccount{DisplayName: "Test Account"},
+		}
+		got, err := service.ServiceAccounts.Create(projectID, request).Do()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		want := "test-account@TestProject.iam.gserviceaccount.com"
+		if got.Email != want {
+			t.Errorf("got %v want %v", got.Email, want)
+		}
+		if len(service.Projects.ProjectList[0].ServiceAccounts) != 1 {
+			t.Errorf("expected service account to be added to the project")
+		}
+	})
+	t.Run("should return err if project doesn't exist", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		request := &iam.CreateServiceAccountRequest{AccountId: "test-account"}
+
+		_, err := service.ServiceAccounts.Create("projects/TestProject", request).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+	t.Run("should return err if account already exists", func(t *testing.T) {
+		projectID := "projects/TestProject"
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject(projectID, "", nil)
+		service.ServiceAccounts.NewServiceAccount(projectID, "test-account", "")
+		request := &iam.CreateServiceAccountRequest{AccountId: "test-account"}
+
+		_, err := service.ServiceAccounts.Create(projectID, request).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+	t.Run("should return err if account id is invalid", func(t *testing.T) {
+		projectID := "projects/TestProject"
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject(projectID, "", nil)
+		request := &iam.CreateServiceAccountRequest{AccountId: "Bad_ID"}
+
+		_, err := service.ServiceAccounts.Create(projectID, request).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+	t.Run("should return err if request is nil", func(t *testing.T) {
+		projectID := "projects/TestProject"
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject(projectID, "", nil)
+
+		_, err := service.ServiceAccounts.Create(projectID, nil).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestServiceAccounts_List_Do(t *testing.T) {
+	projectID := "projects/TestProject"
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject(projectID, "", nil)
+	for _, id := range []string{"account-one", "account-two", "account-three"} {
+		service.ServiceAccounts.NewServiceAccount(projectID, id, "")
+	}
+
+	t.Run("should page through service accounts", func(t *testing.T) {
+		first, _ := service.ServiceAccounts.List(projectID).PageSize(2).Do()
+		second, _ := service.ServiceAccounts.List(projectID).PageSize(2).PageToken(first.NextPageToken).Do()
+
+		want := 3
+		got := len(first.Accounts) + len(second.Accounts)
+
+		if got != want {
+			t.Errorf("got %v want %v", got, want)
+		}
+		if second.NextPageToken != "" {
+			t.Errorf("expected last page to have no next page token")
+		}
+	})
+}
+
+func TestServiceAccounts_Lifecycle(t *testing.T) {
+	projectID := "projects/TestProject"
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject(projectID, "", nil)
+	serviceAccount := service.ServiceAccounts.NewServiceAccount(projectID, "test-account", "")
+
+	t.Run("should disable and enable service account", func(t *testing.T) {
+		service.ServiceAccounts.Disable(serviceAccount.Name, nil).Do()
+		got, _ := service.ServiceAccounts.Get(serviceAccount.Name).Do()
+		if !got.Disabled {
+			t.Errorf("expected service account to be disabled")
+		}
+
+		service.ServiceAccounts.Enable(serviceAccount.Name, nil).Do()
+		got, _ = service.ServiceAccounts.Get(serviceAccount.Name).Do()
+		if got.Disabled {
+			t.Errorf("expected service account to be enabled")
+		}
+	})
+	t.Run("should not get deleted service account", func(t *testing.T) {
+		service.ServiceAccounts.Delete(serviceAccount.Name).Do()
+
+		_, err := service.ServiceAccounts.Get(serviceAccount.Name).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+	t.Run("should undelete service account by unique id", func(t *testing.T) {
+		response, err := service.ServiceAccounts.Undelete("projects/-/serviceAccounts/"+serviceAccount.UniqueID, nil).Do()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		want := serviceAccount.Email
+		got := response.RestoredAccount.Email
+
+		if got != want {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("should return err undeleting an active service account", func(t *testing.T) {
+		_, err := service.ServiceAccounts.Undelete("projects/-/serviceAccounts/"+serviceAccount.UniqueID, nil).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestServiceAccounts_Keys(t *testing.T) {
+	projectID := "projects/TestProject"
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject(projectID, "", nil)
+	serviceAccount := service.ServiceAccounts.NewServiceAccount(projectID, "test-account", "")
+
+	t.Run("should create and list keys", func(t *testing.T) {
+		key, err := service.ServiceAccounts.Keys.Create(serviceAccount.Name, &iam.CreateServiceAccountKeyRequest{}).Do()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if key.PrivateKeyData == "" {
+			t.Errorf("expected private key data on created key")
+		}
+
+		response, _ := service.ServiceAccounts.Keys.List(serviceAccount.Name).KeyTypes("USER_MANAGED").Do()
+
+		want := key.Name
+		got := response.Keys[0].Name
+
+		if got != want {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("should delete key", func(t *testing.T) {
+		response, _ := service.ServiceAccounts.Keys.List(serviceAccount.Name).Do()
+		service.ServiceAccounts.Keys.Delete(response.Keys[0].Name).Do()
+
+		response, _ = service.ServiceAccounts.Keys.List(serviceAccount.Name).Do()
+
+		if len(response.Keys) != 0 {
+			t.Errorf("expected no keys but found %v", len(response.Keys))
+		}
+	})
+}
+
+func TestServiceAccounts_IamPolicy_Do(t *testing.T) {
+	t.Run("should set and get service account policy", func(t *testing.T) {
+		projectID := "projects/TestProject"
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject(projectID, "", nil)
+		serviceAccount := service.ServiceAccounts.NewServiceAccount(projectID, "test-account", "")
+
+		policy := GeneratePolicy(NewBinding("roles/iam.serviceAccountUser", "user:test@testdomain.co"))
+		request := &cloudresourcemanager.SetIamPolicyRequest{Policy: policy}
+		service.ServiceAccounts.SetIamPolicy(serviceAccount.Name, request).Do()
+
+		want := policy
+		got, _ := service.ServiceAccounts.GetIamPolicy(serviceAccount.Name, nil).Do()
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("should return err if resource name doesn't match format", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		request := &cloudresourcemanager.SetIamPolicyRequest{Policy: GeneratePolicy()}
+
+		_, err := service.ServiceAccounts.SetIamPolicy("test-account", request).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
@@ -0,0 +1,442 @@
+package mockgcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+// RolePermissions is the catalog of roles the mock knows about, and the permissions each one grants.
+// It's used by TestIamPermissions to decide what a member can do.  It only covers the permissions for
+// the services this package mocks, and tests can add their own (custom) roles to it
+var RolePermissions = map[string][]string{
+	"roles/owner": {
+		"resourcemanager.projects.get", "resourcemanager.projects.getIamPolicy", "resourcemanager.projects.setIamPolicy",
+		"resourcemanager.projects.update", "resourcemanager.projects.delete",
+		"iam.serviceAccounts.get", "iam.serviceAccounts.list", "iam.serviceAccounts.create", "iam.serviceAccounts.delete",
+		"iam.serviceAccounts.getIamPolicy", "iam.serviceAccounts.setIamPolicy", "iam.serviceAccounts.actAs",
+		"iam.serviceAccountKeys.create", "iam.serviceAccountKeys.list", "iam.serviceAccountKeys.delete",
+		"storage.buckets.create", "storage.buckets.delete", "storage.buckets.get", "storage.buckets.list", "storage.buckets.update",
+		"storage.buckets.getIamPolicy", "storage.buckets.setIamPolicy",
+		"pubsub.topics.create", "pubsub.topics.delete", "pubsub.topics.get", "pubsub.topics.list", "pubsub.topics.publish",
+		"pubsub.topics.getIamPolicy", "pubsub.topics.setIamPolicy",
+		"pubsub.subscriptions.create", "pubsub.subscriptions.delete", "pubsub.subscriptions.get", "pubsub.subscriptions.list",
+		"pubsub.subscriptions.consume", "pubsub.subscriptions.getIamPolicy", "pubsub.subscriptions.setIamPolicy",
+		"secretmanager.secrets.create", "secretmanager.secrets.delete", "secretmanager.secrets.get", "secretmanager.secrets.list",
+		"secretmanager.secrets.getIamPolicy", "secretmanager.secrets.setIamPolicy",
+		"secretmanager.versions.add", "secretmanager.versions.get", "secretmanager.versions.list", "secretmanager.versions.access",
+		"secretmanager.versions.enable", "secretmanager.versions.disable", "secretmanager.versions.destroy",
+		"cloudkms.keyRings.create", "cloudkms.keyRings.get", "cloudkms.keyRings.list",
+		"cloudkms.keyRings.getIamPolicy", "cloudkms.keyRings.setIamPolicy",
+		"cloudkms.cryptoKeys.create", "cloudkms.cryptoKeys.get", "cloudkms.cryptoKeys.list",
+		"cloudkms.cryptoKeys.getIamPolicy", "cloudkms.cryptoKeys.setIamPolicy",
+	},
+	"roles/editor": {
+		"resourcemanager.projects.get", "resourcemanager.projects.getIamPolicy", "resourcemanager.projects.update",
+		"iam.serviceAccounts.get", "iam.serviceAccounts.list", "iam.serviceAccounts.create", "iam.serviceAccounts.delete",
+		"iam.serviceAccounts.getIamPolicy", "iam.serviceAccounts.actAs",
+		"iam.serviceAccountKeys.create", "iam.serviceAccountKeys.list", "iam.serviceAccountKeys.delete",
+		"storage.buckets.create", "storage.buckets.delete", "storage.buckets.get", "storage.buckets.list", "storage.buckets.update",
+		"pubsub.topics.create", "pubsub.topics.delete", "pubsub.topics.get", "pubsub.topics.list", "pubsub.topics.publish",
+		"pubsub.subscriptions.create", "pubsub.subscriptions.delete", "pubsub.subscriptions.get", "pubsub.subscriptions.list",
+		"pubsub.subscriptions.consume",
+		"secretmanager.secrets.create", "secretmanager.secrets.delete", "secretmanager.secrets.get", "secretmanager.secrets.list",
+		"secretmanager.versions.add", "secretmanager.versions.get", "secretmanager.versions.list",
+		"secretmanager.versions.enable", "secretmanager.versions.disable", "secretmanager.versions.destroy",
+		"cloudkms.keyRings.create", "cloudkms.keyRings.get", "cloudkms.keyRings.list",
+		"cloudkms.cryptoKeys.create", "cloudkms.cryptoKeys.get", "cloudkms.cryptoKeys.list",
+	},
+	"roles/viewer": {
+		"resourcemanager.projects.get", "resourcemanager.projects.getIamPolicy",
+		"iam.serviceAccounts.get", "iam.serviceAccounts.list", "iam.serviceAccounts.getIamPolicy",
+		"iam.serviceAccountKeys.list",
+		"storage.buckets.get", "storage.buckets.list",
+		"pubsub.topics.get", "pubsub.topics.list", "pubsub.subscriptions.get", "pubsub.subscriptions.list",
+		"secretmanager.secrets.get", "secretmanager.secrets.list", "secretmanager.versions.get", "secretmanager.versions.list",
+		"cloudkms.keyRings.get", "cloudkms.keyRings.list", "cloudkms.cryptoKeys.get", "cloudkms.cryptoKeys.list",
+	},
+	"roles/resourcemanager.organizationAdmin": {
+		"resourcemanager.organizations.get", "resourcemanager.organizations.getIamPolicy", "resourcemanager.organizations.setIamPolicy",
+		"resourcemanager.folders.get", "resourcemanager.folders.list", "resourcemanager.folders.getIamPolicy", "resourcemanager.folders.setIamPolicy",
+		"resourcemanager.projects.get", "resourcemanager.projects.list", "resourcemanager.projects.getIamPolicy", "resourcemanager.projects.setIamPolicy",
+	},
+	"roles/resourcemanager.organizationViewer": {
+		"resourcemanager.organizations.get",
+	},
+	"roles/resourcemanager.folderAdmin": {
+		"resourcemanager.folders.get", "resourcemanager.folders.list", "resourcemanager.folders.create", "resourcemanager.folders.delete",
+		"resourcemanager.folders.update", "resourcemanager.folders.move", "resourcemanager.folders.getIamPolicy", "resourcemanager.folders.setIamPolicy",
+	},
+	"roles/resourcemanager.folderViewer": {
+		"resourcemanager.folders.get", "resourcemanager.folders.list",
+	},
+	"roles/resourcemanager.projectIamAdmin": {
+		"resourcemanager.projects.get", "resourcemanager.projects.getIamPolicy", "resourcemanager.projects.setIamPolicy",
+	},
+	"roles/iam.securityReviewer": {
+		"resourcemanager.organizations.getIamPolicy", "resourcemanager.folders.getIamPolicy", "resourcemanager.projects.getIamPolicy",
+		"iam.serviceAccounts.getIamPolicy",
+	},
+	"roles/iam.serviceAccountUser": {
+		"iam.serviceAccounts.actAs", "iam.serviceAccounts.get", "iam.serviceAccounts.list",
+	},
+	"roles/iam.serviceAccountAdmin": {
+		"iam.serviceAccounts.get", "iam.serviceAccounts.list", "iam.serviceAccounts.create", "iam.serviceAccounts.delete",
+		"iam.serviceAccounts.disable", "iam.serviceAccounts.enable", "iam.serviceAccounts.undelete",
+		"iam.serviceAccounts.getIamPolicy", "iam.serviceAccounts.setIamPolicy",
+	},
+	"roles/iam.serviceAccountKeyAdmin": {
+		"iam.serviceAccountKeys.create", "iam.serviceAccountKeys.list", "iam.serviceAccountKeys.delete",
+	},
+	"roles/storage.admin": {
+		"storage.buckets.create", "storage.buckets.delete", "storage.buckets.get", "storage.buckets.list", "storage.buckets.update",
+		"storage.buckets.getIamPolicy", "storage.buckets.setIamPolicy",
+		"storage.objects.create", "storage.objects.delete", "storage.objects.get", "storage.objects.list", "storage.objects.update",
+		"storage.objects.getIamPolicy", "storage.objects.setIamPolicy",
+	},
+	"roles/storage.objectAdmin": {
+		"storage.objects.create", "storage.objects.delete", "storage.objects.get", "storage.objects.list", "storage.objects.update",
+	},
+	"roles/storage.objectCreator": {
+		"storage.objects.create",
+	},
+	"roles/storage.objectViewer": {
+		"storage.objects.get", "storage.objects.list",
+	},
+	"roles/storage.legacyBucketOwner": {
+		"storage.buckets.get", "storage.buckets.update", "storage.buckets.getIamPolicy", "storage.buckets.setIamPolicy",
+		"storage.objects.create", "storage.objects.delete", "storage.objects.list",
+	},
+	"roles/storage.legacyBucketWriter": {
+		"storage.buckets.get", "storage.objects.create", "storage.objects.delete", "storage.objects.list",
+	},
+	"roles/storage.legacyBucketReader": {
+		"storage.buckets.get", "storage.objects.list",
+	},
+	"roles/storage.legacyObjectOwner": {
+		"storage.objects.get", "storage.objects.update", "storage.objects.getIamPolicy", "storage.objects.setIamPolicy",
+	},
+	"roles/storage.legacyObjectReader": {
+		"storage.objects.get",
+	},
+	"roles/pubsub.admin": {
+		"pubsub.topics.create", "pubsub.topics.delete", "pubsub.topics.get", "pubsub.topics.list", "pubsub.topics.publish",
+		"pubsub.topics.getIamPolicy", "pubsub.topics.setIamPolicy",
+		"pubsub.subscriptions.create", "pubsub.subscriptions.delete", "pubsub.subscriptions.get", "pubsub.subscriptions.list",
+		"pubsub.subscriptions.consume", "pubsub.subscriptions.getIamPolicy", "pubsub.subscriptions.setIamPolicy",
+	},
+	"roles/pubsub.editor": {
+		"pubsub.topics.create", "pubsub.topics.delete", "pubsub.topics.get", "pubsub.topics.list", "pubsub.topics.publish",
+		"pubsub.subscriptions.create", "pubsub.subscriptions.delete", "pubsub.subscriptions.get", "pubsub.subscriptions.list",
+		"pubsub.subscriptions.consume",
+	},
+	"roles/pubsub.publisher": {
+		"pubsub.topics.publish",
+	},
+	"roles/pubsub.subscriber": {
+		"pubsub.subscriptions.consume", "pubsub.topics.attachSubscription",
+	},
+	"roles/pubsub.viewer": {
+		"pubsub.topics.get", "pubsub.topics.list", "pubsub.subscriptions.get", "pubsub.subscriptions.list",
+	},
+	"roles/secretmanager.admin": {
+		"secretmanager.secrets.create", "secretmanager.secrets.delete", "secretmanager.secrets.get", "secretmanager.secrets.list",
+		"secretmanager.secrets.getIamPolicy", "secretmanager.secrets.setIamPolicy",
+		"secretmanager.versions.add", "secretmanager.versions.get", "secretmanager.versions.list", "secretmanager.versions.access",
+		"secretmanager.versions.enable", "secretmanager.versions.disable", "secretmanager.versions.destroy",
+	},
+	"roles/secretmanager.secretAccessor": {
+		"secretmanager.versions.access",
+	},
+	"roles/secretmanager.secretVersionManager": {
+		"secretmanager.versions.add", "secretmanager.versions.get", "secretmanager.versions.list",
+		"secretmanager.versions.enable", "secretmanager.versions.disable", "secretmanager.versions.destroy",
+	},
+	"roles/secretmanager.viewer": {
+		"secretmanager.secrets.get", "secretmanager.secrets.list", "secretmanager.versions.get", "secretmanager.versions.list",
+	},
+	"roles/cloudkms.admin": {
+		"cloudkms.keyRings.create", "cloudkms.keyRings.get", "cloudkms.keyRings.list",
+		"cloudkms.keyRings.getIamPolicy", "cloudkms.keyRings.setIamPolicy",
+		"cloudkms.cryptoKeys.create", "cloudkms.cryptoKeys.get", "cloudkms.cryptoKeys.list", "cloudkms.cryptoKeys.update",
+		"cloudkms.cryptoKeys.getIamPolicy", "cloudkms.cryptoKeys.setIamPolicy",
+	},
+	"roles/cloudkms.cryptoKeyEncrypterDecrypter": {
+		"cloudkms.cryptoKeyVersions.useToEncrypt", "cloudkms.cryptoKeyVersions.useToDecrypt",
+	},
+	"roles/cloudkms.cryptoKeyEncrypter": {
+		"cloudkms.cryptoKeyVersions.useToEncrypt",
+	},
+	"roles/cloudkms.cryptoKeyDecrypter": {
+		"cloudkms.cryptoKeyVersions.useToDecrypt",
+	},
+	"roles/cloudkms.viewer": {
+		"cloudkms.keyRings.get", "cloudkms.keyRings.list", "cloudkms.cryptoKeys.get", "cloudkms.cryptoKeys.list",
+	},
+}
+
+// IamPolicyHolder is embedded in every mocked resource that has an IAM policy, so they all share the
+// same Get/Set/TestIamPermissions semantics.  GetIamPolicy and SetIamPolicy copy the policy out and in, so
+// changing a policy returned from them (or passed into them) won't change what's stored.  The New
+// constructors and the Policy field hold the caller's policy as it is, so a test can seed it directly
+type IamPolicyHolder struct {
+	Policy *cloudresourcemanager.Policy
+}
+
+// GetIamPolicy returns a copy of the held policy.  If there isn't one, an empty policy is returned
+func (h *IamPolicyHolder) GetIamPolicy() *cloudresourcemanager.Policy {
+	if h.Policy == nil {
+		return &cloudresourcemanager.Policy{}
+	}
+	return copyPolicy(h.Policy)
+}
+
+// SetIamPolicy replaces the held policy with a copy of policy, and returns a copy of what was stored
+func (h *IamPolicyHolder) SetIamPolicy(policy *cloudresourcemanager.Policy) *cloudresourcemanager.Policy {
+	if policy == nil {
+		policy = &cloudresourcemanager.Policy{}
+	}
+	h.Policy = copyPolicy(policy)
+	return copyPolicy(h.Policy)
+}
+
+// TestIamPermissions returns the subset of permissions that the held policy grants to a member.
+// isMember is called with each member of a binding, and decides if it refers to the member being tested.
+// Conditional bindings are treated as if their condition holds
+func (h *IamPolicyHolder) TestIamPermissions(permissions []string, isMember func(member string) bool) []string {
+	return h.testIamPermissions(permissions, isMember, nil)
+}
+
+// testIamPermissions is TestIamPermissions, with conditional bindings only granting their permissions if
+// conditionHolds returns true for the condition
+func (h *IamPolicyHolder) testIamPermissions(permissions []string, isMember func(member string) bool, conditionHolds func(condition *cloudresourcemanager.Expr) bool) []string {
+	granted := map[string]bool{}
+	if h.Policy != nil {
+		for _, binding := range h.Policy.Bindings {
+			if binding == nil || (binding.Condition != nil && conditionHolds != nil && !conditionHolds(binding.Condition)) {
+				continue
+			}
+			for _, member := range binding.Members {
+				if isMember(member) {
+					for _, permission := range RolePermissions[binding.Role] {
+						granted[permission] = true
+					}
+					break
+				}
+			}
+		}
+	}
+
+	var result []string
+	for _, permission := range permissions {
+		if granted[permission] {
+			result = append(result, permission)
+		}
+	}
+	return result
+}
+
+// callerMatches returns true if a binding member applies to the service's Caller.  allUsers
+// matches everyone, allAuthenticatedUsers matches any caller that has been set, and group members
+// match if the Caller is in the group, directly or through nested groups
+func (s *MockService) callerMatches(member string) bool {
+	switch member {
+	case "allUsers":
+		return true
+	case "allAuthenticatedUsers":
+		return s.Caller != ""
+	}
+	if s.Caller != "" && strings.HasPrefix(member, "group:") && s.Groups.hasTransitiveMember(memberEmail(member), s.Caller) {
+		return true
+	}
+	return s.Caller != "" && member == s.Caller
+}
+
+// conditionHolds returns a function that evaluates IAM conditions on the holder's resource, with
+// request.time read from the service's Clock.  Conditions that can't be evaluated don't hold
+func (s *MockService) conditionHolds(holder *IamPolicyHolder) func(condition *cloudresourcemanager.Expr) bool {
+	ctx := ConditionContext{RequestTime: s.now()}
+	for _, node := range s.resourceNodes() {
+		if node.Holder == holder {
+			ctx.ResourceName = node.shortName()
+			ctx.ResourceType = node.AssetType
+			ctx.ResourceService = node.service()
+		}
+	}
+	return func(condition *cloudresourcemanager.Expr) bool {
+		ok, err := EvaluateCondition(condition.Expression, ctx)
+		return err == nil && ok
+	}
+}
+
+// getIamPolicy is the shared implementation for all GetIamPolicy calls.  It checks the resource name is
+// in the format given, then uses lookup to find the resource's policy holder
+func getIamPolicy(resource string, format *regexp.Regexp, lookup func(string) *IamPolicyHolder) (*cloudresourcemanager.Policy, error) {
+	holder, err := findPolicyHolder(resource, format, lookup)
+	if err != nil {
+		return nil, err
+	}
+	return holder.GetIamPolicy(), nil
+}
+
+// setIamPolicy is the shared implementation for all SetIamPolicy calls.  The change is published to the
+// service's asset feeds
+func setIamPolicy(s *MockService, resource string, format *regexp.Regexp, lookup func(string) *IamPolicyHolder, request *cloudresourcemanager.SetIamPolicyRequest) (*cloudresourcemanager.Policy, error) {
+	holder, err := findPolicyHolder(resource, format, lookup)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil || request.Policy == nil {
+		return nil, fmt.Errorf("policy is required")
+	}
+	prior := s.assetSnapshot(holder)
+	before := holder.GetIamPolicy()
+	policy := holder.SetIamPolicy(request.Policy)
+	s.auditSetIamPolicy(holder, before, policy)
+	s.publishAssetChange(prior, s.assetSnapshot(holder))
+	return policy, nil
+}
+
+// testIamPermissions is the shared implementation for all TestIamPermissions calls, testing the
+// permissions against the service's Caller
+func testIamPermissions(s *MockService, resource string, format *regexp.Regexp, lookup func(string) *IamPolicyHolder, request *cloudresourcemanager.TestIamPermissionsRequest) (*cloudresourcemanager.TestIamPermissionsResponse, error) {
+	holder, err := findPolicyHolder(resource, format, lookup)
+	if err != nil {
+		return nil, err
+	}
+	var permissions []string
+	if request != nil {
+		permissions = request.Permissions
+	}
+	return &cloudresourcemanager.TestIamPermissionsResponse{
+		Permissions: holder.testIamPermissions(permissions, s.callerMatches, s.conditionHolds(holder)),
+	}, nil
+}
+
+// getAPIIamPolicy runs getIamPolicy, and converts the policy into result, which is the policy type of
+// another google API package (like *pubsub.Policy)
+func getAPIIamPolicy(result interface{}, resource string, format *regexp.Regexp, lookup func(string) *IamPolicyHolder) error {
+	policy, err := getIamPolicy(resource, format, lookup)
+	if err != nil {
+		return err
+	}
+	return convertPolicy(policy, result)
+}
+
+// setAPIIamPolicy converts setiampolicyrequest from another google API package's SetIamPolicyRequest type
+// and runs setIamPolicy with it, converting the policy that was set into result
+func setAPIIamPolicy(s *MockService, result, setiampolicyrequest interface{}, resource string, format *regexp.Regexp, lookup func(string) *IamPolicyHolder) error {
+	request := &cloudresourcemanager.SetIamPolicyRequest{}
+	if err := convertPolicy(setiampolicyrequest, request); err != nil {
+		return err
+	}
+	policy, err := setIamPolicy(s, resource, format, lookup, request)
+	if err != nil {
+		return err
+	}
+	return convertPolicy(policy, result)
+}
+
+// testAPIIamPermissions runs testIamPermissions with the request and response types of another google
+// API package
+func testAPIIamPermissions(s *MockService, result, testiampermissionsrequest interface{}, resource string, format *regexp.Regexp, lookup func(string) *IamPolicyHolder) error {
+	request := &cloudresourcemanager.TestIamPermissionsRequest{}
+	if err := convertPolicy(testiampermissionsrequest, request); err != nil {
+		return err
+	}
+	response, err := testIamPermissions(s, resource, format, lookup, request)
+	if err != nil {
+		return err
+	}
+	return convertPolicy(response, result)
+}
+
+func findPolicyHolder(resource string, format *regexp.Regexp, lookup func(string) *IamPolicyHolder) (*IamPolicyHolder, error) {
+	if !format.MatchString(resource) {
+		return nil, fmt.Errorf("resource format invalid")
+	}
+	holder := lookup(resource)
+	if holder == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, resource)
+	}
+	return holder, nil
+}
+
+// copyPolicy returns a deep copy of a policy, keeping nil slices nil so copies compare equal
+// to the original with reflect.DeepEqual
+func copyPolicy(policy *cloudresourcemanager.Policy) *cloudresourcemanager.Policy {
+	if policy == nil {
+		return nil
+	}
+	p := *policy
+	if policy.Bindings != nil {
+		p.Bindings = make([]*cloudresourcemanager.Binding, len(policy.Bindings))
+		for i, b := range policy.Bindings {
+			p.Bindings[i] = copyBinding(b)
+		}
+	}
+	if policy.AuditConfigs != nil {
+		p.AuditConfigs = make([]*cloudresourcemanager.AuditConfig, len(policy.AuditConfigs))
+		for i, a := range policy.AuditConfigs {
+			if a == nil {
+				continue
+			}
+			auditConfig := *a
+			if a.AuditLogConfigs != nil {
+				auditConfig.AuditLogConfigs = make([]*cloudresourcemanager.AuditLogConfig, len(a.AuditLogConfigs))
+				for j, l := range a.AuditLogConfigs {
+					if l == nil {
+						continue
+					}
+					logConfig := *l
+					logConfig.ExemptedMembers = copyStrings(l.ExemptedMembers)
+					auditConfig.AuditLogConfigs[j] = &logConfig
+				}
+			}
+			p.AuditConfigs[i] = &auditConfig
+		}
+	}
+	p.ForceSendFields = copyStrings(policy.ForceSendFields)
+	p.NullFields = copyStrings(policy.NullFields)
+	return &p
+}
+
+func copyBinding(binding *cloudresourcemanager.Binding) *cloudresourcemanager.Binding {
+	if binding == nil {
+		return nil
+	}
+	b := *binding
+	b.Members = copyStrings(binding.Members)
+	if binding.Condition != nil {
+		condition := *binding.Condition
+		b.Condition = &condition
+	}
+	return &b
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(s)), s...)
+}
+
+// convertPolicy copies a policy between the policy types of the different google API packages
+// (cloudresourcemanager.Policy, storage.Policy, pubsub.Policy...).  They all share the same JSON
+// shape, so it round trips the policy through JSON.  The same works for the SetIamPolicyRequest
+// and TestIamPermissions types, which are also identical across the packages
+func convertPolicy(from, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+// resourceFormat returns a regexp matching resource names starting with prefix (like "projects/")
+func resourceFormat(prefix string) *regexp.Regexp {
+	return regexp.MustCompile("^" + regexp.QuoteMeta(strings.TrimSuffix(prefix, "/")) + "/.+")
+}
@@ -0,0 +1,125 @@
+package mockgcp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+func TestIamPolicyHolder_GetIamPolicy(t *testing.T) {
+	t.Run("should return a copy of the policy", func(t *testing.T) {
+		holder := &IamPolicyHolder{Policy: GeneratePolicy(NewBinding("roles/viewer", "user:test@testdomain.co"))}
+
+		got := holder.GetIamPolicy()
+		got.Bindings[0].Members[0] = "user:changed@testdomain.co"
+
+		if holder.Policy.Bindings[0].Members[0] != "user:test@testdomain.co" {
+			t.Errorf("expected held policy to be unchanged but got %v", holder.Policy.Bindings[0].Members)
+		}
+	})
+	t.Run("should return empty policy if none is held", func(t *testing.T) {
+		holder := &IamPolicyHolder{}
+
+		want := &cloudresourcemanager.Policy{}
+		got := holder.GetIamPolicy()
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+}
+
+func TestIamPolicyHolder_SetIamPolicy(t *testing.T) {
+	t.Run("should store a copy of the policy", func(t *testing.T) {
+		holder := &IamPolicyHolder{}
+		policy := GeneratePolicy(NewBinding("roles/viewer", "user:test@testdomain.co"))
+
+		holder.SetIamPolicy(policy)
+		policy.Bindings[0].Role = "roles/owner"
+
+		want := "roles/viewer"
+		got := holder.Policy.Bindings[0].Role
+
+		if got != want {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+}
+
+func TestIamPolicyHolder_TestIamPermissions(t *testing.T) {
+	holder := &IamPolicyHolder{Policy: GeneratePolicy(
+		NewBinding("roles/viewer", "user:viewer@testdomain.co"),
+		NewBinding("roles/iam.serviceAccountUser", "allUsers"),
+	)}
+	permissions := []string{"resourcemanager.projects.get", "resourcemanager.projects.setIamPolicy", "iam.serviceAccounts.actAs"}
+
+	t.Run("should return permissions granted to member", func(t *testing.T) {
+		want := []string{"resourcemanager.projects.get", "iam.serviceAccounts.actAs"}
+		got := holder.TestIamPermissions(permissions, func(member string) bool {
+			return member == "user:viewer@testdomain.co" || member == "allUsers"
+		})
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("should return nothing for member without bindings", func(t *testing.T) {
+		got := holder.TestIamPermissions(permissions, func(member string) bool { return false })
+
+		if len(got) != 0 {
+			t.Errorf("expected no permissions but got %v", got)
+		}
+	})
+}
+
+func TestProject_TestIamPermissions_Do(t *testing.T) {
+	projectID := "projects/TestProject"
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject(projectID, "", GeneratePolicy(NewBinding("roles/owner", "user:owner@testdomain.co")))
+	request := &cloudresourcemanager.TestIamPermissionsRequest{
+		Permissions: []string{"resourcemanager.projects.setIamPolicy"},
+	}
+
+	t.Run("should return permissions the caller has", func(t *testing.T) {
+		service.Caller = "user:owner@testdomain.co"
+
+		want := request.Permissions
+		got, _ := service.Projects.TestIamPermissions(projectID, request).Do()
+
+		if !reflect.DeepEqual(got.Permissions, want) {
+			t.Errorf("got %v want %v", got.Permissions, want)
+		}
+	})
+	t.Run("should return no permissions for another caller", func(t *testing.T) {
+		service.Caller = "user:other@testdomain.co"
+
+		got, _ := service.Projects.TestIamPermissions(projectID, request).Do()
+
+		if len(got.Permissions) != 0 {
+			t.Errorf("expected no permissions but got %v", got.Permissions)
+		}
+	})
+	t.Run("should return err if project doesn't exist", func(t *testing.T) {
+		_, err := service.Projects.TestIamPermissions("projects/Missing", request).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestFolder_GetIamPolicy_Do_ResourceFormat(t *testing.T) {
+	t.Run("should return err if folder name doesn't match format", func(t *testing.T) {
+		folderID := "TestFolder"
+		service, _ := NewService(context.TODO())
+		service.Folders.NewFolder(folderID, folderID, GeneratePolicy())
+
+		_, err := service.Folders.GetIamPolicy(folderID, nil).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
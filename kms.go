@@ -0,0 +1,632 @@
+package mockgcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+)
+
+var (
+	locationNameFormat  = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+$`)
+	keyRingNameFormat   = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[-a-zA-Z0-9_]{1,63}$`)
+	cryptoKeyNameFormat = regexp.MustCompile(`^(projects/[^/]+/locations/[^/]+/keyRings/[^/]+)/cryptoKeys/[-a-zA-Z0-9_]{1,63}$`)
+)
+
+// KeyRing is a mock of a google Cloud KMS KeyRing, which belongs to a Project location and holds its crypto keys.
+// Like the real API, key rings can't be deleted
+type KeyRing struct {
+	IamPolicyHolder
+	Name       string
+	ProjectID  string
+	Location   string
+	CreateTime time.Time
+	CryptoKeys []*CryptoKey
+
+	service *MockService
+}
+
+// CryptoKey is a mock of a google Cloud KMS CryptoKey inside a KeyRing
+type CryptoKey struct {
+	IamPolicyHolder
+	Name       string
+	Purpose    string
+	Labels     map[string]string
+	CreateTime time.Time
+}
+
+// toAPI converts the mock key ring to the type returned by the Cloud KMS API
+func (k *KeyRing) toAPI() *cloudkms.KeyRing {
+	return &cloudkms.KeyRing{Name: k.Name, CreateTime: k.CreateTime.Format(time.RFC3339Nano)}
+}
+
+// toAPI converts the mock crypto key to the type returned by the Cloud KMS API
+func (k *CryptoKey) toAPI() *cloudkms.CryptoKey {
+	return &cloudkms.CryptoKey{
+		Name:       k.Name,
+		Purpose:    k.Purpose,
+		Labels:     copyLabels(k.Labels),
+		CreateTime: k.CreateTime.Format(time.RFC3339Nano),
+	}
+}
+
+// KeyRingsService is a mock of google Cloud KMS's projects.locations.keyRings Service
+type KeyRingsService struct {
+	Service    *MockService
+	CryptoKeys *CryptoKeysService
+}
+
+// NewKeyRingsService will return a new KeyRings Service
+func NewKeyRingsService(s *MockService) *KeyRingsService {
+	rs := &KeyRingsService{Service: s}
+	rs.CryptoKeys = &CryptoKeysService{Service: s}
+	return rs
+}
+
+// NewKeyRing creates a new key ring with the specified ID and policy in the project location and returns a
+// pointer to the created key ring.  If policy isn't specified it will generate a blank one.  It returns nil
+// if the project doesn't exist in the Projects Service
+func (r *KeyRingsService) NewKeyRing(projectID, location, keyRingID string, policy *cloudresourcemanager.Policy) *KeyRing {
+	project := r.Service.Projects.get(projectResourceName(projectID))
+	if project == nil {
+		return nil
+	}
+	if policy == nil {
+		policy = &cloudresourcemanager.Policy{}
+	}
+	keyRing := &KeyRing{
+		IamPolicyHolder: IamPolicyHolder{Policy: policy},
+		Name:            fmt.Sprintf("%v/locations/%v/keyRings/%v", project.ProjectID, location, keyRingID),
+		ProjectID:       project.ProjectID,
+		Location:        location,
+		CreateTime:      r.Service.now().UTC(),
+		service:         r.Service,
+	}
+	project.KeyRings = append(project.KeyRings, keyRing)
+	r.Service.publishNewAsset(&keyRing.IamPolicyHolder)
+	return keyRing
+}
+
+// NewCryptoKey creates a new crypto key with the specified ID, purpose and policy in the key ring and returns
+// a pointer to the created key.  If purpose isn't specified it defaults to ENCRYPT_DECRYPT
+func (k *KeyRing) NewCryptoKey(cryptoKeyID, purpose string, policy *cloudresourcemanager.Policy) *CryptoKey {
+	if policy == nil {
+		policy = &cloudresourcemanager.Policy{}
+	}
+	if purpose == "" {
+		purpose = "ENCRYPT_DECRYPT"
+	}
+	cryptoKey := &CryptoKey{
+		IamPolicyHolder: IamPolicyHolder{Policy: policy},
+		Name:            fmt.Sprintf("%v/cryptoKeys/%v", k.Name, cryptoKeyID),
+		Purpose:         purpose,
+		CreateTime:      k.service.now().UTC(),
+	}
+	k.CryptoKeys = append(k.CryptoKeys, cryptoKey)
+	if k.service != nil {
+		k.service.publishNewAsset(&cryptoKey.IamPolicyHolder)
+	}
+	return cryptoKey
+}
+
+// find returns the key ring with the given resource name, or nil if there isn't one
+func (r *KeyRingsService) find(name string) *KeyRing {
+	project := r.Service.Projects.get(projectOf(name))
+	if project == nil {
+		return nil
+	}
+	for _, keyRing := range project.KeyRings {
+		if keyRing.Name == name {
+			return keyRing
+		}
+	}
+	return nil
+}
+
+// policyHolder returns the policy holder for the key ring, or nil if there isn't one
+func (r *KeyRingsService) policyHolder(resource string) *IamPolicyHolder {
+	keyRing := r.find(resource)
+	if keyRing == nil {
+		return nil
+	}
+	return &keyRing.IamPolicyHolder
+}
+
+// Create will take a location resource name and a key ring and returns a Create Call, so we can run a Do()
+// method on it.  The key ring's ID is set on the call with KeyRingId()
+func (r *KeyRingsService) Create(parent string, keyring *cloudkms.KeyRing) *KeyRingsCreateCall {
+	return &KeyRingsCreateCall{Service: r.Service, Parent: parent, Keyring: keyring}
+}
+
+// KeyRingsCreateCall is a structure that is returned by KeyRings.Create.  Then we call Do() on it to create
+// the key ring
+type KeyRingsCreateCall struct {
+	Service   *MockService
+	Parent    string
+	Keyring   *cloudkms.KeyRing
+	keyRingID string
+	ctx       context.Context
+}
+
+// KeyRingId sets the ID of the key ring to create
+func (c *KeyRingsCreateCall) KeyRingId(keyRingId string) *KeyRingsCreateCall {
+	c.keyRingID = keyRingId
+	return c
+}
+
+// Do will be called on KeyRingsCreateCall to create the key ring and return it
+func (c *KeyRingsCreateCall) Do(opts ...googleapi.CallOption) (result *cloudkms.KeyRing, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.Create", c.Parent, c.Keyring)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if !locationNameFormat.MatchString(c.Parent) {
+		return nil, fmt.Errorf("resource format invalid")
+	}
+	if c.Service.Projects.get(projectOf(c.Parent)) == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, projectOf(c.Parent))
+	}
+	name := fmt.Sprintf("%v/keyRings/%v", c.Parent, c.keyRingID)
+	if !keyRingNameFormat.MatchString(name) {
+		return nil, fmt.Errorf("invalid key ring id: %v", c.keyRingID)
+	}
+	if c.Service.KeyRings.find(name) != nil {
+		return nil, fmt.Errorf("%v: %v", resourceAlreadyExistsError, name)
+	}
+	location := c.Parent[strings.LastIndex(c.Parent, "/")+1:]
+	keyRing := c.Service.KeyRings.NewKeyRing(projectOf(c.Parent), location, c.keyRingID, nil)
+	c.Service.AuditLogs.recordMutation("cloudkms.googleapis.com", "google.cloud.kms.v1.KeyManagementService.CreateKeyRing", keyRing.Name)
+	return keyRing.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *KeyRingsCreateCall) Context(ctx context.Context) *KeyRingsCreateCall {
+	c.ctx = ctx
+	return c
+}
+
+// Get will take a key ring resource name and returns a Get Call, so we can run a Do() method on it.
+func (r *KeyRingsService) Get(name string) *KeyRingsGetCall {
+	return &KeyRingsGetCall{Service: r.Service, Name: name}
+}
+
+// KeyRingsGetCall is a structure that is returned by KeyRings.Get.  Then we call Do() on it to return the key ring
+type KeyRingsGetCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on KeyRingsGetCall and return the key ring found
+func (c *KeyRingsGetCall) Do(opts ...googleapi.CallOption) (result *cloudkms.KeyRing, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.Get", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	keyRing := c.Service.KeyRings.find(c.Name)
+	if keyRing == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	return keyRing.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *KeyRingsGetCall) Context(ctx context.Context) *KeyRingsGetCall {
+	c.ctx = ctx
+	return c
+}
+
+// List will take a location resource name and returns a List Call, so we can run a Do() method on it.
+func (r *KeyRingsService) List(parent string) *KeyRingsListCall {
+	return &KeyRingsListCall{Service: r.Service, Parent: parent}
+}
+
+// KeyRingsListCall is a structure that is returned by KeyRings.List.  Then we call Do() on it to return the
+// key rings in the location
+type KeyRingsListCall struct {
+	Service *MockService
+	Parent  string
+	ctx     context.Context
+}
+
+// Do will be called on KeyRingsListCall and return the key rings in the location
+func (c *KeyRingsListCall) Do(opts ...googleapi.CallOption) (result *cloudkms.ListKeyRingsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.List", c.Parent, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	project := c.Service.Projects.get(projectOf(c.Parent))
+	if project == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Parent)
+	}
+	response := &cloudkms.ListKeyRingsResponse{}
+	for _, keyRing := range project.KeyRings {
+		if strings.HasPrefix(keyRing.Name, c.Parent+"/keyRings/") {
+			response.KeyRings = append(response.KeyRings, keyRing.toAPI())
+		}
+	}
+	response.TotalSize = int64(len(response.KeyRings))
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *KeyRingsListCall) Context(ctx context.Context) *KeyRingsListCall {
+	c.ctx = ctx
+	return c
+}
+
+// GetIamPolicy will take a key ring resource name and returns a GetIamPolicy Call, so we can run a Do() method on it.
+func (r *KeyRingsService) GetIamPolicy(resource string) *KeyRingsGetIamPolicyCall {
+	return &KeyRingsGetIamPolicyCall{Service: r.Service, Resource: resource}
+}
+
+// KeyRingsGetIamPolicyCall is a structure that is returned by KeyRings.GetIamPolicy.  Then we call Do() on it
+// to actually return the policy
+type KeyRingsGetIamPolicyCall struct {
+	Service  *MockService
+	Resource string
+	ctx      context.Context
+}
+
+// Do will be called on KeyRingsGetIamPolicyCall and return the policy found
+func (c *KeyRingsGetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudkms.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.GetIamPolicy", c.Resource, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	policy := &cloudkms.Policy{}
+	if err := getAPIIamPolicy(policy, c.Resource, keyRingNameFormat, c.Service.KeyRings.policyHolder); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Context sets the context used by Do
+func (c *KeyRingsGetIamPolicyCall) Context(ctx context.Context) *KeyRingsGetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// SetIamPolicy will take a key ring resource name and a setiampolicyrequest and returns a SetIamPolicy Call,
+// so we can run a Do() method on it.
+func (r *KeyRingsService) SetIamPolicy(resource string, setiampolicyrequest *cloudkms.SetIamPolicyRequest) *KeyRingsSetIamPolicyCall {
+	return &KeyRingsSetIamPolicyCall{Service: r.Service, Resource: resource, Setiampolicyrequest: setiampolicyrequest}
+}
+
+// KeyRingsSetIamPolicyCall is a structure that is returned by KeyRings.SetIamPolicy.  Then we call Do() on it
+// to Set the KeyRing Policy
+type KeyRingsSetIamPolicyCall struct {
+	Service             *MockService
+	Resource            string
+	Setiampolicyrequest *cloudkms.SetIamPolicyRequest
+	ctx                 context.Context
+}
+
+// Do will be called on KeyRingsSetIamPolicyCall to process the policy change and returns the policy it sets
+func (c *KeyRingsSetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudkms.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.SetIamPolicy", c.Resource, c.Setiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	policy := &cloudkms.Policy{}
+	if err := setAPIIamPolicy(c.Service, policy, c.Setiampolicyrequest, c.Resource, keyRingNameFormat, c.Service.KeyRings.policyHolder); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Context sets the context used by Do
+func (c *KeyRingsSetIamPolicyCall) Context(ctx context.Context) *KeyRingsSetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// TestIamPermissions will take a key ring resource name and a testiampermissionsrequest and returns a
+// TestIamPermissions Call, so we can run a Do() method on it.
+func (r *KeyRingsService) TestIamPermissions(resource string, testiampermissionsrequest *cloudkms.TestIamPermissionsRequest) *KeyRingsTestIamPermissionsCall {
+	return &KeyRingsTestIamPermissionsCall{Service: r.Service, Resource: resource, Testiampermissionsrequest: testiampermissionsrequest}
+}
+
+// KeyRingsTestIamPermissionsCall is a structure that is returned by KeyRings.TestIamPermissions.  Then we call
+// Do() on it to find which of the permissions the Caller has
+type KeyRingsTestIamPermissionsCall struct {
+	Service                   *MockService
+	Resource                  string
+	Testiampermissionsrequest *cloudkms.TestIamPermissionsRequest
+	ctx                       context.Context
+}
+
+// Do will be called on KeyRingsTestIamPermissionsCall and return the permissions the Caller has on the key ring
+func (c *KeyRingsTestIamPermissionsCall) Do(opts ...googleapi.CallOption) (result *cloudkms.TestIamPermissionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.TestIamPermissions", c.Resource, c.Testiampermissionsrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	response := &cloudkms.TestIamPermissionsResponse{}
+	if err := testAPIIamPermissions(c.Service, response, c.Testiampermissionsrequest, c.Resource, keyRingNameFormat, c.Service.KeyRings.policyHolder); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *KeyRingsTestIamPermissionsCall) Context(ctx context.Context) *KeyRingsTestIamPermissionsCall {
+	c.ctx = ctx
+	return c
+}
+
+// CryptoKeysService is a mock of google Cloud KMS's projects.locations.keyRings.cryptoKeys Service
+type CryptoKeysService struct {
+	Service *MockService
+}
+
+// find returns the crypto key with the given resource name, or nil if there isn't one
+func (r *CryptoKeysService) find(name string) *CryptoKey {
+	match := cryptoKeyNameFormat.FindStringSubmatch(name)
+	if match == nil {
+		return nil
+	}
+	keyRing := r.Service.KeyRings.find(match[1])
+	if keyRing == nil {
+		return nil
+	}
+	for _, cryptoKey := range keyRing.CryptoKeys {
+		if cryptoKey.Name == name {
+			return cryptoKey
+		}
+	}
+	return nil
+}
+
+// policyHolder returns the policy holder for the crypto key, or nil if there isn't one
+func (r *CryptoKeysService) policyHolder(resource string) *IamPolicyHolder {
+	cryptoKey := r.find(resource)
+	if cryptoKey == nil {
+		return nil
+	}
+	return &cryptoKey.IamPolicyHolder
+}
+
+// Create will take a key ring resource name and a crypto key and returns a Create Call, so we can run a Do()
+// method on it.  The crypto key's ID is set on the call with CryptoKeyId()
+func (r *CryptoKeysService) Create(parent string, cryptokey *cloudkms.CryptoKey) *CryptoKeysCreateCall {
+	return &CryptoKeysCreateCall{Service: r.Service, Parent: parent, Cryptokey: cryptokey}
+}
+
+// CryptoKeysCreateCall is a structure that is returned by KeyRings.CryptoKeys.Create.  Then we call Do() on it
+// to create the crypto key
+type CryptoKeysCreateCall struct {
+	Service     *MockService
+	Parent      string
+	Cryptokey   *cloudkms.CryptoKey
+	cryptoKeyID string
+	ctx         context.Context
+}
+
+// CryptoKeyId sets the ID of the crypto key to create
+func (c *CryptoKeysCreateCall) CryptoKeyId(cryptoKeyId string) *CryptoKeysCreateCall {
+	c.cryptoKeyID = cryptoKeyId
+	return c
+}
+
+// Do will be called on CryptoKeysCreateCall to create the crypto key and return it
+func (c *CryptoKeysCreateCall) Do(opts ...googleapi.CallOption) (result *cloudkms.CryptoKey, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.CryptoKeys.Create", c.Parent, c.Cryptokey)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	keyRing := c.Service.KeyRings.find(c.Parent)
+	if keyRing == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Parent)
+	}
+	name := fmt.Sprintf("%v/cryptoKeys/%v", c.Parent, c.cryptoKeyID)
+	if !cryptoKeyNameFormat.MatchString(name) {
+		return nil, fmt.Errorf("invalid crypto key id: %v", c.cryptoKeyID)
+	}
+	if c.Service.KeyRings.CryptoKeys.find(name) != nil {
+		return nil, fmt.Errorf("%v: %v", resourceAlreadyExistsError, name)
+	}
+	purpose := ""
+	if c.Cryptokey != nil {
+		purpose = c.Cryptokey.Purpose
+	}
+	cryptoKey := keyRing.NewCryptoKey(c.cryptoKeyID, purpose, nil)
+	if c.Cryptokey != nil {
+		cryptoKey.Labels = copyLabels(c.Cryptokey.Labels)
+	}
+	c.Service.AuditLogs.recordMutation("cloudkms.googleapis.com", "google.cloud.kms.v1.KeyManagementService.CreateCryptoKey", cryptoKey.Name)
+	return cryptoKey.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *CryptoKeysCreateCall) Context(ctx context.Context) *CryptoKeysCreateCall {
+	c.ctx = ctx
+	return c
+}
+
+// Get will take a crypto key resource name and returns a Get Call, so we can run a Do() method on it.
+func (r *CryptoKeysService) Get(name string) *CryptoKeysGetCall {
+	return &CryptoKeysGetCall{Service: r.Service, Name: name}
+}
+
+// CryptoKeysGetCall is a structure that is returned by KeyRings.CryptoKeys.Get.  Then we call Do() on it to
+// return the crypto key
+type CryptoKeysGetCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on CryptoKeysGetCall and return the crypto key found
+func (c *CryptoKeysGetCall) Do(opts ...googleapi.CallOption) (result *cloudkms.CryptoKey, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.CryptoKeys.Get", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	cryptoKey := c.Service.KeyRings.CryptoKeys.find(c.Name)
+	if cryptoKey == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	return cryptoKey.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *CryptoKeysGetCall) Context(ctx context.Context) *CryptoKeysGetCall {
+	c.ctx = ctx
+	return c
+}
+
+// List will take a key ring resource name and returns a List Call, so we can run a Do() method on it.
+func (r *CryptoKeysService) List(parent string) *CryptoKeysListCall {
+	return &CryptoKeysListCall{Service: r.Service, Parent: parent}
+}
+
+// CryptoKeysListCall is a structure that is returned by KeyRings.CryptoKeys.List.  Then we call Do() on it to
+// return the crypto keys in the key ring
+type CryptoKeysListCall struct {
+	Service *MockService
+	Parent  string
+	ctx     context.Context
+}
+
+// Do will be called on CryptoKeysListCall and return the crypto keys in the key ring
+func (c *CryptoKeysListCall) Do(opts ...googleapi.CallOption) (result *cloudkms.ListCryptoKeysResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.CryptoKeys.List", c.Parent, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	keyRing := c.Service.KeyRings.find(c.Parent)
+	if keyRing == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Parent)
+	}
+	response := &cloudkms.ListCryptoKeysResponse{TotalSize: int64(len(keyRing.CryptoKeys))}
+	for _, cryptoKey := range keyRing.CryptoKeys {
+		response.CryptoKeys = append(response.CryptoKeys, cryptoKey.toAPI())
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *CryptoKeysListCall) Context(ctx context.Context) *CryptoKeysListCall {
+	c.ctx = ctx
+	return c
+}
+
+// GetIamPolicy will take a crypto key resource name and returns a GetIamPolicy Call, so we can run a Do()
+// method on it.
+func (r *CryptoKeysService) GetIamPolicy(resource string) *CryptoKeysGetIamPolicyCall {
+	return &CryptoKeysGetIamPolicyCall{Service: r.Service, Resource: resource}
+}
+
+// CryptoKeysGetIamPolicyCall is a structure that is returned by KeyRings.CryptoKeys.GetIamPolicy.  Then we
+// call Do() on it to actually return the policy
+type CryptoKeysGetIamPolicyCall struct {
+	Service  *MockService
+	Resource string
+	ctx      context.Context
+}
+
+// Do will be called on CryptoKeysGetIamPolicyCall and return the policy found
+func (c *CryptoKeysGetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudkms.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.CryptoKeys.GetIamPolicy", c.Resource, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	policy := &cloudkms.Policy{}
+	if err := getAPIIamPolicy(policy, c.Resource, cryptoKeyNameFormat, c.Service.KeyRings.CryptoKeys.policyHolder); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Context sets the context used by Do
+func (c *CryptoKeysGetIamPolicyCall) Context(ctx context.Context) *CryptoKeysGetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// SetIamPolicy will take a crypto key resource name and a setiampolicyrequest and returns a SetIamPolicy Call,
+// so we can run a Do() method on it.
+func (r *CryptoKeysService) SetIamPolicy(resource string, setiampolicyrequest *cloudkms.SetIamPolicyRequest) *CryptoKeysSetIamPolicyCall {
+	return &CryptoKeysSetIamPolicyCall{Service: r.Service, Resource: resource, Setiampolicyrequest: setiampolicyrequest}
+}
+
+// CryptoKeysSetIamPolicyCall is a structure that is returned by KeyRings.CryptoKeys.SetIamPolicy.  Then we
+// call Do() on it to Set the CryptoKey Policy
+type CryptoKeysSetIamPolicyCall struct {
+	Service             *MockService
+	Resource            string
+	Setiampolicyrequest *cloudkms.SetIamPolicyRequest
+	ctx                 context.Context
+}
+
+// Do will be called on CryptoKeysSetIamPolicyCall to process the policy change and returns the policy it sets
+func (c *CryptoKeysSetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudkms.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.CryptoKeys.SetIamPolicy", c.Resource, c.Setiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	policy := &cloudkms.Policy{}
+	if err := setAPIIamPolicy(c.Service, policy, c.Setiampolicyrequest, c.Resource, cryptoKeyNameFormat, c.Service.KeyRings.CryptoKeys.policyHolder); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Context sets the context used by Do
+func (c *CryptoKeysSetIamPolicyCall) Context(ctx context.Context) *CryptoKeysSetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// TestIamPermissions will take a crypto key resource name and a testiampermissionsrequest and returns a
+// TestIamPermissions Call, so we can run a Do() method on it.
+func (r *CryptoKeysService) TestIamPermissions(resource string, testiampermissionsrequest *cloudkms.TestIamPermissionsRequest) *CryptoKeysTestIamPermissionsCall {
+	return &CryptoKeysTestIamPermissionsCall{Service: r.Service, Resource: resource, Testiampermissionsrequest: testiampermissionsrequest}
+}
+
+// CryptoKeysTestIamPermissionsCall is a structure that is returned by KeyRings.CryptoKeys.TestIamPermissions.
+// Then we call Do() on it to find which of the permissions the Caller has
+type CryptoKeysTestIamPermissionsCall struct {
+	Service                   *MockService
+	Resource                  string
+	Testiampermissionsrequest *cloudkms.TestIamPermissionsRequest
+	ctx                       context.Context
+}
+
+// Do will be called on CryptoKeysTestIamPermissionsCall and return the permissions the Caller has on the
+// crypto key
+func (c *CryptoKeysTestIamPermissionsCall) Do(opts ...googleapi.CallOption) (result *cloudkms.TestIamPermissionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "KeyRings.CryptoKeys.TestIamPermissions", c.Resource, c.Testiampermissionsrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	response := &cloudkms.TestIamPermissionsResponse{}
+	if err := testAPIIamPermissions(c.Service, response, c.Testiampermissionsrequest, c.Resource, cryptoKeyNameFormat, c.Service.KeyRings.CryptoKeys.policyHolder); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *CryptoKeysTestIamPermissionsCall) Context(ctx context.Context) *CryptoKeysTestIamPermissionsCall {
+	c.ctx = ctx
+	return c
+}
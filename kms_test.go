@@ -0,0 +1,83 @@
+package mockgcp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	cloudkms "google.golang.org/api/cloudkms/v1"
+)
+
+func TestKeyRings_Create_Do(t *testing.T) {
+	t.Run("should create key ring and crypto key", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+
+		keyRing, err := service.KeyRings.Create("projects/test-project/locations/global", &cloudkms.KeyRing{}).KeyRingId("test-ring").Do()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		_, err = service.KeyRings.CryptoKeys.Create(keyRing.Name, &cloudkms.CryptoKey{}).CryptoKeyId("test-key").Do()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		response, _ := service.KeyRings.CryptoKeys.List(keyRing.Name).Do()
+		want := "projects/test-project/locations/global/keyRings/test-ring/cryptoKeys/test-key"
+		got := response.CryptoKeys[0].Name
+
+		if got != want {
+			t.Errorf("got %v want %v", got, want)
+		}
+		if response.CryptoKeys[0].Purpose != "ENCRYPT_DECRYPT" {
+			t.Errorf("got %v want %v", response.CryptoKeys[0].Purpose, "ENCRYPT_DECRYPT")
+		}
+	})
+	t.Run("should return err if project doesn't exist", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+
+		_, err := service.KeyRings.Create("projects/test-project/locations/global", &cloudkms.KeyRing{}).KeyRingId("test-ring").Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestCryptoKeys_IamPolicy_Do(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject("projects/test-project", "", nil)
+	keyRing := service.KeyRings.NewKeyRing("test-project", "global", "test-ring", nil)
+	cryptoKey := keyRing.NewCryptoKey("test-key", "", nil)
+	policy := &cloudkms.Policy{Bindings: []*cloudkms.Binding{
+		{Role: "roles/cloudkms.cryptoKeyEncrypter", Members: []string{"user:test@testdomain.co"}},
+	}}
+
+	t.Run("should set and get crypto key policy", func(t *testing.T) {
+		service.KeyRings.CryptoKeys.SetIamPolicy(cryptoKey.Name, &cloudkms.SetIamPolicyRequest{Policy: policy}).Do()
+
+		got, _ := service.KeyRings.CryptoKeys.GetIamPolicy(cryptoKey.Name).Do()
+
+		if !reflect.DeepEqual(got.Bindings, policy.Bindings) {
+			t.Errorf("got %v want %v", got.Bindings, policy.Bindings)
+		}
+	})
+	t.Run("should test permissions for caller", func(t *testing.T) {
+		service.Caller = "user:test@testdomain.co"
+		request := &cloudkms.TestIamPermissionsRequest{Permissions: []string{"cloudkms.cryptoKeyVersions.useToEncrypt", "cloudkms.cryptoKeyVersions.useToDecrypt"}}
+
+		want := []string{"cloudkms.cryptoKeyVersions.useToEncrypt"}
+		got, _ := service.KeyRings.CryptoKeys.TestIamPermissions(cryptoKey.Name, request).Do()
+
+		if !reflect.DeepEqual(got.Permissions, want) {
+			t.Errorf("got %v want %v", got.Permissions, want)
+		}
+	})
+	t.Run("should not share policy with key ring", func(t *testing.T) {
+		got, _ := service.KeyRings.GetIamPolicy(keyRing.Name).Do()
+
+		if len(got.Bindings) != 0 {
+			t.Errorf("expected no bindings but got %v", got.Bindings)
+		}
+	})
+}
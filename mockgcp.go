@@ -6,7 +6,7 @@ import (
 	"math/rand"
 	"net/http"
 	"reflect"
-	"regexp"
+	"strings"
 	"time"
 
 	"google.golang.org/api/cloudresourcemanager/v3"
@@ -15,7 +15,14 @@ import (
 )
 
 const (
-	resourceNotFoundError = "resource not found"
+	resourceNotFoundError      = "resource not found"
+	resourceAlreadyExistsError = "resource already exists"
+)
+
+var (
+	organizationFormat = resourceFormat("organizations/")
+	projectFormat      = resourceFormat("projects/")
+	folderFormat       = resourceFormat("folders/")
 )
 
 // GCPClient is the final wrapper we make with locally assigned methods, so we can match it on
@@ -26,6 +33,8 @@ const (
 // the library.
 type GCPClient struct {
 	Service *MockService
+
+	expectations *Expectations
 }
 
 // NewClient returns the mock GCPClient client above
@@ -44,59 +53,129 @@ type PolicyCallItf interface {
 // ProjectSetIamPolicy is a wrapper for the Projects.SetIamPolicy method so we can create and interface to match
 // our mock client to the GCP client
 func (client *GCPClient) ProjectSetIamPolicy(resource string, setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest) PolicyCallItf {
+	if client.expectations != nil {
+		return client.expectations.call("ProjectSetIamPolicy", resource, setiampolicyrequest)
+	}
 	return client.Service.Projects.SetIamPolicy(resource, setiampolicyrequest)
 }
 
-// ProjectsSearch Searches for folders by Name to get the ID
+// crmClient returns the client the wrappers served by the mock's Handler use.  In expectation mode the
+// calls made through it are answered by the expectations
+func (client *GCPClient) crmClient(method string, args ...interface{}) *cloudresourcemanager.Service {
+	if client.expectations != nil {
+		return client.expectations.client(method, args...)
+	}
+	return client.Service.CRMClient()
+}
+
+// ProjectsSearch Searches for projects by a query, such as displayName:Name to get the ID.  It's served by
+// the mock's Handler
 func (client *GCPClient) ProjectsSearch() *cloudresourcemanager.ProjectsSearchCall {
-	return &cloudresourcemanager.ProjectsSearchCall{}
+	return client.crmClient("ProjectsSearch").Projects.Search()
+}
+
+// ProjectsGet is a wrapper for the Projects.Get method, served by the mock's Handler
+func (client *GCPClient) ProjectsGet(name string) *cloudresourcemanager.ProjectsGetCall {
+	return client.crmClient("ProjectsGet", name).Projects.Get(name)
+}
+
+// ProjectsDelete is a wrapper for the Projects.Delete method, served by the mock's Handler
+func (client *GCPClient) ProjectsDelete(name string) *cloudresourcemanager.ProjectsDeleteCall {
+	return client.crmClient("ProjectsDelete", name).Projects.Delete(name)
+}
+
+// ProjectsUndelete is a wrapper for the Projects.Undelete method, served by the mock's Handler
+func (client *GCPClient) ProjectsUndelete(name string, undeleteprojectrequest *cloudresourcemanager.UndeleteProjectRequest) *cloudresourcemanager.ProjectsUndeleteCall {
+	return client.crmClient("ProjectsUndelete", name, undeleteprojectrequest).Projects.Undelete(name, undeleteprojectrequest)
 }
 
 
 // ProjectGetIamPolicy is a wrapper for the Projects.GetIamPolicy method so we can create and interface to match
 // our mock client to the GCP client
 func (client *GCPClient) ProjectGetIamPolicy(resource string, getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest) PolicyCallItf {
+	if client.expectations != nil {
+		return client.expectations.call("ProjectGetIamPolicy", resource, getiampolicyrequest)
+	}
 	return client.Service.Projects.GetIamPolicy(resource, getiampolicyrequest)
 }
 
-// FoldersSearch Searches for folders by Name to get the ID
+// FoldersSearch Searches for folders by a query, such as displayName:Name to get the ID.  It's served by
+// the mock's Handler
 func (client *GCPClient) FoldersSearch() *cloudresourcemanager.FoldersSearchCall {
-	return &cloudresourcemanager.FoldersSearchCall{}
+	return client.crmClient("FoldersSearch").Folders.Search()
 }
 
 // FolderSetIamPolicy is a wrapper for the Folders.SetIamPolicy method so we can create and interface to match
 // our mock client to the GCP client
 func (client *GCPClient) FolderSetIamPolicy(resource string, setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest) PolicyCallItf {
+	if client.expectations != nil {
+		return client.expectations.call("FolderSetIamPolicy", resource, setiampolicyrequest)
+	}
 	return client.Service.Folders.SetIamPolicy(resource, setiampolicyrequest)
 }
 
 // FolderGetIamPolicy is a wrapper for the Folders.SetIamPolicy method so we can create and interface to match
 // our mock client to the GCP client
 func (client *GCPClient) FolderGetIamPolicy(resource string, getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest) PolicyCallItf {
+	if client.expectations != nil {
+		return client.expectations.call("FolderGetIamPolicy", resource, getiampolicyrequest)
+	}
 	return client.Service.Folders.GetIamPolicy(resource, getiampolicyrequest)
 }
 
-// OrganizationsSearch Searches for folders by Name to get the ID
+// OrganizationsSearch Searches for organizations by a query, such as domain:Domain to get the ID.  It's
+// served by the mock's Handler
 func (client *GCPClient) OrganizationsSearch() *cloudresourcemanager.OrganizationsSearchCall {
-	return nil
+	return client.crmClient("OrganizationsSearch").Organizations.Search()
 }
 // OrganizationSetIamPolicy is a wrapper for the Organizations.SetIamPolicy method so we can create and interface to match
 // our mock client to the GCP client
 func (client *GCPClient) OrganizationSetIamPolicy(resource string, setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest) PolicyCallItf {
+	if client.expectations != nil {
+		return client.expectations.call("OrganizationSetIamPolicy", resource, setiampolicyrequest)
+	}
 	return client.Service.Organizations.SetIamPolicy(resource, setiampolicyrequest)
 }
 
 // OrganizationGetIamPolicy is a wrapper for the Organizations.GetIamPolicy method so we can create and interface to match
 // our mock client to the GCP client
 func (client *GCPClient) OrganizationGetIamPolicy(resource string, getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest) PolicyCallItf {
+	if client.expectations != nil {
+		return client.expectations.call("OrganizationGetIamPolicy", resource, getiampolicyrequest)
+	}
 	return client.Service.Organizations.GetIamPolicy(resource, getiampolicyrequest)
 }
 
 // MockService is a mockup of cloud resource manager's service (wrapper for it's client)
 type MockService struct {
-	Projects      *ProjectsService
-	Folders       *FoldersService
-	Organizations *OrganizationsService
+	Projects        *ProjectsService
+	Folders         *FoldersService
+	Organizations   *OrganizationsService
+	ServiceAccounts *ServiceAccountsService
+	Buckets         *BucketsService
+	Topics          *TopicsService
+	Subscriptions   *SubscriptionsService
+	Secrets         *SecretsService
+	KeyRings        *KeyRingsService
+	Groups          *GroupsService
+	Assets          *AssetsService
+	AuditLogs       *AuditLogsService
+	Faults          *FaultsService
+	Quotas          *QuotasService
+	Calls           *CallsService
+
+	feeds assetFeeds
+
+	// operations counts the long running operations the gRPC server has returned, to name them
+	operations uint64
+
+	// Clock is where the service gets the time from.  It's the system clock unless a test swaps in a
+	// FakeClock
+	Clock Clock
+
+	// Caller is the member (such as user:alice@example.com) the mock treats as the authenticated
+	// caller, which is used by the TestIamPermissions calls
+	Caller string
 }
 
 // NewService creates a MockService and returns it with an http client Wrapper
@@ -112,32 +191,56 @@ func NewService(ctx context.Context, opts ...option.ClientOption) (*MockService,
 // New is the client which NewService will call to create a new service.
 // This wil be wrapped with an http wrapper with NewService
 func New(client *http.Client) (*MockService, error) {
-	s := &MockService{}
+	s := &MockService{Clock: systemClock{}}
 	s.Folders = NewFoldersService(s)
 	s.Organizations = NewOrganizationsService(s)
 	s.Projects = NewProjectsService(s)
+	s.ServiceAccounts = NewServiceAccountsService(s)
+	s.Buckets = NewBucketsService(s)
+	s.Topics = NewTopicsService(s)
+	s.Subscriptions = NewSubscriptionsService(s)
+	s.Secrets = NewSecretsService(s)
+	s.KeyRings = NewKeyRingsService(s)
+	s.Groups = NewGroupsService(s)
+	s.Assets = NewAssetsService(s)
+	s.AuditLogs = NewAuditLogsService(s)
+	s.Faults = NewFaultsService(s)
+	s.Quotas = NewQuotasService(s)
+	s.Calls = NewCallsService(s)
 	return s, nil
 }
 
 // Organization is a mock of a google cloud Organization
 type Organization struct {
+	IamPolicyHolder
 	OrganizationID string
 	Domain         string
-	Policy         *cloudresourcemanager.Policy
 }
 
-// Project is a mock of a google cloud Project
+// Project is a mock of a google cloud Project.  Parent is the folder or organization the project sits
+// under (such as folders/123), and is empty if it isn't in the hierarchy.  DeleteTime is set once the
+// project has been deleted
 type Project struct {
-	ProjectID   string
-	DisplayName string
-	Policy      *cloudresourcemanager.Policy
-}
-
-// Folder is a mock of a google cloud Folder
+	IamPolicyHolder
+	ProjectID       string
+	DisplayName     string
+	Parent          string
+	DeleteTime      time.Time
+	ServiceAccounts []*ServiceAccount
+	Buckets         []*Bucket
+	Topics          []*Topic
+	Subscriptions   []*Subscription
+	Secrets         []*Secret
+	KeyRings        []*KeyRing
+}
+
+// Folder is a mock of a google cloud Folder.  Parent is the folder or organization the folder sits
+// under, and is empty if it isn't in the hierarchy
 type Folder struct {
+	IamPolicyHolder
 	FolderID    string
 	DisplayName string
-	Policy      *cloudresourcemanager.Policy
+	Parent      string
 }
 
 // OrganizationsService is a mock of google Cloud's Organization Service
@@ -159,12 +262,13 @@ func (r *OrganizationsService) NewOrganization(orgID, domain string, policy *clo
 		policy = &cloudresourcemanager.Policy{}
 	}
 	organization := &Organization{
-		OrganizationID: orgID,
-		Domain:         domain,
-		Policy:         policy,
+		IamPolicyHolder: IamPolicyHolder{Policy: policy},
+		OrganizationID:  orgID,
+		Domain:          domain,
 	}
 
 	r.OrganizationList = append(r.OrganizationList, organization)
+	r.Service.publishNewAsset(&organization.IamPolicyHolder)
 
 	return organization
 }
@@ -212,29 +316,48 @@ func (r *OrganizationsService) SetIamPolicy(resource string, setiampolicyrequest
 	return c
 }
 
+// TestIamPermissions will take a resource name (organization ID), and a testiampermissionsrequest
+// and returns a TestIamPermissions Call, so we can run a Do() method on it.
+func (r *OrganizationsService) TestIamPermissions(resource string, testiampermissionsrequest *cloudresourcemanager.TestIamPermissionsRequest) *OrganizationsTestIamPermissionsCall {
+	c := &OrganizationsTestIamPermissionsCall{Service: r.Service}
+	c.Resource = resource
+	c.Testiampermissionsrequest = testiampermissionsrequest
+	return c
+}
+
+// policyHolder returns the policy holder for the organization with the given ID, or nil if there isn't one
+func (r *OrganizationsService) policyHolder(resource string) *IamPolicyHolder {
+	for _, organization := range r.OrganizationList {
+		if organization.OrganizationID == resource {
+			return &organization.IamPolicyHolder
+		}
+	}
+	return nil
+}
+
 // OrganizationsGetIamPolicyCall is a structure that is returned by Organizations.GetIamPolicy which contains the Request
 // to get a policy.  Then we call Do() on it to actually return the policy
 type OrganizationsGetIamPolicyCall struct {
 	Service             *MockService
 	Resource            string
 	Getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest
+	ctx                 context.Context
 }
 
 // Do will be called on OrganizationsGetIamPolicyCall and return the policy found
-func (c *OrganizationsGetIamPolicyCall) Do(opts ...googleapi.CallOption) (*cloudresourcemanager.Policy, error) {
-	for _, organization := range c.Service.Organizations.OrganizationList {
-		if organization.OrganizationID == c.Resource {
-			policy := *organization.Policy
-			bindings := make([]*cloudresourcemanager.Binding, 0, len(organization.Policy.Bindings))
-			for _, b := range organization.Policy.Bindings {
-				binding := *b
-				bindings = append(bindings, &binding)
-			}
-			policy.Bindings = bindings
-			return &policy, nil
-		}
+func (c *OrganizationsGetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Organizations.GetIamPolicy", c.Resource, c.Getiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Resource)
+	return getIamPolicy(c.Resource, organizationFormat, c.Service.Organizations.policyHolder)
+}
+
+// Context sets the context used by Do
+func (c *OrganizationsGetIamPolicyCall) Context(ctx context.Context) *OrganizationsGetIamPolicyCall {
+	c.ctx = ctx
+	return c
 }
 
 // OrganizationsSetIamPolicyCall is a structure that is returned by Organizations.SetIamPolicy which contains the Request
@@ -243,27 +366,57 @@ type OrganizationsSetIamPolicyCall struct {
 	Service             *MockService
 	Resource            string
 	Setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest
+	ctx                 context.Context
 }
 
 // Do will be called on OrganizationsGetIamPolicyCall to process the policy change and returns the policy it sets
-func (c *OrganizationsSetIamPolicyCall) Do(opts ...googleapi.CallOption) (*cloudresourcemanager.Policy, error) {
-	match, _ := regexp.MatchString("organizations/.*", c.Resource)
-	if !match {
-		return nil, fmt.Errorf("resource format invalid")
+func (c *OrganizationsSetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Organizations.SetIamPolicy", c.Resource, c.Setiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
 	}
-	for _, organization := range c.Service.Organizations.OrganizationList {
-		if organization.OrganizationID == c.Resource {
-			organization.Policy = c.Setiampolicyrequest.Policy
-			return organization.Policy, nil
-		}
+	return setIamPolicy(c.Service, c.Resource, organizationFormat, c.Service.Organizations.policyHolder, c.Setiampolicyrequest)
+}
+
+// Context sets the context used by Do
+func (c *OrganizationsSetIamPolicyCall) Context(ctx context.Context) *OrganizationsSetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// OrganizationsTestIamPermissionsCall is a structure that is returned by Organizations.TestIamPermissions which contains the
+// Request to test permissions.  Then we call Do() on it to find which of the permissions the Caller has
+type OrganizationsTestIamPermissionsCall struct {
+	Service                   *MockService
+	Resource                  string
+	Testiampermissionsrequest *cloudresourcemanager.TestIamPermissionsRequest
+	ctx                       context.Context
+}
+
+// Do will be called on OrganizationsTestIamPermissionsCall and return the permissions the Caller has on the organization
+func (c *OrganizationsTestIamPermissionsCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.TestIamPermissionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Organizations.TestIamPermissions", c.Resource, c.Testiampermissionsrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Resource)
+	return testIamPermissions(c.Service, c.Resource, organizationFormat, c.Service.Organizations.policyHolder, c.Testiampermissionsrequest)
+}
+
+// Context sets the context used by Do
+func (c *OrganizationsTestIamPermissionsCall) Context(ctx context.Context) *OrganizationsTestIamPermissionsCall {
+	c.ctx = ctx
+	return c
 }
 
-// ProjectsService is a mock of google Cloud's Project Service
+// ProjectsService is a mock of google Cloud's Project Service.  Deleted projects are moved out of
+// ProjectList, and kept for 30 days so they can be undeleted
 type ProjectsService struct {
 	Service     *MockService
 	ProjectList []*Project
+
+	deleted []*Project
 }
 
 // NewProjectsService will return a new Project Service
@@ -319,11 +472,12 @@ func (r *ProjectsService) NewProject(projectID, projectName string, policy *clou
 		policy = &cloudresourcemanager.Policy{}
 	}
 	project := &Project{
-		ProjectID:   projectID,
-		DisplayName: projectName,
-		Policy:      policy,
+		IamPolicyHolder: IamPolicyHolder{Policy: policy},
+		ProjectID:       projectID,
+		DisplayName:     projectName,
 	}
 	r.ProjectList = append(r.ProjectList, project)
+	r.Service.publishNewAsset(&project.IamPolicyHolder)
 	return project
 }
 
@@ -341,6 +495,47 @@ func (r *ProjectsService) GenerateProjects(count int, baseName string) (projects
 	return projects
 }
 
+// get returns the project with the given ID (in the "projects/" form used throughout
+// this package), or nil if it isn't in the Projects Service
+func (r *ProjectsService) get(projectID string) *Project {
+	for _, project := range r.ProjectList {
+		if project.ProjectID == projectID {
+			return project
+		}
+	}
+	return nil
+}
+
+// projectResourceName returns a project ID in the "projects/" form used throughout this package.  The
+// google APIs outside of resource manager take bare project IDs, so this lets us accept either
+func projectResourceName(projectID string) string {
+	if strings.HasPrefix(projectID, "projects/") {
+		return projectID
+	}
+	return "projects/" + projectID
+}
+
+// projectOf returns the "projects/{project}" part of a resource name like projects/{project}/topics/{topic}
+func projectOf(name string) string {
+	parts := strings.SplitN(name, "/", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + "/" + parts[1]
+}
+
+// copyLabels returns a copy of a label map
+func copyLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	result := make(map[string]string, len(labels))
+	for k, v := range labels {
+		result[k] = v
+	}
+	return result
+}
+
 // FindPolicy will Search a Project Service and return the project with that policy.
 // It will only return the first one found, so this should only be used for testing
 // where you need to return the project added, and not a reliable way of determining
@@ -372,29 +567,48 @@ func (r *ProjectsService) SetIamPolicy(resource string, setiampolicyrequest *clo
 	return c
 }
 
+// TestIamPermissions will take a resource name (project ID), and a testiampermissionsrequest
+// and returns a TestIamPermissions Call, so we can run a Do() method on it.
+func (r *ProjectsService) TestIamPermissions(resource string, testiampermissionsrequest *cloudresourcemanager.TestIamPermissionsRequest) *ProjectsTestIamPermissionsCall {
+	c := &ProjectsTestIamPermissionsCall{Service: r.Service}
+	c.Resource = resource
+	c.Testiampermissionsrequest = testiampermissionsrequest
+	return c
+}
+
+// policyHolder returns the policy holder for the project with the given ID, or nil if there isn't one
+func (r *ProjectsService) policyHolder(resource string) *IamPolicyHolder {
+	for _, project := range r.ProjectList {
+		if project.ProjectID == resource {
+			return &project.IamPolicyHolder
+		}
+	}
+	return nil
+}
+
 // ProjectsGetIamPolicyCall is a structure that is returned by Projects.GetIamPolicy which contains the Request
 // to get a policy.  Then we call Do() on it to actually return the policy
 type ProjectsGetIamPolicyCall struct {
 	Service             *MockService
 	Resource            string
 	Getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest
+	ctx                 context.Context
 }
 
 // Do will be called on OrganizationsGetIamPolicyCall and return the policy found
-func (c *ProjectsGetIamPolicyCall) Do(opts ...googleapi.CallOption) (*cloudresourcemanager.Policy, error) {
-	for _, project := range c.Service.Projects.ProjectList {
-		if project.ProjectID == c.Resource {
-			policy := *project.Policy
-			bindings := make([]*cloudresourcemanager.Binding, 0, len(project.Policy.Bindings))
-			for _, b := range project.Policy.Bindings {
-				binding := *b
-				bindings = append(bindings, &binding)
-			}
-			policy.Bindings = bindings
-			return &policy, nil
-		}
+func (c *ProjectsGetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Projects.GetIamPolicy", c.Resource, c.Getiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Resource)
+	return getIamPolicy(c.Resource, projectFormat, c.Service.Projects.policyHolder)
+}
+
+// Context sets the context used by Do
+func (c *ProjectsGetIamPolicyCall) Context(ctx context.Context) *ProjectsGetIamPolicyCall {
+	c.ctx = ctx
+	return c
 }
 
 // ProjectsSetIamPolicyCall is a structure that is returned by Projects.SetIamPolicy which contains the Request
@@ -403,21 +617,201 @@ type ProjectsSetIamPolicyCall struct {
 	Service             *MockService
 	Resource            string
 	Setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest
+	ctx                 context.Context
 }
 
 // Do will be called on ProjectsGetIamPolicyCall to process the policy change and returns the policy it sets
-func (c *ProjectsSetIamPolicyCall) Do(opts ...googleapi.CallOption) (*cloudresourcemanager.Policy, error) {
-	match, _ := regexp.MatchString("projects/.*", c.Resource)
-	if !match {
+func (c *ProjectsSetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Projects.SetIamPolicy", c.Resource, c.Setiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	return setIamPolicy(c.Service, c.Resource, projectFormat, c.Service.Projects.policyHolder, c.Setiampolicyrequest)
+}
+
+// Context sets the context used by Do
+func (c *ProjectsSetIamPolicyCall) Context(ctx context.Context) *ProjectsSetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// ProjectsTestIamPermissionsCall is a structure that is returned by Projects.TestIamPermissions which contains the
+// Request to test permissions.  Then we call Do() on it to find which of the permissions the Caller has
+type ProjectsTestIamPermissionsCall struct {
+	Service                   *MockService
+	Resource                  string
+	Testiampermissionsrequest *cloudresourcemanager.TestIamPermissionsRequest
+	ctx                       context.Context
+}
+
+// Do will be called on ProjectsTestIamPermissionsCall and return the permissions the Caller has on the project
+func (c *ProjectsTestIamPermissionsCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.TestIamPermissionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Projects.TestIamPermissions", c.Resource, c.Testiampermissionsrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	return testIamPermissions(c.Service, c.Resource, projectFormat, c.Service.Projects.policyHolder, c.Testiampermissionsrequest)
+}
+
+// Context sets the context used by Do
+func (c *ProjectsTestIamPermissionsCall) Context(ctx context.Context) *ProjectsTestIamPermissionsCall {
+	c.ctx = ctx
+	return c
+}
+
+// Delete will take a project resource name and returns a Delete Call, so we can run a Do() method on it.
+func (r *ProjectsService) Delete(name string) *ProjectsDeleteCall {
+	return &ProjectsDeleteCall{Service: r.Service, Name: name}
+}
+
+// ProjectsDeleteCall is a structure that is returned by Projects.Delete.  Then we call Do() on it to delete
+// the project
+type ProjectsDeleteCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on ProjectsDeleteCall to remove the project, and everything in it, from the Projects
+// Service.  Like GCP the project can be undeleted until it's purged 30 days later.  It returns a finished
+// Operation
+func (c *ProjectsDeleteCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.Operation, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Projects.Delete", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if !projectFormat.MatchString(c.Name) {
 		return nil, fmt.Errorf("resource format invalid")
 	}
-	for _, project := range c.Service.Projects.ProjectList {
-		if project.ProjectID == c.Resource {
-			project.Policy = c.Setiampolicyrequest.Policy
-			return project.Policy, nil
+	for i, project := range c.Service.Projects.ProjectList {
+		if project.ProjectID == c.Name {
+			prior := c.Service.assetSnapshot(&project.IamPolicyHolder)
+			c.Service.Projects.ProjectList = append(c.Service.Projects.ProjectList[:i], c.Service.Projects.ProjectList[i+1:]...)
+			project.DeleteTime = c.Service.now().UTC()
+			c.Service.Projects.deleted = append(c.Service.Projects.deleted, project)
+			c.Service.AuditLogs.recordMutation("cloudresourcemanager.googleapis.com", "DeleteProject", project.ProjectID)
+			c.Service.publishAssetChange(prior, nil)
+			return &cloudresourcemanager.Operation{Done: true}, nil
+		}
+	}
+	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+}
+
+// Context sets the context used by Do
+func (c *ProjectsDeleteCall) Context(ctx context.Context) *ProjectsDeleteCall {
+	c.ctx = ctx
+	return c
+}
+
+// DeletedProjects returns the projects that have been deleted but not purged yet.  Projects are purged 30
+// days after they're deleted, by the service's Clock
+func (r *ProjectsService) DeletedProjects() []*Project {
+	r.purge()
+	return r.deleted
+}
+
+// purge drops the deleted projects that are past the 30 day undelete window
+func (r *ProjectsService) purge() {
+	now := r.Service.now()
+	kept := []*Project{}
+	for _, project := range r.deleted {
+		if now.Before(project.DeleteTime.Add(purgeAfter)) {
+			kept = append(kept, project)
 		}
 	}
-	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Resource)
+	r.deleted = kept
+}
+
+// Undelete will take a project resource name and an undeleteprojectrequest and returns an Undelete Call,
+// so we can run a Do() method on it.
+func (r *ProjectsService) Undelete(name string, undeleteprojectrequest *cloudresourcemanager.UndeleteProjectRequest) *ProjectsUndeleteCall {
+	return &ProjectsUndeleteCall{Service: r.Service, Name: name, Undeleteprojectrequest: undeleteprojectrequest}
+}
+
+// ProjectsUndeleteCall is a structure that is returned by Projects.Undelete.  Then we call Do() on it to
+// restore the project
+type ProjectsUndeleteCall struct {
+	Service                *MockService
+	Name                   string
+	Undeleteprojectrequest *cloudresourcemanager.UndeleteProjectRequest
+	ctx                    context.Context
+}
+
+// Do will be called on ProjectsUndeleteCall to put a deleted project back in the Projects Service.  It
+// fails once the project has been purged.  It returns a finished Operation
+func (c *ProjectsUndeleteCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.Operation, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Projects.Undelete", c.Name, c.Undeleteprojectrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	for i, project := range c.Service.Projects.DeletedProjects() {
+		if project.ProjectID == c.Name {
+			c.Service.Projects.deleted = append(c.Service.Projects.deleted[:i], c.Service.Projects.deleted[i+1:]...)
+			project.DeleteTime = time.Time{}
+			c.Service.Projects.ProjectList = append(c.Service.Projects.ProjectList, project)
+			c.Service.AuditLogs.recordMutation("cloudresourcemanager.googleapis.com", "UndeleteProject", project.ProjectID)
+			c.Service.publishNewAsset(&project.IamPolicyHolder)
+			return &cloudresourcemanager.Operation{Done: true}, nil
+		}
+	}
+	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+}
+
+// Context sets the context used by Do
+func (c *ProjectsUndeleteCall) Context(ctx context.Context) *ProjectsUndeleteCall {
+	c.ctx = ctx
+	return c
+}
+
+// Move will take a project resource name and a moveprojectrequest and returns a Move Call, so we can run a
+// Do() method on it.
+func (r *ProjectsService) Move(name string, moveprojectrequest *cloudresourcemanager.MoveProjectRequest) *ProjectsMoveCall {
+	return &ProjectsMoveCall{Service: r.Service, Name: name, Moveprojectrequest: moveprojectrequest}
+}
+
+// ProjectsMoveCall is a structure that is returned by Projects.Move.  Then we call Do() on it to move the
+// project to its new parent
+type ProjectsMoveCall struct {
+	Service            *MockService
+	Name               string
+	Moveprojectrequest *cloudresourcemanager.MoveProjectRequest
+	ctx                context.Context
+}
+
+// Do will be called on ProjectsMoveCall to set the project's parent to the destination folder or
+// organization, which has to exist.  It returns a finished Operation
+func (c *ProjectsMoveCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.Operation, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Projects.Move", c.Name, c.Moveprojectrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	project := c.Service.Projects.get(c.Name)
+	if project == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	if c.Moveprojectrequest == nil {
+		return nil, fmt.Errorf("destination parent is required")
+	}
+	destination := c.Moveprojectrequest.DestinationParent
+	if c.Service.Folders.policyHolder(destination) == nil && c.Service.Organizations.policyHolder(destination) == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, destination)
+	}
+	prior := c.Service.assetSnapshot(&project.IamPolicyHolder)
+	project.Parent = destination
+	c.Service.AuditLogs.recordMutation("cloudresourcemanager.googleapis.com", "MoveProject", project.ProjectID)
+	c.Service.publishAssetChange(prior, c.Service.assetSnapshot(&project.IamPolicyHolder))
+	return &cloudresourcemanager.Operation{Done: true}, nil
+}
+
+// Context sets the context used by Do
+func (c *ProjectsMoveCall) Context(ctx context.Context) *ProjectsMoveCall {
+	c.ctx = ctx
+	return c
 }
 
 // FoldersService is a mock of google Cloud's Folder Service
@@ -439,11 +833,12 @@ func (r *FoldersService) NewFolder(folderID string, folderName string, policy *c
 		policy = &cloudresourcemanager.Policy{}
 	}
 	folder := &Folder{
-		FolderID:    folderID,
-		DisplayName: folderName,
-		Policy:      policy,
+		IamPolicyHolder: IamPolicyHolder{Policy: policy},
+		FolderID:        folderID,
+		DisplayName:     folderName,
 	}
 	r.FolderList = append(r.FolderList, folder)
+	r.Service.publishNewAsset(&folder.IamPolicyHolder)
 
 	return folder
 }
@@ -493,30 +888,48 @@ func (r *FoldersService) SetIamPolicy(resource string, setiampolicyrequest *clou
 	return c
 }
 
+// TestIamPermissions will take a resource name (folder ID), and a testiampermissionsrequest
+// and returns a TestIamPermissions Call, so we can run a Do() method on it.
+func (r *FoldersService) TestIamPermissions(resource string, testiampermissionsrequest *cloudresourcemanager.TestIamPermissionsRequest) *FoldersTestIamPermissionsCall {
+	c := &FoldersTestIamPermissionsCall{Service: r.Service}
+	c.Resource = resource
+	c.Testiampermissionsrequest = testiampermissionsrequest
+	return c
+}
+
+// policyHolder returns the policy holder for the folder with the given ID, or nil if there isn't one
+func (r *FoldersService) policyHolder(resource string) *IamPolicyHolder {
+	for _, folder := range r.FolderList {
+		if folder.FolderID == resource {
+			return &folder.IamPolicyHolder
+		}
+	}
+	return nil
+}
+
 // FoldersGetIamPolicyCall is a structure that is returned by Folders.GetIamPolicy which contains the Request
 // to get a policy.  Then we call Do() on it to actually return the policy
 type FoldersGetIamPolicyCall struct {
 	Service             *MockService
 	Resource            string
 	Getiampolicyrequest *cloudresourcemanager.GetIamPolicyRequest
+	ctx                 context.Context
 }
 
 // Do will be called on OrganizationsGetIamPolicyCall and return the policy found
-func (c *FoldersGetIamPolicyCall) Do(opts ...googleapi.CallOption) (*cloudresourcemanager.Policy, error) {
-
-	for _, folder := range c.Service.Folders.FolderList {
-		if folder.FolderID == c.Resource {
-			policy := *folder.Policy
-			bindings := make([]*cloudresourcemanager.Binding, 0, len(folder.Policy.Bindings))
-			for _, b := range folder.Policy.Bindings {
-				binding := *b
-				bindings = append(bindings, &binding)
-			}
-			policy.Bindings = bindings
-			return &policy, nil
-		}
+func (c *FoldersGetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Folders.GetIamPolicy", c.Resource, c.Getiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Resource)
+	return getIamPolicy(c.Resource, folderFormat, c.Service.Folders.policyHolder)
+}
+
+// Context sets the context used by Do
+func (c *FoldersGetIamPolicyCall) Context(ctx context.Context) *FoldersGetIamPolicyCall {
+	c.ctx = ctx
+	return c
 }
 
 // FoldersSetIamPolicyCall is a structure that is returned by Folders.SetIamPolicy which contains the Request
@@ -525,21 +938,48 @@ type FoldersSetIamPolicyCall struct {
 	Service             *MockService
 	Resource            string
 	Setiampolicyrequest *cloudresourcemanager.SetIamPolicyRequest
+	ctx                 context.Context
 }
 
 // Do will be called on FoldersGetIamPolicyCall to process the policy change and returns the policy it sets
-func (c *FoldersSetIamPolicyCall) Do(opts ...googleapi.CallOption) (*cloudresourcemanager.Policy, error) {
-	match, _ := regexp.MatchString("folders/.*", c.Resource)
-	if !match {
-		return nil, fmt.Errorf("resource format invalid")
+func (c *FoldersSetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Folders.SetIamPolicy", c.Resource, c.Setiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
 	}
-	for _, folder := range c.Service.Folders.FolderList {
-		if folder.FolderID == c.Resource {
-			folder.Policy = c.Setiampolicyrequest.Policy
-			return folder.Policy, nil
-		}
+	return setIamPolicy(c.Service, c.Resource, folderFormat, c.Service.Folders.policyHolder, c.Setiampolicyrequest)
+}
+
+// Context sets the context used by Do
+func (c *FoldersSetIamPolicyCall) Context(ctx context.Context) *FoldersSetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// FoldersTestIamPermissionsCall is a structure that is returned by Folders.TestIamPermissions which contains the
+// Request to test permissions.  Then we call Do() on it to find which of the permissions the Caller has
+type FoldersTestIamPermissionsCall struct {
+	Service                   *MockService
+	Resource                  string
+	Testiampermissionsrequest *cloudresourcemanager.TestIamPermissionsRequest
+	ctx                       context.Context
+}
+
+// Do will be called on FoldersTestIamPermissionsCall and return the permissions the Caller has on the folder
+func (c *FoldersTestIamPermissionsCall) Do(opts ...googleapi.CallOption) (result *cloudresourcemanager.TestIamPermissionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Folders.TestIamPermissions", c.Resource, c.Testiampermissionsrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Resource)
+	return testIamPermissions(c.Service, c.Resource, folderFormat, c.Service.Folders.policyHolder, c.Testiampermissionsrequest)
+}
+
+// Context sets the context used by Do
+func (c *FoldersTestIamPermissionsCall) Context(ctx context.Context) *FoldersTestIamPermissionsCall {
+	c.ctx = ctx
+	return c
 }
 
 // NewPolicy creates a policy with the specified bindings
@@ -0,0 +1,175 @@
+package mockgcp
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+// PolicyDelta is the difference between two policies, in the format GCP writes to the serviceData of
+// SetIamPolicy audit logs
+type PolicyDelta struct {
+	BindingDeltas []*BindingDelta `json:"bindingDeltas,omitempty"`
+}
+
+// BindingDelta is a single member being added to or removed from a role.  Action is ADD or REMOVE
+type BindingDelta struct {
+	Action    string                     `json:"action"`
+	Role      string                     `json:"role"`
+	Member    string                     `json:"member"`
+	Condition *cloudresourcemanager.Expr `json:"condition,omitempty"`
+}
+
+// bindingKey identifies a role and condition pair, which is what a binding is in a policy
+type bindingKey struct {
+	role      string
+	condition string
+}
+
+// keyOf returns the bindingKey for a binding
+func keyOf(role string, condition *cloudresourcemanager.Expr) bindingKey {
+	key := bindingKey{role: role}
+	if condition != nil {
+		key.condition = condition.Title + "\x00" + condition.Expression
+	}
+	return key
+}
+
+// policyMembers returns the members of every role and condition pair in a policy, with the pair's condition
+func policyMembers(policy *cloudresourcemanager.Policy) (map[bindingKey]map[string]bool, map[bindingKey]*cloudresourcemanager.Expr) {
+	members := map[bindingKey]map[string]bool{}
+	conditions := map[bindingKey]*cloudresourcemanager.Expr{}
+	if policy == nil {
+		return members, conditions
+	}
+	for _, binding := range policy.Bindings {
+		if binding == nil {
+			continue
+		}
+		key := keyOf(binding.Role, binding.Condition)
+		if members[key] == nil {
+			members[key] = map[string]bool{}
+		}
+		conditions[key] = binding.Condition
+		for _, member := range binding.Members {
+			members[key][member] = true
+		}
+	}
+	return members, conditions
+}
+
+// DiffPolicies returns the binding deltas that turn before into after, sorted by role, member and action
+// like GCP sorts them.  Bindings are matched on their role and condition, so changing a binding's condition
+// removes its members under the old condition and adds them under the new one.  Either policy can be nil
+func DiffPolicies(before, after *cloudresourcemanager.Policy) *PolicyDelta {
+	beforeMembers, beforeConditions := policyMembers(before)
+	afterMembers, afterConditions := policyMembers(after)
+	delta := &PolicyDelta{}
+	for key, members := range beforeMembers {
+		for member := range members {
+			if !afterMembers[key][member] {
+				delta.BindingDeltas = append(delta.BindingDeltas, &BindingDelta{Action: "REMOVE", Role: key.role, Member: member, Condition: beforeConditions[key]})
+			}
+		}
+	}
+	for key, members := range afterMembers {
+		for member := range members {
+			if !beforeMembers[key][member] {
+				delta.BindingDeltas = append(delta.BindingDeltas, &BindingDelta{Action: "ADD", Role: key.role, Member: member, Condition: afterConditions[key]})
+			}
+		}
+	}
+	sort.Slice(delta.BindingDeltas, func(i, j int) bool {
+		a, b := delta.BindingDeltas[i], delta.BindingDeltas[j]
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		if a.Member != b.Member {
+			return a.Member < b.Member
+		}
+		if a.Action != b.Action {
+			return a.Action < b.Action
+		}
+		return keyOf(a.Role, a.Condition).condition < keyOf(b.Role, b.Condition).condition
+	})
+	return delta
+}
+
+// ApplyPolicyDelta returns a copy of policy with delta applied to it, so applying the delta from DiffPolicies
+// to before gives after.  It returns an error if a REMOVE is for a member the policy doesn't have or an ADD
+// for one it already has, as the delta wasn't taken from this policy.  Bindings left without members are
+// dropped, and the policy version is raised to 3 if a conditional binding is added
+func ApplyPolicyDelta(policy *cloudresourcemanager.Policy, delta *PolicyDelta) (*cloudresourcemanager.Policy, error) {
+	result := copyPolicy(policy)
+	if result == nil {
+		result = &cloudresourcemanager.Policy{}
+	}
+	if delta == nil {
+		return result, nil
+	}
+	for _, bindingDelta := range delta.BindingDeltas {
+		key := keyOf(bindingDelta.Role, bindingDelta.Condition)
+		var binding *cloudresourcemanager.Binding
+		for _, b := range result.Bindings {
+			if b != nil && keyOf(b.Role, b.Condition) == key {
+				binding = b
+				break
+			}
+		}
+		switch bindingDelta.Action {
+		case "ADD":
+			if binding != nil && BindingContains(binding, bindingDelta.Member) {
+				return nil, fmt.Errorf("%v already has %v", bindingDelta.Member, bindingDelta.Role)
+			}
+			if binding == nil {
+				binding = copyBinding(&cloudresourcemanager.Binding{Role: bindingDelta.Role, Condition: bindingDelta.Condition})
+				result.Bindings = append(result.Bindings, binding)
+			}
+			binding.Members = append(binding.Members, bindingDelta.Member)
+			if binding.Condition != nil && result.Version < 3 {
+				result.Version = 3
+			}
+		case "REMOVE":
+			if binding == nil || !BindingContains(binding, bindingDelta.Member) {
+				return nil, fmt.Errorf("%v doesn't have %v", bindingDelta.Member, bindingDelta.Role)
+			}
+			members := []string{}
+			for _, member := range binding.Members {
+				if member != bindingDelta.Member {
+					members = append(members, member)
+				}
+			}
+			binding.Members = members
+		default:
+			return nil, fmt.Errorf("invalid binding delta action: %v", bindingDelta.Action)
+		}
+	}
+	bindings := result.Bindings[:0]
+	for _, binding := range result.Bindings {
+		if binding != nil && len(binding.Members) > 0 {
+			bindings = append(bindings, binding)
+		}
+	}
+	result.Bindings = bindings
+	return result, nil
+}
+
+// Reverse returns the delta that undoes d, so applying it to after gives before.  This is what a rollback
+// of the change d describes does
+func (d *PolicyDelta) Reverse() *PolicyDelta {
+	reverse := &PolicyDelta{}
+	if d == nil {
+		return reverse
+	}
+	for i := len(d.BindingDeltas) - 1; i >= 0; i-- {
+		bindingDelta := *d.BindingDeltas[i]
+		if bindingDelta.Action == "ADD" {
+			bindingDelta.Action = "REMOVE"
+		} else if bindingDelta.Action == "REMOVE" {
+			bindingDelta.Action = "ADD"
+		}
+		reverse.BindingDeltas = append(reverse.BindingDeltas, &bindingDelta)
+	}
+	return reverse
+}
@@ -0,0 +1,123 @@
+package mockgcp
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+func TestDiffPolicies(t *testing.T) {
+	condition := &cloudresourcemanager.Expr{Title: "expires", Expression: `request.time < timestamp("2030-01-01T00:00:00Z")`}
+	conditional := NewBinding("roles/viewer", "user:carol@testdomain.co")
+	conditional.Condition = condition
+	tests := []struct {
+		name   string
+		before *cloudresourcemanager.Policy
+		after  *cloudresourcemanager.Policy
+		want   []BindingDelta
+	}{
+		{
+			name:   "should return no deltas for equal policies",
+			before: GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co")),
+			after:  GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co")),
+			want:   []BindingDelta{},
+		},
+		{
+			name:   "should add every member of a new policy",
+			before: nil,
+			after:  GeneratePolicy(NewBinding("roles/owner", "user:bob@testdomain.co", "user:alice@testdomain.co")),
+			want: []BindingDelta{
+				{Action: "ADD", Role: "roles/owner", Member: "user:alice@testdomain.co"},
+				{Action: "ADD", Role: "roles/owner", Member: "user:bob@testdomain.co"},
+			},
+		},
+		{
+			name:   "should sort by role then member",
+			before: GeneratePolicy(NewBinding("roles/viewer", "user:alice@testdomain.co"), NewBinding("roles/owner", "user:bob@testdomain.co")),
+			after:  GeneratePolicy(NewBinding("roles/viewer", "user:bob@testdomain.co"), NewBinding("roles/editor", "user:bob@testdomain.co")),
+			want: []BindingDelta{
+				{Action: "ADD", Role: "roles/editor", Member: "user:bob@testdomain.co"},
+				{Action: "REMOVE", Role: "roles/owner", Member: "user:bob@testdomain.co"},
+				{Action: "REMOVE", Role: "roles/viewer", Member: "user:alice@testdomain.co"},
+				{Action: "ADD", Role: "roles/viewer", Member: "user:bob@testdomain.co"},
+			},
+		},
+		{
+			name:   "should treat a new condition as a remove and an add",
+			before: GeneratePolicy(NewBinding("roles/viewer", "user:carol@testdomain.co")),
+			after:  GeneratePolicy(conditional),
+			want: []BindingDelta{
+				{Action: "ADD", Role: "roles/viewer", Member: "user:carol@testdomain.co", Condition: condition},
+				{Action: "REMOVE", Role: "roles/viewer", Member: "user:carol@testdomain.co"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []BindingDelta{}
+			for _, delta := range DiffPolicies(tt.before, tt.after).BindingDeltas {
+				got = append(got, *delta)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPolicyDelta(t *testing.T) {
+	before := GeneratePolicy(
+		NewBinding("roles/owner", "user:alice@testdomain.co"),
+		NewBinding("roles/viewer", "user:alice@testdomain.co", "user:bob@testdomain.co"),
+	)
+	conditional := NewBinding("roles/editor", "user:carol@testdomain.co")
+	conditional.Condition = &cloudresourcemanager.Expr{Title: "prod", Expression: `resource.name.startsWith("projects/prod")`}
+	after := GeneratePolicy(
+		NewBinding("roles/owner", "user:bob@testdomain.co"),
+		NewBinding("roles/viewer", "user:alice@testdomain.co", "user:bob@testdomain.co"),
+		conditional,
+	)
+	delta := DiffPolicies(before, after)
+
+	t.Run("should turn before into after", func(t *testing.T) {
+		got, err := ApplyPolicyDelta(before, delta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(DiffPolicies(got, after).BindingDeltas) != 0 {
+			t.Errorf("got %v want %v", got.Bindings, after.Bindings)
+		}
+		if got.Version != 3 {
+			t.Errorf("got version %v want %v", got.Version, 3)
+		}
+	})
+	t.Run("should roll back with the reversed delta", func(t *testing.T) {
+		got, err := ApplyPolicyDelta(after, delta.Reverse())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(DiffPolicies(got, before).BindingDeltas) != 0 {
+			t.Errorf("got %v want %v", got.Bindings, before.Bindings)
+		}
+		if PolicyContains(got, "roles/editor") != nil {
+			t.Errorf("expected the emptied roles/editor binding to be dropped")
+		}
+	})
+	t.Run("should not change the policy passed in", func(t *testing.T) {
+		original := copyPolicy(before)
+		ApplyPolicyDelta(before, delta)
+		if !reflect.DeepEqual(before, original) {
+			t.Errorf("got %v want %v", before, original)
+		}
+	})
+	t.Run("should error on a delta taken from another policy", func(t *testing.T) {
+		if _, err := ApplyPolicyDelta(after, delta); err == nil {
+			t.Errorf("expected an error applying the delta twice")
+		}
+		bad := &PolicyDelta{BindingDeltas: []*BindingDelta{{Action: "CHANGE", Role: "roles/owner", Member: "user:alice@testdomain.co"}}}
+		if _, err := ApplyPolicyDelta(before, bad); err == nil {
+			t.Errorf("expected an error for an invalid action")
+		}
+	})
+}
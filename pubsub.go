@@ -0,0 +1,746 @@
+package mockgcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+const (
+	// deletedTopic is what a subscription's topic is set to once the topic is deleted
+	deletedTopic = "_deleted-topic_"
+)
+
+var (
+	topicNameFormat        = regexp.MustCompile(`^projects/[^/]+/topics/[a-zA-Z][-a-zA-Z0-9_.~+%]{2,254}$`)
+	subscriptionNameFormat = regexp.MustCompile(`^projects/[^/]+/subscriptions/[a-zA-Z][-a-zA-Z0-9_.~+%]{2,254}$`)
+)
+
+// Topic is a mock of a google cloud Pub/Sub Topic, which belongs to a Project
+type Topic struct {
+	IamPolicyHolder
+	Name      string
+	ProjectID string
+	Labels    map[string]string
+}
+
+// Subscription is a mock of a google cloud Pub/Sub Subscription, which belongs to a Project.  The topic it's
+// attached to can be in another project, and is set to _deleted-topic_ if the topic is deleted
+type Subscription struct {
+	IamPolicyHolder
+	Name               string
+	ProjectID          string
+	Topic              string
+	AckDeadlineSeconds int64
+	Labels             map[string]string
+}
+
+// toAPI converts the mock topic to the type returned by the Pub/Sub API
+func (t *Topic) toAPI() *pubsub.Topic {
+	return &pubsub.Topic{Name: t.Name, Labels: copyLabels(t.Labels)}
+}
+
+// toAPI converts the mock subscription to the type returned by the Pub/Sub API
+func (s *Subscription) toAPI() *pubsub.Subscription {
+	return &pubsub.Subscription{
+		Name:               s.Name,
+		Topic:              s.Topic,
+		AckDeadlineSeconds: s.AckDeadlineSeconds,
+		Labels:             copyLabels(s.Labels),
+		State:              "ACTIVE",
+	}
+}
+
+// TopicsService is a mock of google Cloud Pub/Sub's projects.topics Service
+type TopicsService struct {
+	Service       *MockService
+	Subscriptions *TopicsSubscriptionsService
+}
+
+// NewTopicsService will return a new Topics Service
+func NewTopicsService(s *MockService) *TopicsService {
+	rs := &TopicsService{Service: s}
+	rs.Subscriptions = &TopicsSubscriptionsService{Service: s}
+	return rs
+}
+
+// NewTopic creates a new topic with the specified ID and policy on the project and returns a pointer to
+// the created topic.  If policy isn't specified it will generate a blank one.  It returns nil if the
+// project doesn't exist in the Projects Service
+func (r *TopicsService) NewTopic(projectID, topicID string, policy *cloudresourcemanager.Policy) *Topic {
+	project := r.Service.Projects.get(projectResourceName(projectID))
+	if project == nil {
+		return nil
+	}
+	if policy == nil {
+		policy = &cloudresourcemanager.Policy{}
+	}
+	topic := &Topic{
+		IamPolicyHolder: IamPolicyHolder{Policy: policy},
+		Name:            fmt.Sprintf("%v/topics/%v", project.ProjectID, topicID),
+		ProjectID:       project.ProjectID,
+	}
+	project.Topics = append(project.Topics, topic)
+	r.Service.publishNewAsset(&topic.IamPolicyHolder)
+	return topic
+}
+
+// find returns the topic with the given resource name, or nil if there isn't one
+func (r *TopicsService) find(name string) *Topic {
+	project := r.Service.Projects.get(projectOf(name))
+	if project == nil {
+		return nil
+	}
+	for _, topic := range project.Topics {
+		if topic.Name == name {
+			return topic
+		}
+	}
+	return nil
+}
+
+// policyHolder returns the policy holder for the topic, or nil if there isn't one
+func (r *TopicsService) policyHolder(resource string) *IamPolicyHolder {
+	topic := r.find(resource)
+	if topic == nil {
+		return nil
+	}
+	return &topic.IamPolicyHolder
+}
+
+// Create will take a topic resource name and a topic and returns a Create Call, so we can run a Do() method on it.
+func (r *TopicsService) Create(name string, topic *pubsub.Topic) *TopicsCreateCall {
+	return &TopicsCreateCall{Service: r.Service, Name: name, Topic: topic}
+}
+
+// TopicsCreateCall is a structure that is returned by Topics.Create.  Then we call Do() on it to create the topic
+type TopicsCreateCall struct {
+	Service *MockService
+	Name    string
+	Topic   *pubsub.Topic
+	ctx     context.Context
+}
+
+// Do will be called on TopicsCreateCall to create the topic and return it
+func (c *TopicsCreateCall) Do(opts ...googleapi.CallOption) (result *pubsub.Topic, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Topics.Create", c.Name, c.Topic)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if !topicNameFormat.MatchString(c.Name) || strings.HasPrefix(c.Name[strings.LastIndex(c.Name, "/")+1:], "goog") {
+		return nil, fmt.Errorf("resource format invalid")
+	}
+	if c.Service.Projects.get(projectOf(c.Name)) == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, projectOf(c.Name))
+	}
+	if c.Service.Topics.find(c.Name) != nil {
+		return nil, fmt.Errorf("%v: %v", resourceAlreadyExistsError, c.Name)
+	}
+	topic := c.Service.Topics.NewTopic(projectOf(c.Name), c.Name[strings.LastIndex(c.Name, "/")+1:], nil)
+	if c.Topic != nil {
+		topic.Labels = copyLabels(c.Topic.Labels)
+	}
+	c.Service.AuditLogs.recordMutation("pubsub.googleapis.com", "google.pubsub.v1.Publisher.CreateTopic", topic.Name)
+	return topic.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *TopicsCreateCall) Context(ctx context.Context) *TopicsCreateCall {
+	c.ctx = ctx
+	return c
+}
+
+// Get will take a topic resource name and returns a Get Call, so we can run a Do() method on it.
+func (r *TopicsService) Get(topic string) *TopicsGetCall {
+	return &TopicsGetCall{Service: r.Service, Topic: topic}
+}
+
+// TopicsGetCall is a structure that is returned by Topics.Get.  Then we call Do() on it to return the topic
+type TopicsGetCall struct {
+	Service *MockService
+	Topic   string
+	ctx     context.Context
+}
+
+// Do will be called on TopicsGetCall and return the topic found
+func (c *TopicsGetCall) Do(opts ...googleapi.CallOption) (result *pubsub.Topic, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Topics.Get", c.Topic, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	topic := c.Service.Topics.find(c.Topic)
+	if topic == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Topic)
+	}
+	return topic.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *TopicsGetCall) Context(ctx context.Context) *TopicsGetCall {
+	c.ctx = ctx
+	return c
+}
+
+// List will take a project resource name and returns a List Call, so we can run a Do() method on it.
+func (r *TopicsService) List(project string) *TopicsListCall {
+	return &TopicsListCall{Service: r.Service, Project: project}
+}
+
+// TopicsListCall is a structure that is returned by Topics.List.  Then we call Do() on it to return the
+// project's topics
+type TopicsListCall struct {
+	Service *MockService
+	Project string
+	ctx     context.Context
+}
+
+// Do will be called on TopicsListCall and return the topics in the project
+func (c *TopicsListCall) Do(opts ...googleapi.CallOption) (result *pubsub.ListTopicsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Topics.List", c.Project, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	project := c.Service.Projects.get(c.Project)
+	if project == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Project)
+	}
+	response := &pubsub.ListTopicsResponse{}
+	for _, topic := range project.Topics {
+		response.Topics = append(response.Topics, topic.toAPI())
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *TopicsListCall) Context(ctx context.Context) *TopicsListCall {
+	c.ctx = ctx
+	return c
+}
+
+// Delete will take a topic resource name and returns a Delete Call, so we can run a Do() method on it.
+func (r *TopicsService) Delete(topic string) *TopicsDeleteCall {
+	return &TopicsDeleteCall{Service: r.Service, Topic: topic}
+}
+
+// TopicsDeleteCall is a structure that is returned by Topics.Delete.  Then we call Do() on it to delete the topic
+type TopicsDeleteCall struct {
+	Service *MockService
+	Topic   string
+	ctx     context.Context
+}
+
+// Do will be called on TopicsDeleteCall to delete the topic.  Subscriptions on the topic aren't deleted, but
+// their topic is set to _deleted-topic_, the same as the real API
+func (c *TopicsDeleteCall) Do(opts ...googleapi.CallOption) (result *pubsub.Empty, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Topics.Delete", c.Topic, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	project := c.Service.Projects.get(projectOf(c.Topic))
+	if project == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Topic)
+	}
+	for i, topic := range project.Topics {
+		if topic.Name != c.Topic {
+			continue
+		}
+		prior := c.Service.assetSnapshot(&topic.IamPolicyHolder)
+		project.Topics = append(project.Topics[:i], project.Topics[i+1:]...)
+		c.Service.AuditLogs.recordMutation("pubsub.googleapis.com", "google.pubsub.v1.Publisher.DeleteTopic", topic.Name)
+		c.Service.publishAssetChange(prior, nil)
+		for _, p := range c.Service.Projects.ProjectList {
+			for _, subscription := range p.Subscriptions {
+				if subscription.Topic == c.Topic {
+					subscription.Topic = deletedTopic
+				}
+			}
+		}
+		return &pubsub.Empty{}, nil
+	}
+	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Topic)
+}
+
+// Context sets the context used by Do
+func (c *TopicsDeleteCall) Context(ctx context.Context) *TopicsDeleteCall {
+	c.ctx = ctx
+	return c
+}
+
+// GetIamPolicy will take a topic resource name and returns a GetIamPolicy Call, so we can run a Do() method on it.
+func (r *TopicsService) GetIamPolicy(resource string) *TopicsGetIamPolicyCall {
+	return &TopicsGetIamPolicyCall{Service: r.Service, Resource: resource}
+}
+
+// TopicsGetIamPolicyCall is a structure that is returned by Topics.GetIamPolicy.  Then we call Do() on it
+// to actually return the policy
+type TopicsGetIamPolicyCall struct {
+	Service  *MockService
+	Resource string
+	ctx      context.Context
+}
+
+// Do will be called on TopicsGetIamPolicyCall and return the policy found
+func (c *TopicsGetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *pubsub.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Topics.GetIamPolicy", c.Resource, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	policy := &pubsub.Policy{}
+	if err := getAPIIamPolicy(policy, c.Resource, topicNameFormat, c.Service.Topics.policyHolder); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Context sets the context used by Do
+func (c *TopicsGetIamPolicyCall) Context(ctx context.Context) *TopicsGetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// SetIamPolicy will take a topic resource name and a setiampolicyrequest and returns a SetIamPolicy Call,
+// so we can run a Do() method on it.
+func (r *TopicsService) SetIamPolicy(resource string, setiampolicyrequest *pubsub.SetIamPolicyRequest) *TopicsSetIamPolicyCall {
+	return &TopicsSetIamPolicyCall{Service: r.Service, Resource: resource, Setiampolicyrequest: setiampolicyrequest}
+}
+
+// TopicsSetIamPolicyCall is a structure that is returned by Topics.SetIamPolicy.  Then we call Do() on it to
+// Set the Topic Policy
+type TopicsSetIamPolicyCall struct {
+	Service             *MockService
+	Resource            string
+	Setiampolicyrequest *pubsub.SetIamPolicyRequest
+	ctx                 context.Context
+}
+
+// Do will be called on TopicsSetIamPolicyCall to process the policy change and returns the policy it sets
+func (c *TopicsSetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *pubsub.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Topics.SetIamPolicy", c.Resource, c.Setiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	policy := &pubsub.Policy{}
+	if err := setAPIIamPolicy(c.Service, policy, c.Setiampolicyrequest, c.Resource, topicNameFormat, c.Service.Topics.policyHolder); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Context sets the context used by Do
+func (c *TopicsSetIamPolicyCall) Context(ctx context.Context) *TopicsSetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// TestIamPermissions will take a topic resource name and a testiampermissionsrequest and returns a
+// TestIamPermissions Call, so we can run a Do() method on it.
+func (r *TopicsService) TestIamPermissions(resource string, testiampermissionsrequest *pubsub.TestIamPermissionsRequest) *TopicsTestIamPermissionsCall {
+	return &TopicsTestIamPermissionsCall{Service: r.Service, Resource: resource, Testiampermissionsrequest: testiampermissionsrequest}
+}
+
+// TopicsTestIamPermissionsCall is a structure that is returned by Topics.TestIamPermissions.  Then we call Do()
+// on it to find which of the permissions the Caller has
+type TopicsTestIamPermissionsCall struct {
+	Service                   *MockService
+	Resource                  string
+	Testiampermissionsrequest *pubsub.TestIamPermissionsRequest
+	ctx                       context.Context
+}
+
+// Do will be called on TopicsTestIamPermissionsCall and return the permissions the Caller has on the topic
+func (c *TopicsTestIamPermissionsCall) Do(opts ...googleapi.CallOption) (result *pubsub.TestIamPermissionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Topics.TestIamPermissions", c.Resource, c.Testiampermissionsrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	response := &pubsub.TestIamPermissionsResponse{}
+	if err := testAPIIamPermissions(c.Service, response, c.Testiampermissionsrequest, c.Resource, topicNameFormat, c.Service.Topics.policyHolder); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *TopicsTestIamPermissionsCall) Context(ctx context.Context) *TopicsTestIamPermissionsCall {
+	c.ctx = ctx
+	return c
+}
+
+// TopicsSubscriptionsService is a mock of google Cloud Pub/Sub's projects.topics.subscriptions Service
+type TopicsSubscriptionsService struct {
+	Service *MockService
+}
+
+// List will take a topic resource name and returns a List Call, so we can run a Do() method on it.
+func (r *TopicsSubscriptionsService) List(topic string) *TopicsSubscriptionsListCall {
+	return &TopicsSubscriptionsListCall{Service: r.Service, Topic: topic}
+}
+
+// TopicsSubscriptionsListCall is a structure that is returned by Topics.Subscriptions.List.  Then we call
+// Do() on it to return the names of the subscriptions attached to the topic
+type TopicsSubscriptionsListCall struct {
+	Service *MockService
+	Topic   string
+	ctx     context.Context
+}
+
+// Do will be called on TopicsSubscriptionsListCall and return the subscription names for the topic
+func (c *TopicsSubscriptionsListCall) Do(opts ...googleapi.CallOption) (result *pubsub.ListTopicSubscriptionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Topics.Subscriptions.List", c.Topic, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if c.Service.Topics.find(c.Topic) == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Topic)
+	}
+	response := &pubsub.ListTopicSubscriptionsResponse{}
+	for _, project := range c.Service.Projects.ProjectList {
+		for _, subscription := range project.Subscriptions {
+			if subscription.Topic == c.Topic {
+				response.Subscriptions = append(response.Subscriptions, subscription.Name)
+			}
+		}
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *TopicsSubscriptionsListCall) Context(ctx context.Context) *TopicsSubscriptionsListCall {
+	c.ctx = ctx
+	return c
+}
+
+// SubscriptionsService is a mock of google Cloud Pub/Sub's projects.subscriptions Service
+type SubscriptionsService struct {
+	Service *MockService
+}
+
+// NewSubscriptionsService will return a new Subscriptions Service
+func NewSubscriptionsService(s *MockService) *SubscriptionsService {
+	rs := &SubscriptionsService{Service: s}
+	return rs
+}
+
+// NewSubscription creates a new subscription with the specified ID and policy on the project, attached to
+// the topic (a full topic resource name), and returns a pointer to the created subscription.  If policy isn't
+// specified it will generate a blank one.  It returns nil if the project doesn't exist in the Projects Service
+func (r *SubscriptionsService) NewSubscription(projectID, subscriptionID, topic string, policy *cloudresourcemanager.Policy) *Subscription {
+	project := r.Service.Projects.get(projectResourceName(projectID))
+	if project == nil {
+		return nil
+	}
+	if policy == nil {
+		policy = &cloudresourcemanager.Policy{}
+	}
+	subscription := &Subscription{
+		IamPolicyHolder:    IamPolicyHolder{Policy: policy},
+		Name:               fmt.Sprintf("%v/subscriptions/%v", project.ProjectID, subscriptionID),
+		ProjectID:          project.ProjectID,
+		Topic:              topic,
+		AckDeadlineSeconds: 10,
+	}
+	project.Subscriptions = append(project.Subscriptions, subscription)
+	r.Service.publishNewAsset(&subscription.IamPolicyHolder)
+	return subscription
+}
+
+// find returns the subscription with the given resource name, or nil if there isn't one
+func (r *SubscriptionsService) find(name string) *Subscription {
+	project := r.Service.Projects.get(projectOf(name))
+	if project == nil {
+		return nil
+	}
+	for _, subscription := range project.Subscriptions {
+		if subscription.Name == name {
+			return subscription
+		}
+	}
+	return nil
+}
+
+// policyHolder returns the policy holder for the subscription, or nil if there isn't one
+func (r *SubscriptionsService) policyHolder(resource string) *IamPolicyHolder {
+	subscription := r.find(resource)
+	if subscription == nil {
+		return nil
+	}
+	return &subscription.IamPolicyHolder
+}
+
+// Create will take a subscription resource name and a subscription and returns a Create Call, so we can
+// run a Do() method on it.
+func (r *SubscriptionsService) Create(name string, subscription *pubsub.Subscription) *SubscriptionsCreateCall {
+	return &SubscriptionsCreateCall{Service: r.Service, Name: name, Subscription: subscription}
+}
+
+// SubscriptionsCreateCall is a structure that is returned by Subscriptions.Create.  Then we call Do() on it
+// to create the subscription
+type SubscriptionsCreateCall struct {
+	Service      *MockService
+	Name         string
+	Subscription *pubsub.Subscription
+	ctx          context.Context
+}
+
+// Do will be called on SubscriptionsCreateCall to create the subscription and return it.  The topic has to exist
+func (c *SubscriptionsCreateCall) Do(opts ...googleapi.CallOption) (result *pubsub.Subscription, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Subscriptions.Create", c.Name, c.Subscription)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if !subscriptionNameFormat.MatchString(c.Name) {
+		return nil, fmt.Errorf("resource format invalid")
+	}
+	if c.Service.Projects.get(projectOf(c.Name)) == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, projectOf(c.Name))
+	}
+	if c.Subscription == nil || c.Service.Topics.find(c.Subscription.Topic) == nil {
+		return nil, fmt.Errorf("%v: topic", resourceNotFoundError)
+	}
+	if c.Service.Subscriptions.find(c.Name) != nil {
+		return nil, fmt.Errorf("%v: %v", resourceAlreadyExistsError, c.Name)
+	}
+	subscription := c.Service.Subscriptions.NewSubscription(projectOf(c.Name), c.Name[strings.LastIndex(c.Name, "/")+1:], c.Subscription.Topic, nil)
+	if c.Subscription.AckDeadlineSeconds != 0 {
+		subscription.AckDeadlineSeconds = c.Subscription.AckDeadlineSeconds
+	}
+	subscription.Labels = copyLabels(c.Subscription.Labels)
+	c.Service.AuditLogs.recordMutation("pubsub.googleapis.com", "google.pubsub.v1.Subscriber.CreateSubscription", subscription.Name)
+	return subscription.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *SubscriptionsCreateCall) Context(ctx context.Context) *SubscriptionsCreateCall {
+	c.ctx = ctx
+	return c
+}
+
+// Get will take a subscription resource name and returns a Get Call, so we can run a Do() method on it.
+func (r *SubscriptionsService) Get(subscription string) *SubscriptionsGetCall {
+	return &SubscriptionsGetCall{Service: r.Service, Subscription: subscription}
+}
+
+// SubscriptionsGetCall is a structure that is returned by Subscriptions.Get.  Then we call Do() on it to
+// return the subscription
+type SubscriptionsGetCall struct {
+	Service      *MockService
+	Subscription string
+	ctx          context.Context
+}
+
+// Do will be called on SubscriptionsGetCall and return the subscription found
+func (c *SubscriptionsGetCall) Do(opts ...googleapi.CallOption) (result *pubsub.Subscription, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Subscriptions.Get", c.Subscription, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	subscription := c.Service.Subscriptions.find(c.Subscription)
+	if subscription == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Subscription)
+	}
+	return subscription.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *SubscriptionsGetCall) Context(ctx context.Context) *SubscriptionsGetCall {
+	c.ctx = ctx
+	return c
+}
+
+// List will take a project resource name and returns a List Call, so we can run a Do() method on it.
+func (r *SubscriptionsService) List(project string) *SubscriptionsListCall {
+	return &SubscriptionsListCall{Service: r.Service, Project: project}
+}
+
+// SubscriptionsListCall is a structure that is returned by Subscriptions.List.  Then we call Do() on it to
+// return the project's subscriptions
+type SubscriptionsListCall struct {
+	Service *MockService
+	Project string
+	ctx     context.Context
+}
+
+// Do will be called on SubscriptionsListCall and return the subscriptions in the project
+func (c *SubscriptionsListCall) Do(opts ...googleapi.CallOption) (result *pubsub.ListSubscriptionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Subscriptions.List", c.Project, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	project := c.Service.Projects.get(c.Project)
+	if project == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Project)
+	}
+	response := &pubsub.ListSubscriptionsResponse{}
+	for _, subscription := range project.Subscriptions {
+		response.Subscriptions = append(response.Subscriptions, subscription.toAPI())
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *SubscriptionsListCall) Context(ctx context.Context) *SubscriptionsListCall {
+	c.ctx = ctx
+	return c
+}
+
+// Delete will take a subscription resource name and returns a Delete Call, so we can run a Do() method on it.
+func (r *SubscriptionsService) Delete(subscription string) *SubscriptionsDeleteCall {
+	return &SubscriptionsDeleteCall{Service: r.Service, Subscription: subscription}
+}
+
+// SubscriptionsDeleteCall is a structure that is returned by Subscriptions.Delete.  Then we call Do() on it
+// to delete the subscription
+type SubscriptionsDeleteCall struct {
+	Service      *MockService
+	Subscription string
+	ctx          context.Context
+}
+
+// Do will be called on SubscriptionsDeleteCall to delete the subscription
+func (c *SubscriptionsDeleteCall) Do(opts ...googleapi.CallOption) (result *pubsub.Empty, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Subscriptions.Delete", c.Subscription, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	project := c.Service.Projects.get(projectOf(c.Subscription))
+	if project == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Subscription)
+	}
+	for i, subscription := range project.Subscriptions {
+		if subscription.Name == c.Subscription {
+			prior := c.Service.assetSnapshot(&subscription.IamPolicyHolder)
+			project.Subscriptions = append(project.Subscriptions[:i], project.Subscriptions[i+1:]...)
+			c.Service.AuditLogs.recordMutation("pubsub.googleapis.com", "google.pubsub.v1.Subscriber.DeleteSubscription", subscription.Name)
+			c.Service.publishAssetChange(prior, nil)
+			return &pubsub.Empty{}, nil
+		}
+	}
+	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Subscription)
+}
+
+// Context sets the context used by Do
+func (c *SubscriptionsDeleteCall) Context(ctx context.Context) *SubscriptionsDeleteCall {
+	c.ctx = ctx
+	return c
+}
+
+// GetIamPolicy will take a subscription resource name and returns a GetIamPolicy Call, so we can run a
+// Do() method on it.
+func (r *SubscriptionsService) GetIamPolicy(resource string) *SubscriptionsGetIamPolicyCall {
+	return &SubscriptionsGetIamPolicyCall{Service: r.Service, Resource: resource}
+}
+
+// SubscriptionsGetIamPolicyCall is a structure that is returned by Subscriptions.GetIamPolicy.  Then we call
+// Do() on it to actually return the policy
+type SubscriptionsGetIamPolicyCall struct {
+	Service  *MockService
+	Resource string
+	ctx      context.Context
+}
+
+// Do will be called on SubscriptionsGetIamPolicyCall and return the policy found
+func (c *SubscriptionsGetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *pubsub.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Subscriptions.GetIamPolicy", c.Resource, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	policy := &pubsub.Policy{}
+	if err := getAPIIamPolicy(policy, c.Resource, subscriptionNameFormat, c.Service.Subscriptions.policyHolder); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Context sets the context used by Do
+func (c *SubscriptionsGetIamPolicyCall) Context(ctx context.Context) *SubscriptionsGetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// SetIamPolicy will take a subscription resource name and a setiampolicyrequest and returns a SetIamPolicy
+// Call, so we can run a Do() method on it.
+func (r *SubscriptionsService) SetIamPolicy(resource string, setiampolicyrequest *pubsub.SetIamPolicyRequest) *SubscriptionsSetIamPolicyCall {
+	return &SubscriptionsSetIamPolicyCall{Service: r.Service, Resource: resource, Setiampolicyrequest: setiampolicyrequest}
+}
+
+// SubscriptionsSetIamPolicyCall is a structure that is returned by Subscriptions.SetIamPolicy.  Then we call
+// Do() on it to Set the Subscription Policy
+type SubscriptionsSetIamPolicyCall struct {
+	Service             *MockService
+	Resource            string
+	Setiampolicyrequest *pubsub.SetIamPolicyRequest
+	ctx                 context.Context
+}
+
+// Do will be called on SubscriptionsSetIamPolicyCall to process the policy change and returns the policy it sets
+func (c *SubscriptionsSetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *pubsub.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Subscriptions.SetIamPolicy", c.Resource, c.Setiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	policy := &pubsub.Policy{}
+	if err := setAPIIamPolicy(c.Service, policy, c.Setiampolicyrequest, c.Resource, subscriptionNameFormat, c.Service.Subscriptions.policyHolder); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Context sets the context used by Do
+func (c *SubscriptionsSetIamPolicyCall) Context(ctx context.Context) *SubscriptionsSetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// TestIamPermissions will take a subscription resource name and a testiampermissionsrequest and returns a
+// TestIamPermissions Call, so we can run a Do() method on it.
+func (r *SubscriptionsService) TestIamPermissions(resource string, testiampermissionsrequest *pubsub.TestIamPermissionsRequest) *SubscriptionsTestIamPermissionsCall {
+	return &SubscriptionsTestIamPermissionsCall{Service: r.Service, Resource: resource, Testiampermissionsrequest: testiampermissionsrequest}
+}
+
+// SubscriptionsTestIamPermissionsCall is a structure that is returned by Subscriptions.TestIamPermissions.
+// Then we call Do() on it to find which of the permissions the Caller has
+type SubscriptionsTestIamPermissionsCall struct {
+	Service                   *MockService
+	Resource                  string
+	Testiampermissionsrequest *pubsub.TestIamPermissionsRequest
+	ctx                       context.Context
+}
+
+// Do will be called on SubscriptionsTestIamPermissionsCall and return the permissions the Caller has on the subscription
+func (c *SubscriptionsTestIamPermissionsCall) Do(opts ...googleapi.CallOption) (result *pubsub.TestIamPermissionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Subscriptions.TestIamPermissions", c.Resource, c.Testiampermissionsrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	response := &pubsub.TestIamPermissionsResponse{}
+	if err := testAPIIamPermissions(c.Service, response, c.Testiampermissionsrequest, c.Resource, subscriptionNameFormat, c.Service.Subscriptions.policyHolder); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *SubscriptionsTestIamPermissionsCall) Context(ctx context.Context) *SubscriptionsTestIamPermissionsCall {
+	c.ctx = ctx
+	return c
+}
@@ -0,0 +1,154 @@
+package mockgcp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+func TestTopics_Create_Do(t *testing.T) {
+	t.Run("should create topic on project", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+
+		_, err := service.Topics.Create("projects/test-project/topics/test-topic", &pubsub.Topic{}).Do()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		response, _ := service.Topics.List("projects/test-project").Do()
+		want := "projects/test-project/topics/test-topic"
+		got := response.Topics[0].Name
+
+		if got != want {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("should return err if project doesn't exist", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+
+		_, err := service.Topics.Create("projects/test-project/topics/test-topic", &pubsub.Topic{}).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+	t.Run("should return err if topic name starts with goog", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+
+		_, err := service.Topics.Create("projects/test-project/topics/google-topic", &pubsub.Topic{}).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestSubscriptions_Create_Do(t *testing.T) {
+	t.Run("should create subscription on topic in another project", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/topic-project", "", nil)
+		service.Projects.NewProject("projects/subscription-project", "", nil)
+		topic := service.Topics.NewTopic("topic-project", "test-topic", nil)
+
+		_, err := service.Subscriptions.Create("projects/subscription-project/subscriptions/test-subscription", &pubsub.Subscription{Topic: topic.Name}).Do()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		response, _ := service.Topics.Subscriptions.List(topic.Name).Do()
+		want := []string{"projects/subscription-project/subscriptions/test-subscription"}
+
+		if !reflect.DeepEqual(response.Subscriptions, want) {
+			t.Errorf("got %v want %v", response.Subscriptions, want)
+		}
+	})
+	t.Run("should return err if topic doesn't exist", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+
+		_, err := service.Subscriptions.Create("projects/test-project/subscriptions/test-subscription", &pubsub.Subscription{Topic: "projects/test-project/topics/missing"}).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestTopics_Delete_Do(t *testing.T) {
+	t.Run("should detach subscriptions from deleted topic", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+		topic := service.Topics.NewTopic("test-project", "test-topic", nil)
+		subscription := service.Subscriptions.NewSubscription("test-project", "test-subscription", topic.Name, nil)
+
+		service.Topics.Delete(topic.Name).Do()
+
+		want := "_deleted-topic_"
+		got, _ := service.Subscriptions.Get(subscription.Name).Do()
+
+		if got.Topic != want {
+			t.Errorf("got %v want %v", got.Topic, want)
+		}
+		if _, err := service.Topics.Get(topic.Name).Do(); err == nil {
+			t.Errorf("expected an error getting deleted topic but got none")
+		}
+	})
+}
+
+func TestTopics_IamPolicy_Do(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject("projects/test-project", "", nil)
+	topic := service.Topics.NewTopic("test-project", "test-topic", nil)
+	policy := &pubsub.Policy{Bindings: []*pubsub.Binding{
+		{Role: "roles/pubsub.publisher", Members: []string{"serviceAccount:publisher@test-project.iam.gserviceaccount.com"}},
+	}}
+
+	t.Run("should set and get topic policy", func(t *testing.T) {
+		service.Topics.SetIamPolicy(topic.Name, &pubsub.SetIamPolicyRequest{Policy: policy}).Do()
+
+		got, _ := service.Topics.GetIamPolicy(topic.Name).Do()
+
+		if !reflect.DeepEqual(got.Bindings, policy.Bindings) {
+			t.Errorf("got %v want %v", got.Bindings, policy.Bindings)
+		}
+	})
+	t.Run("should test permissions for caller", func(t *testing.T) {
+		service.Caller = "serviceAccount:publisher@test-project.iam.gserviceaccount.com"
+		request := &pubsub.TestIamPermissionsRequest{Permissions: []string{"pubsub.topics.publish", "pubsub.topics.delete"}}
+
+		want := []string{"pubsub.topics.publish"}
+		got, _ := service.Topics.TestIamPermissions(topic.Name, request).Do()
+
+		if !reflect.DeepEqual(got.Permissions, want) {
+			t.Errorf("got %v want %v", got.Permissions, want)
+		}
+	})
+	t.Run("should return err if topic name doesn't match format", func(t *testing.T) {
+		_, err := service.Topics.SetIamPolicy("test-topic", &pubsub.SetIamPolicyRequest{Policy: policy}).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestSubscriptions_IamPolicy_Do(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject("projects/test-project", "", nil)
+	topic := service.Topics.NewTopic("test-project", "test-topic", nil)
+	subscription := service.Subscriptions.NewSubscription("test-project", "test-subscription", topic.Name, nil)
+	policy := &pubsub.Policy{Bindings: []*pubsub.Binding{
+		{Role: "roles/pubsub.subscriber", Members: []string{"user:test@testdomain.co"}},
+	}}
+
+	service.Subscriptions.SetIamPolicy(subscription.Name, &pubsub.SetIamPolicyRequest{Policy: policy}).Do()
+	got, _ := service.Subscriptions.GetIamPolicy(subscription.Name).Do()
+
+	if !reflect.DeepEqual(got.Bindings, policy.Bindings) {
+		t.Errorf("got %v want %v", got.Bindings, policy.Bindings)
+	}
+}
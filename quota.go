@@ -0,0 +1,171 @@
+package mockgcp
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	googleapi "google.golang.org/api/googleapi"
+)
+
+// Quota is a token bucket limiting the calls made through the mock, the way GCP limits requests per minute.
+// Methods are path.Match patterns for the calls the quota counts, written like Fault methods
+// (Projects.SetIamPolicy, Projects.*), and a quota with no Methods counts every call.  Limit calls are
+// allowed each Period (a minute if it isn't set), refilling evenly over the period, and up to Limit can be
+// made at once.  PerCaller gives each Caller its own bucket, and PerProject gives each project its own
+// bucket, so a quota can be per minute per user, or per project like the limit on project creation
+type Quota struct {
+	Name       string
+	Methods    []string
+	Limit      int
+	Period     time.Duration
+	PerCaller  bool
+	PerProject bool
+}
+
+// matches returns true if the quota counts calls to method
+func (q *Quota) matches(method string) bool {
+	if len(q.Methods) == 0 {
+		return true
+	}
+	for _, pattern := range q.Methods {
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// subject returns the caller and project a call's tokens are taken from, as the QuotaFailure subject
+func (q *Quota) subject(caller, resource string) string {
+	parts := []string{}
+	if q.PerProject {
+		parts = append(parts, projectOf(resource))
+	}
+	if q.PerCaller {
+		parts = append(parts, caller)
+	}
+	if len(parts) == 0 {
+		return "global"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// tokenBucket is the tokens left for a quota and subject, as of last
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// QuotasService holds the quotas set on a MockService.  Calls over quota fail with a 429
+// RESOURCE_EXHAUSTED googleapi.Error carrying a google.rpc.QuotaFailure in its Details, from Do() and
+// through the REST Handler alike.  Buckets refill by the service's Clock, so a FakeClock lets tests check
+// their throttling without waiting
+type QuotasService struct {
+	Service *MockService
+
+	mu       sync.Mutex
+	quotas   []*Quota
+	buckets  map[string]*tokenBucket
+	rejected map[string]int
+}
+
+// NewQuotasService returns a QuotasService with no quotas set
+func NewQuotasService(s *MockService) *QuotasService {
+	rs := &QuotasService{Service: s, buckets: map[string]*tokenBucket{}, rejected: map[string]int{}}
+	return rs
+}
+
+// Set adds a quota, replacing the quota with the same name if there is one.  Its buckets start full
+func (r *QuotasService) Set(quota Quota) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if quota.Period == 0 {
+		quota.Period = time.Minute
+	}
+	for key := range r.buckets {
+		if strings.HasPrefix(key, quota.Name+"|") {
+			delete(r.buckets, key)
+		}
+	}
+	for i, existing := range r.quotas {
+		if existing.Name == quota.Name {
+			r.quotas[i] = &quota
+			return
+		}
+	}
+	r.quotas = append(r.quotas, &quota)
+}
+
+// Clear removes every quota, along with the counts of rejected calls
+func (r *QuotasService) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quotas = nil
+	r.buckets = map[string]*tokenBucket{}
+	r.rejected = map[string]int{}
+}
+
+// Rejected returns how many calls the named quota has turned away
+func (r *QuotasService) Rejected(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rejected[name]
+}
+
+// take takes a token from each quota counting a call to method on resource, and returns the error to
+// fail the call with if any of them is out of tokens.  No tokens are taken from a call that's rejected
+func (r *QuotasService) take(method, resource string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.Service.now()
+	taking := []*tokenBucket{}
+	for _, quota := range r.quotas {
+		if !quota.matches(method) {
+			continue
+		}
+		subject := quota.subject(r.Service.Caller, resource)
+		key := quota.Name + "|" + subject
+		bucket, ok := r.buckets[key]
+		if !ok {
+			bucket = &tokenBucket{tokens: float64(quota.Limit), last: now}
+			r.buckets[key] = bucket
+		}
+		if elapsed := now.Sub(bucket.last); elapsed > 0 {
+			bucket.tokens += float64(quota.Limit) * float64(elapsed) / float64(quota.Period)
+			if bucket.tokens > float64(quota.Limit) {
+				bucket.tokens = float64(quota.Limit)
+			}
+			bucket.last = now
+		}
+		if bucket.tokens < 1 {
+			r.rejected[quota.Name]++
+			return quotaError(quota, subject)
+		}
+		taking = append(taking, bucket)
+	}
+	for _, bucket := range taking {
+		bucket.tokens--
+	}
+	return nil
+}
+
+// quotaError returns the error GCP gives a call over quota
+func quotaError(quota *Quota, subject string) error {
+	description := fmt.Sprintf("Quota exceeded for quota metric '%v' and limit '%v per %v' for consumer '%v'.", quota.Name, quota.Limit, quota.Period, subject)
+	return &googleapi.Error{
+		Code:    http.StatusTooManyRequests,
+		Message: description,
+		Details: []interface{}{
+			map[string]interface{}{
+				"@type": "type.googleapis.com/google.rpc.QuotaFailure",
+				"violations": []interface{}{
+					map[string]interface{}{"subject": subject, "description": description},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,90 @@
+package mockgcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+)
+
+func TestQuotasService(t *testing.T) {
+	clock := NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	newService := func() *MockService {
+		service, _ := NewService(context.TODO())
+		service.Clock = clock
+		service.Projects.NewProject("projects/test-project", "", nil)
+		service.Projects.NewProject("projects/other-project", "", nil)
+		return service
+	}
+	setPolicy := func(service *MockService, project string) error {
+		_, err := service.Projects.SetIamPolicy(project, &cloudresourcemanager.SetIamPolicyRequest{Policy: GeneratePolicy()}).Do()
+		return err
+	}
+
+	t.Run("should reject calls over the limit and refill over the period", func(t *testing.T) {
+		service := newService()
+		service.Quotas.Set(Quota{Name: "WriteRequestsPerMinute", Methods: []string{"Projects.SetIamPolicy"}, Limit: 2})
+		for i := 0; i < 2; i++ {
+			if err := setPolicy(service, "projects/test-project"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := setPolicy(service, "projects/test-project")
+		var apiError *googleapi.Error
+		if !errors.As(err, &apiError) || apiError.Code != 429 {
+			t.Fatalf("got %v want a 429", err)
+		}
+		detail, _ := apiError.Details[0].(map[string]interface{})
+		if detail["@type"] != "type.googleapis.com/google.rpc.QuotaFailure" {
+			t.Errorf("got %v want a QuotaFailure", apiError.Details)
+		}
+		if _, err := service.Projects.GetIamPolicy("projects/test-project", nil).Do(); err != nil {
+			t.Errorf("expected reads to be outside the quota but got %v", err)
+		}
+
+		clock.Advance(30 * time.Second)
+		if err := setPolicy(service, "projects/test-project"); err != nil {
+			t.Errorf("expected a token after half the period but got %v", err)
+		}
+		if err := setPolicy(service, "projects/test-project"); err == nil {
+			t.Errorf("expected only one token after half the period")
+		}
+		if service.Quotas.Rejected("WriteRequestsPerMinute") != 2 {
+			t.Errorf("got %v want 2", service.Quotas.Rejected("WriteRequestsPerMinute"))
+		}
+	})
+	t.Run("should keep a bucket per caller and project", func(t *testing.T) {
+		service := newService()
+		service.Quotas.Set(Quota{Name: "RequestsPerUserPerProject", Methods: []string{"Projects.*"}, Limit: 1, PerCaller: true, PerProject: true})
+		service.Caller = "user:alice@testdomain.co"
+		if err := setPolicy(service, "projects/test-project"); err != nil {
+			t.Fatal(err)
+		}
+		if err := setPolicy(service, "projects/test-project"); err == nil {
+			t.Errorf("expected alice to be over quota on projects/test-project")
+		}
+		if err := setPolicy(service, "projects/other-project"); err != nil {
+			t.Errorf("got %v want nil for another project", err)
+		}
+		service.Caller = "user:bob@testdomain.co"
+		if err := setPolicy(service, "projects/test-project"); err != nil {
+			t.Errorf("got %v want nil for another caller", err)
+		}
+	})
+	t.Run("should send quota failures through the handler", func(t *testing.T) {
+		service := newService()
+		service.Quotas.Set(Quota{Name: "ReadRequestsPerMinute", Methods: []string{"Projects.Get*"}, Limit: 1})
+		client := servedClient(t, service)
+		if _, err := client.Projects.Get("projects/test-project").Do(); err != nil {
+			t.Fatal(err)
+		}
+		_, err := client.Projects.GetIamPolicy("projects/test-project", &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+		var apiError *googleapi.Error
+		if !errors.As(err, &apiError) || apiError.Code != 429 || len(apiError.Details) != 1 {
+			t.Errorf("got %v want a 429 with a QuotaFailure", err)
+		}
+	})
+}
@@ -0,0 +1,357 @@
+package mockgcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+)
+
+// Interaction is a single recorded request and the response GCP gave it.  Only the path and query of the
+// URL are kept, so a cassette recorded against the real endpoint replays against any other.  Request
+// headers aren't kept, so credentials never end up in a cassette
+type Interaction struct {
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	Query        string `json:"query,omitempty"`
+	RequestBody  string `json:"requestBody,omitempty"`
+	StatusCode   int    `json:"statusCode"`
+	ContentType  string `json:"contentType,omitempty"`
+	ResponseBody string `json:"responseBody,omitempty"`
+}
+
+// matches returns true if the interaction was recorded for a request with this method, path, query and body
+func (i *Interaction) matches(method, path, query, body string) bool {
+	return i.Method == method && i.Path == path && i.Query == query && i.RequestBody == body
+}
+
+// Cassette is a list of recorded interactions.  It's saved as newline delimited JSON, one Interaction per
+// line, so recordings diff well and can be edited by hand
+type Cassette struct {
+	Interactions []*Interaction
+
+	mu       sync.Mutex
+	replayed map[int]bool
+}
+
+// LoadCassette reads a cassette saved with Save
+func LoadCassette(path string) (*Cassette, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	cassette := &Cassette{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		interaction := &Interaction{}
+		if err := json.Unmarshal(scanner.Bytes(), interaction); err != nil {
+			return nil, fmt.Errorf("cassette %v line %v invalid: %v", path, line, err)
+		}
+		cassette.Interactions = append(cassette.Interactions, interaction)
+	}
+	return cassette, scanner.Err()
+}
+
+// Save writes the cassette to path
+func (c *Cassette) Save(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var buffer bytes.Buffer
+	for _, interaction := range c.Interactions {
+		line, err := json.Marshal(interaction)
+		if err != nil {
+			return err
+		}
+		buffer.Write(append(line, '\n'))
+	}
+	return os.WriteFile(path, buffer.Bytes(), 0644)
+}
+
+// add appends a recorded interaction
+func (c *Cassette) add(interaction *Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, interaction)
+}
+
+// next returns the interaction to replay for a request.  Matching interactions are replayed in the order
+// they were recorded, and once they've all been replayed the last one keeps being returned, so polling the
+// same resource sees the same changes it did when it was recorded
+func (c *Cassette) next(method, path, query, body string) *Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.replayed == nil {
+		c.replayed = map[int]bool{}
+	}
+	var last *Interaction
+	for i, interaction := range c.Interactions {
+		if !interaction.matches(method, path, query, body) {
+			continue
+		}
+		if !c.replayed[i] {
+			c.replayed[i] = true
+			return interaction
+		}
+		last = interaction
+	}
+	return last
+}
+
+// RecorderMode is whether a Recorder records or replays
+type RecorderMode int
+
+const (
+	// RecordMode sends requests on to GCP and records them in the cassette
+	RecordMode RecorderMode = iota
+	// ReplayMode answers requests from the cassette, and never makes a network call.  The recorded responses
+	// are returned as GCP gave them, errors included, rather than rebuilt by the mock, so a replay sees
+	// fields the mock doesn't model.  To serve a recording through the mock instead, so it can also be
+	// changed, load it with ImportCassette and call the service's Handler
+	ReplayMode
+)
+
+// Recorder is an http.RoundTripper that records the traffic of a google API client into a Cassette, or
+// replays a cassette in place of GCP.  Wrap a client with it using
+// option.WithHTTPClient(&http.Client{Transport: recorder}).  In record mode Transport makes the real
+// calls, and should be the authenticated transport (http.DefaultTransport is used if it's nil)
+type Recorder struct {
+	Mode      RecorderMode
+	Cassette  *Cassette
+	Transport http.RoundTripper
+}
+
+// NewRecorder returns a Recorder in mode over cassette, creating an empty cassette if it's nil
+func NewRecorder(mode RecorderMode, cassette *Cassette, transport http.RoundTripper) *Recorder {
+	if cassette == nil {
+		cassette = &Cassette{}
+	}
+	return &Recorder{Mode: mode, Cassette: cassette, Transport: transport}
+}
+
+// RoundTrip records or replays a single request.  The request's body is read and closed, and in record mode
+// a clone of the request carrying a copy of the body is sent on, so req itself is never changed
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	raw, body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	query := normalizeQuery(req.URL.Query())
+	if r.Mode == ReplayMode {
+		interaction := r.Cassette.next(req.Method, req.URL.Path, query, body)
+		if interaction == nil {
+			return nil, fmt.Errorf("no recorded interaction for %v %v", req.Method, req.URL.Path)
+		}
+		return interaction.response(req), nil
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	outgoing := req.Clone(req.Context())
+	if raw != nil {
+		outgoing.Body = io.NopCloser(bytes.NewReader(raw))
+		outgoing.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(raw)), nil
+		}
+	}
+	resp, err := transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	interaction := &Interaction{
+		Method:       req.Method,
+		Path:         req.URL.Path,
+		Query:        query,
+		RequestBody:  body,
+		StatusCode:   resp.StatusCode,
+		ContentType:  resp.Header.Get("Content-Type"),
+		ResponseBody: string(responseBody),
+	}
+	r.Cassette.add(interaction)
+	return interaction.response(req), nil
+}
+
+// response builds the http response for a recorded interaction
+func (i *Interaction) response(req *http.Request) *http.Response {
+	header := http.Header{}
+	if i.ContentType != "" {
+		header.Set("Content-Type", i.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %v", i.StatusCode, http.StatusText(i.StatusCode)),
+		StatusCode:    i.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(i.ResponseBody)),
+		ContentLength: int64(len(i.ResponseBody)),
+		Request:       req,
+	}
+}
+
+// readRequestBody reads and closes a request's body.  It returns the raw body for the transport, and the
+// body compacted if it's JSON so formatting differences don't stop a replay matching
+func readRequestBody(req *http.Request) ([]byte, string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, "", nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	var compact bytes.Buffer
+	if json.Compact(&compact, body) == nil {
+		return body, compact.String(), nil
+	}
+	return body, string(body), nil
+}
+
+// normalizeQuery returns the query string with its parameters sorted.  The alt and prettyPrint parameters
+// the google clients add to every call are dropped
+func normalizeQuery(query url.Values) string {
+	query.Del("alt")
+	query.Del("prettyPrint")
+	return query.Encode()
+}
+
+// crmRecordedPath matches the resource manager calls ImportCassette understands
+var crmRecordedPath = regexp.MustCompile(`^/v[13]/((?:projects|folders|organizations)/[^/:]+)(:getIamPolicy)?$`)
+
+// crmRecordedSearchPath matches the resource manager searches ImportCassette understands
+var crmRecordedSearchPath = regexp.MustCompile(`^/v3/(?:projects|folders|organizations):search$`)
+
+// recordedResource is the part of a recorded project, folder or organization ImportCassette reads
+type recordedResource struct {
+	Name        string `json:"name"`
+	ProjectID   string `json:"projectId"`
+	DisplayName string `json:"displayName"`
+	Parent      string `json:"parent"`
+}
+
+// ImportCassette loads the resource manager projects, folders and organizations recorded in a cassette into
+// the service, so a recording of the real org can be served back through the mock.  Successful Get calls
+// create the resource with its display name and parent, as do the results of successful Search calls, and
+// successful GetIamPolicy calls set its policy.  Other interactions are skipped
+func (s *MockService) ImportCassette(cassette *Cassette) error {
+	for _, interaction := range cassette.Interactions {
+		if crmRecordedSearchPath.MatchString(interaction.Path) && interaction.StatusCode == http.StatusOK {
+			results := struct {
+				Projects      []recordedResource `json:"projects"`
+				Folders       []recordedResource `json:"folders"`
+				Organizations []recordedResource `json:"organizations"`
+			}{}
+			if err := json.Unmarshal([]byte(interaction.ResponseBody), &results); err != nil {
+				return fmt.Errorf("recorded search %v invalid: %v", interaction.Path, err)
+			}
+			for _, resource := range append(append(results.Organizations, results.Folders...), results.Projects...) {
+				s.importRecorded(resource)
+			}
+			continue
+		}
+		match := crmRecordedPath.FindStringSubmatch(interaction.Path)
+		if match == nil || interaction.StatusCode != http.StatusOK {
+			continue
+		}
+		name := match[1]
+		if match[2] != "" {
+			policy := &cloudresourcemanager.Policy{}
+			if err := json.Unmarshal([]byte(interaction.ResponseBody), policy); err != nil {
+				return fmt.Errorf("recorded policy for %v invalid: %v", name, err)
+			}
+			s.importResource(name, "", "").SetIamPolicy(policy)
+			continue
+		}
+		if interaction.Method != http.MethodGet {
+			continue
+		}
+		resource := recordedResource{}
+		if err := json.Unmarshal([]byte(interaction.ResponseBody), &resource); err != nil {
+			return fmt.Errorf("recorded resource %v invalid: %v", name, err)
+		}
+		if resource.Name == "" {
+			resource.Name = name
+		}
+		s.importRecorded(resource)
+	}
+	return nil
+}
+
+// importRecorded imports a recorded resource, naming projects by their ID the way the mock does
+func (s *MockService) importRecorded(resource recordedResource) {
+	name := resource.Name
+	if resource.ProjectID != "" {
+		name = "projects/" + resource.ProjectID
+	}
+	if name == "" {
+		return
+	}
+	s.importResource(name, resource.DisplayName, resource.Parent)
+}
+
+// importResource finds the project, folder or organization called name, creating it if it doesn't exist,
+// and fills in the display name and parent if they're given.  It returns the resource's policy holder
+func (s *MockService) importResource(name, displayName, parent string) *IamPolicyHolder {
+	switch {
+	case strings.HasPrefix(name, "projects/"):
+		project := s.Projects.get(name)
+		if project == nil {
+			project = s.Projects.NewProject(name, "", nil)
+		}
+		if displayName != "" {
+			project.DisplayName = displayName
+		}
+		if parent != "" {
+			project.Parent = parent
+		}
+		return &project.IamPolicyHolder
+	case strings.HasPrefix(name, "folders/"):
+		var folder *Folder
+		for _, f := range s.Folders.FolderList {
+			if f.FolderID == name {
+				folder = f
+			}
+		}
+		if folder == nil {
+			folder = s.Folders.NewFolder(name, "", nil)
+		}
+		if displayName != "" {
+			folder.DisplayName = displayName
+		}
+		if parent != "" {
+			folder.Parent = parent
+		}
+		return &folder.IamPolicyHolder
+	default:
+		for _, organization := range s.Organizations.OrganizationList {
+			if organization.OrganizationID == name {
+				if displayName != "" {
+					organization.Domain = displayName
+				}
+				return &organization.IamPolicyHolder
+			}
+		}
+		return &s.Organizations.NewOrganization(name, displayName, nil).IamPolicyHolder
+	}
+}
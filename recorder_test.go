@@ -0,0 +1,189 @@
+package mockgcp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/option"
+)
+
+// fakeResourceManager stands in for the real resource manager API when recording
+func fakeResourceManager(t *testing.T) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		switch r.URL.Path {
+		case "/v3/projects/test-project":
+			w.Write([]byte(`{"name":"projects/123","projectId":"test-project","displayName":"Test Project","parent":"folders/2"}`))
+		case "/v3/projects/test-project:getIamPolicy":
+			w.Write([]byte(`{"version":1,"etag":"BwX=","bindings":[{"role":"roles/owner","members":["user:alice@testdomain.co"]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"not found","status":"NOT_FOUND"}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// recordedClient returns a resource manager client going through recorder
+func recordedClient(t *testing.T, recorder *Recorder, endpoint string) *cloudresourcemanager.Service {
+	client, err := cloudresourcemanager.NewService(context.TODO(),
+		option.WithHTTPClient(&http.Client{Transport: recorder}),
+		option.WithEndpoint(endpoint))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestRecorder(t *testing.T) {
+	server, calls := fakeResourceManager(t)
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+
+	recorder := NewRecorder(RecordMode, nil, nil)
+	client := recordedClient(t, recorder, server.URL+"/")
+	if _, err := client.Projects.Get("projects/test-project").Do(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Projects.GetIamPolicy("projects/test-project", &cloudresourcemanager.GetIamPolicyRequest{}).Do(); err != nil {
+		t.Fatal(err)
+	}
+	client.Projects.Get("projects/missing-project").Do()
+	if err := recorder.Cassette.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cassette.Interactions) != 3 {
+		t.Fatalf("expected 3 interactions but got %v", len(cassette.Interactions))
+	}
+
+	t.Run("should replay recorded responses without calling GCP", func(t *testing.T) {
+		recorded := *calls
+		client := recordedClient(t, NewRecorder(ReplayMode, cassette, nil), "https://cloudresourcemanager.googleapis.com/")
+		project, err := client.Projects.Get("projects/test-project").Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.DisplayName != "Test Project" {
+			t.Errorf("got %v want %v", project.DisplayName, "Test Project")
+		}
+		policy, err := client.Projects.GetIamPolicy("projects/test-project", &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !BindingContains(PolicyContains(policy, "roles/owner"), "user:alice@testdomain.co") {
+			t.Errorf("expected alice to be an owner in %v", policy.Bindings)
+		}
+		if _, err := client.Projects.Get("projects/missing-project").Do(); err == nil {
+			t.Errorf("expected the recorded 404 to be an error")
+		}
+		if *calls != recorded {
+			t.Errorf("expected no calls to GCP but got %v", *calls-recorded)
+		}
+	})
+	t.Run("should error on a request that wasn't recorded", func(t *testing.T) {
+		client := recordedClient(t, NewRecorder(ReplayMode, cassette, nil), "https://cloudresourcemanager.googleapis.com/")
+		if _, err := client.Folders.Get("folders/2").Do(); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+	t.Run("should import recorded resources into the mock", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		if err := service.ImportCassette(cassette); err != nil {
+			t.Fatal(err)
+		}
+		project := service.Projects.get("projects/test-project")
+		if project == nil {
+			t.Fatalf("expected projects/test-project to be imported")
+		}
+		if project.DisplayName != "Test Project" || project.Parent != "folders/2" {
+			t.Errorf("got %v %v", project.DisplayName, project.Parent)
+		}
+		policy, _ := service.Projects.GetIamPolicy("projects/test-project", &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+		if !BindingContains(PolicyContains(policy, "roles/owner"), "user:alice@testdomain.co") {
+			t.Errorf("expected alice to be an owner in %v", policy.Bindings)
+		}
+		if len(service.Projects.ProjectList) != 1 {
+			t.Errorf("got %v projects want %v", len(service.Projects.ProjectList), 1)
+		}
+	})
+	t.Run("should serve an imported cassette through the mock", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		if err := service.ImportCassette(cassette); err != nil {
+			t.Fatal(err)
+		}
+		client := servedClient(t, service)
+		policy, err := client.Projects.GetIamPolicy("projects/test-project", &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		policy.Bindings = append(policy.Bindings, NewBinding("roles/viewer", "user:bob@testdomain.co"))
+		if _, err := client.Projects.SetIamPolicy("projects/test-project", &cloudresourcemanager.SetIamPolicyRequest{Policy: policy}).Do(); err != nil {
+			t.Fatal(err)
+		}
+		policy, _ = client.Projects.GetIamPolicy("projects/test-project", &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+		if !BindingContains(PolicyContains(policy, "roles/viewer"), "user:bob@testdomain.co") {
+			t.Errorf("expected bob to be a viewer in %v", policy.Bindings)
+		}
+	})
+	t.Run("should not change the request it records", func(t *testing.T) {
+		var sent string
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			sent = string(body)
+			w.Write([]byte(`{}`))
+		}))
+		t.Cleanup(upstream.Close)
+		body := io.NopCloser(strings.NewReader(`{"policy": {}}`))
+		req, _ := http.NewRequest(http.MethodPost, upstream.URL+"/v3/projects/test-project:setIamPolicy", body)
+
+		recorder := NewRecorder(RecordMode, nil, nil)
+		resp, err := recorder.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if req.Body != body {
+			t.Errorf("expected the request body to be left in place")
+		}
+		if sent != `{"policy": {}}` {
+			t.Errorf("got %v want the request body sent upstream", sent)
+		}
+		if got := recorder.Cassette.Interactions[0].RequestBody; got != `{"policy":{}}` {
+			t.Errorf("got %v want %v", got, `{"policy":{}}`)
+		}
+	})
+}
+
+func TestMockService_ImportCassette_search(t *testing.T) {
+	cassette := &Cassette{Interactions: []*Interaction{
+		{Method: "GET", Path: "/v3/folders:search", Query: "query=parent%3Aorganizations%2F1", StatusCode: 200,
+			ResponseBody: `{"folders":[{"name":"folders/2","displayName":"Test Folder","parent":"organizations/1"}],"nextPageToken":"next"}`},
+		{Method: "GET", Path: "/v3/projects:search", StatusCode: 200,
+			ResponseBody: `{"projects":[{"name":"projects/123","projectId":"test-project","displayName":"Test Project","parent":"folders/2"}]}`},
+	}}
+	service, _ := NewService(context.TODO())
+	if err := service.ImportCassette(cassette); err != nil {
+		t.Fatal(err)
+	}
+	if len(service.Folders.FolderList) != 1 || service.Folders.FolderList[0].Parent != "organizations/1" {
+		t.Errorf("got %v want folders/2 under organizations/1", service.Folders.FolderList)
+	}
+	project := service.Projects.get("projects/test-project")
+	if project == nil || project.DisplayName != "Test Project" || project.Parent != "folders/2" {
+		t.Errorf("got %v want test-project under folders/2", project)
+	}
+}
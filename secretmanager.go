@@ -0,0 +1,649 @@
+package mockgcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+	secretmanager "google.golang.org/api/secretmanager/v1"
+)
+
+var (
+	secretNameFormat        = regexp.MustCompile(`^projects/[^/]+/secrets/[-a-zA-Z0-9_]{1,255}$`)
+	secretVersionNameFormat = regexp.MustCompile(`^(projects/[^/]+/secrets/[^/]+)/versions/([^/]+)$`)
+)
+
+// Secret is a mock of a google cloud Secret Manager Secret, which belongs to a Project and holds its versions
+type Secret struct {
+	IamPolicyHolder
+	Name       string
+	ProjectID  string
+	Labels     map[string]string
+	CreateTime time.Time
+	Versions   []*SecretVersion
+
+	service *MockService
+}
+
+// SecretVersion is a mock of a version of a Secret Manager Secret.  State is one of ENABLED, DISABLED or
+// DESTROYED, and Data is the base64 encoded payload
+type SecretVersion struct {
+	Name        string
+	State       string
+	Data        string
+	CreateTime  time.Time
+	DestroyTime time.Time
+}
+
+// toAPI converts the mock secret to the type returned by the Secret Manager API
+func (s *Secret) toAPI() *secretmanager.Secret {
+	return &secretmanager.Secret{
+		Name:        s.Name,
+		Labels:      copyLabels(s.Labels),
+		CreateTime:  s.CreateTime.Format(time.RFC3339Nano),
+		Replication: &secretmanager.Replication{Automatic: &secretmanager.Automatic{}},
+	}
+}
+
+// toAPI converts the mock secret version to the type returned by the Secret Manager API
+func (v *SecretVersion) toAPI() *secretmanager.SecretVersion {
+	version := &secretmanager.SecretVersion{
+		Name:       v.Name,
+		State:      v.State,
+		CreateTime: v.CreateTime.Format(time.RFC3339Nano),
+	}
+	if v.State == "DESTROYED" {
+		version.DestroyTime = v.DestroyTime.Format(time.RFC3339Nano)
+	}
+	return version
+}
+
+// SecretsService is a mock of google Cloud Secret Manager's projects.secrets Service
+type SecretsService struct {
+	Service  *MockService
+	Versions *SecretsVersionsService
+}
+
+// NewSecretsService will return a new Secrets Service
+func NewSecretsService(s *MockService) *SecretsService {
+	rs := &SecretsService{Service: s}
+	rs.Versions = &SecretsVersionsService{Service: s}
+	return rs
+}
+
+// NewSecret creates a new secret with the specified ID and policy on the project and returns a pointer to the
+// created secret.  If policy isn't specified it will generate a blank one.  It returns nil if the project
+// doesn't exist in the Projects Service
+func (r *SecretsService) NewSecret(projectID, secretID string, policy *cloudresourcemanager.Policy) *Secret {
+	project := r.Service.Projects.get(projectResourceName(projectID))
+	if project == nil {
+		return nil
+	}
+	if policy == nil {
+		policy = &cloudresourcemanager.Policy{}
+	}
+	secret := &Secret{
+		IamPolicyHolder: IamPolicyHolder{Policy: policy},
+		Name:            fmt.Sprintf("%v/secrets/%v", project.ProjectID, secretID),
+		ProjectID:       project.ProjectID,
+		CreateTime:      r.Service.now().UTC(),
+		service:         r.Service,
+	}
+	project.Secrets = append(project.Secrets, secret)
+	r.Service.publishNewAsset(&secret.IamPolicyHolder)
+	return secret
+}
+
+// AddVersion adds a new enabled version holding data (base64 encoded) to the secret, and returns it
+func (s *Secret) AddVersion(data string) *SecretVersion {
+	version := &SecretVersion{
+		Name:       fmt.Sprintf("%v/versions/%d", s.Name, len(s.Versions)+1),
+		State:      "ENABLED",
+		Data:       data,
+		CreateTime: s.service.now().UTC(),
+	}
+	s.Versions = append(s.Versions, version)
+	return version
+}
+
+// find returns the secret with the given resource name, or nil if there isn't one
+func (r *SecretsService) find(name string) *Secret {
+	project := r.Service.Projects.get(projectOf(name))
+	if project == nil {
+		return nil
+	}
+	for _, secret := range project.Secrets {
+		if secret.Name == name {
+			return secret
+		}
+	}
+	return nil
+}
+
+// findVersion returns the secret version with the given resource name, or nil if there isn't one.  The version
+// can be "latest", which is the newest version that isn't destroyed or disabled
+func (r *SecretsService) findVersion(name string) *SecretVersion {
+	match := secretVersionNameFormat.FindStringSubmatch(name)
+	if match == nil {
+		return nil
+	}
+	secret := r.find(match[1])
+	if secret == nil {
+		return nil
+	}
+	if match[2] == "latest" {
+		for i := len(secret.Versions) - 1; i >= 0; i-- {
+			if secret.Versions[i].State == "ENABLED" {
+				return secret.Versions[i]
+			}
+		}
+		return nil
+	}
+	for _, version := range secret.Versions {
+		if version.Name == name {
+			return version
+		}
+	}
+	return nil
+}
+
+// policyHolder returns the policy holder for the secret, or nil if there isn't one
+func (r *SecretsService) policyHolder(resource string) *IamPolicyHolder {
+	secret := r.find(resource)
+	if secret == nil {
+		return nil
+	}
+	return &secret.IamPolicyHolder
+}
+
+// Create will take a project resource name and a secret and returns a Create Call, so we can run a Do() method
+// on it.  The secret's ID is set on the call with SecretId()
+func (r *SecretsService) Create(parent string, secret *secretmanager.Secret) *SecretsCreateCall {
+	return &SecretsCreateCall{Service: r.Service, Parent: parent, Secret: secret}
+}
+
+// SecretsCreateCall is a structure that is returned by Secrets.Create.  Then we call Do() on it to create the secret
+type SecretsCreateCall struct {
+	Service  *MockService
+	Parent   string
+	Secret   *secretmanager.Secret
+	secretID string
+	ctx      context.Context
+}
+
+// SecretId sets the ID of the secret to create
+func (c *SecretsCreateCall) SecretId(secretId string) *SecretsCreateCall {
+	c.secretID = secretId
+	return c
+}
+
+// Do will be called on SecretsCreateCall to create the secret and return it
+func (c *SecretsCreateCall) Do(opts ...googleapi.CallOption) (result *secretmanager.Secret, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.Create", c.Parent, c.Secret)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if c.Service.Projects.get(c.Parent) == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Parent)
+	}
+	name := fmt.Sprintf("%v/secrets/%v", c.Parent, c.secretID)
+	if !secretNameFormat.MatchString(name) {
+		return nil, fmt.Errorf("invalid secret id: %v", c.secretID)
+	}
+	if c.Service.Secrets.find(name) != nil {
+		return nil, fmt.Errorf("%v: %v", resourceAlreadyExistsError, name)
+	}
+	secret := c.Service.Secrets.NewSecret(c.Parent, c.secretID, nil)
+	if c.Secret != nil {
+		secret.Labels = copyLabels(c.Secret.Labels)
+	}
+	c.Service.AuditLogs.recordMutation("secretmanager.googleapis.com", "google.cloud.secretmanager.v1.SecretManagerService.CreateSecret", secret.Name)
+	return secret.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *SecretsCreateCall) Context(ctx context.Context) *SecretsCreateCall {
+	c.ctx = ctx
+	return c
+}
+
+// Get will take a secret resource name and returns a Get Call, so we can run a Do() method on it.
+func (r *SecretsService) Get(name string) *SecretsGetCall {
+	return &SecretsGetCall{Service: r.Service, Name: name}
+}
+
+// SecretsGetCall is a structure that is returned by Secrets.Get.  Then we call Do() on it to return the secret
+type SecretsGetCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on SecretsGetCall and return the secret found
+func (c *SecretsGetCall) Do(opts ...googleapi.CallOption) (result *secretmanager.Secret, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.Get", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	secret := c.Service.Secrets.find(c.Name)
+	if secret == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	return secret.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *SecretsGetCall) Context(ctx context.Context) *SecretsGetCall {
+	c.ctx = ctx
+	return c
+}
+
+// List will take a project resource name and returns a List Call, so we can run a Do() method on it.
+func (r *SecretsService) List(parent string) *SecretsListCall {
+	return &SecretsListCall{Service: r.Service, Parent: parent}
+}
+
+// SecretsListCall is a structure that is returned by Secrets.List.  Then we call Do() on it to return the
+// project's secrets
+type SecretsListCall struct {
+	Service *MockService
+	Parent  string
+	ctx     context.Context
+}
+
+// Do will be called on SecretsListCall and return the secrets in the project
+func (c *SecretsListCall) Do(opts ...googleapi.CallOption) (result *secretmanager.ListSecretsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.List", c.Parent, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	project := c.Service.Projects.get(c.Parent)
+	if project == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Parent)
+	}
+	response := &secretmanager.ListSecretsResponse{}
+	for _, secret := range project.Secrets {
+		response.Secrets = append(response.Secrets, secret.toAPI())
+	}
+	response.TotalSize = int64(len(response.Secrets))
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *SecretsListCall) Context(ctx context.Context) *SecretsListCall {
+	c.ctx = ctx
+	return c
+}
+
+// Delete will take a secret resource name and returns a Delete Call, so we can run a Do() method on it.
+func (r *SecretsService) Delete(name string) *SecretsDeleteCall {
+	return &SecretsDeleteCall{Service: r.Service, Name: name}
+}
+
+// SecretsDeleteCall is a structure that is returned by Secrets.Delete.  Then we call Do() on it to delete
+// the secret and all of its versions
+type SecretsDeleteCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on SecretsDeleteCall to delete the secret
+func (c *SecretsDeleteCall) Do(opts ...googleapi.CallOption) (result *secretmanager.Empty, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.Delete", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	project := c.Service.Projects.get(projectOf(c.Name))
+	if project == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	for i, secret := range project.Secrets {
+		if secret.Name == c.Name {
+			prior := c.Service.assetSnapshot(&secret.IamPolicyHolder)
+			project.Secrets = append(project.Secrets[:i], project.Secrets[i+1:]...)
+			c.Service.AuditLogs.recordMutation("secretmanager.googleapis.com", "google.cloud.secretmanager.v1.SecretManagerService.DeleteSecret", secret.Name)
+			c.Service.publishAssetChange(prior, nil)
+			return &secretmanager.Empty{}, nil
+		}
+	}
+	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+}
+
+// Context sets the context used by Do
+func (c *SecretsDeleteCall) Context(ctx context.Context) *SecretsDeleteCall {
+	c.ctx = ctx
+	return c
+}
+
+// AddVersion will take a secret resource name and an addsecretversionrequest and returns an AddVersion Call,
+// so we can run a Do() method on it.
+func (r *SecretsService) AddVersion(parent string, addsecretversionrequest *secretmanager.AddSecretVersionRequest) *SecretsAddVersionCall {
+	return &SecretsAddVersionCall{Service: r.Service, Parent: parent, Addsecretversionrequest: addsecretversionrequest}
+}
+
+// SecretsAddVersionCall is a structure that is returned by Secrets.AddVersion.  Then we call Do() on it to add
+// the version to the secret
+type SecretsAddVersionCall struct {
+	Service                 *MockService
+	Parent                  string
+	Addsecretversionrequest *secretmanager.AddSecretVersionRequest
+	ctx                     context.Context
+}
+
+// Do will be called on SecretsAddVersionCall to add the version and return it
+func (c *SecretsAddVersionCall) Do(opts ...googleapi.CallOption) (result *secretmanager.SecretVersion, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.AddVersion", c.Parent, c.Addsecretversionrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	secret := c.Service.Secrets.find(c.Parent)
+	if secret == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Parent)
+	}
+	if c.Addsecretversionrequest == nil || c.Addsecretversionrequest.Payload == nil {
+		return nil, fmt.Errorf("payload is required")
+	}
+	version := secret.AddVersion(c.Addsecretversionrequest.Payload.Data)
+	c.Service.AuditLogs.recordMutation("secretmanager.googleapis.com", "google.cloud.secretmanager.v1.SecretManagerService.AddSecretVersion", version.Name)
+	return version.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *SecretsAddVersionCall) Context(ctx context.Context) *SecretsAddVersionCall {
+	c.ctx = ctx
+	return c
+}
+
+// GetIamPolicy will take a secret resource name and returns a GetIamPolicy Call, so we can run a Do() method on it.
+func (r *SecretsService) GetIamPolicy(resource string) *SecretsGetIamPolicyCall {
+	return &SecretsGetIamPolicyCall{Service: r.Service, Resource: resource}
+}
+
+// SecretsGetIamPolicyCall is a structure that is returned by Secrets.GetIamPolicy.  Then we call Do() on it
+// to actually return the policy
+type SecretsGetIamPolicyCall struct {
+	Service  *MockService
+	Resource string
+	ctx      context.Context
+}
+
+// Do will be called on SecretsGetIamPolicyCall and return the policy found
+func (c *SecretsGetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *secretmanager.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.GetIamPolicy", c.Resource, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	policy := &secretmanager.Policy{}
+	if err := getAPIIamPolicy(policy, c.Resource, secretNameFormat, c.Service.Secrets.policyHolder); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Context sets the context used by Do
+func (c *SecretsGetIamPolicyCall) Context(ctx context.Context) *SecretsGetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// SetIamPolicy will take a secret resource name and a setiampolicyrequest and returns a SetIamPolicy Call,
+// so we can run a Do() method on it.
+func (r *SecretsService) SetIamPolicy(resource string, setiampolicyrequest *secretmanager.SetIamPolicyRequest) *SecretsSetIamPolicyCall {
+	return &SecretsSetIamPolicyCall{Service: r.Service, Resource: resource, Setiampolicyrequest: setiampolicyrequest}
+}
+
+// SecretsSetIamPolicyCall is a structure that is returned by Secrets.SetIamPolicy.  Then we call Do() on it to
+// Set the Secret Policy
+type SecretsSetIamPolicyCall struct {
+	Service             *MockService
+	Resource            string
+	Setiampolicyrequest *secretmanager.SetIamPolicyRequest
+	ctx                 context.Context
+}
+
+// Do will be called on SecretsSetIamPolicyCall to process the policy change and returns the policy it sets
+func (c *SecretsSetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *secretmanager.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.SetIamPolicy", c.Resource, c.Setiampolicyrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	policy := &secretmanager.Policy{}
+	if err := setAPIIamPolicy(c.Service, policy, c.Setiampolicyrequest, c.Resource, secretNameFormat, c.Service.Secrets.policyHolder); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Context sets the context used by Do
+func (c *SecretsSetIamPolicyCall) Context(ctx context.Context) *SecretsSetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// TestIamPermissions will take a secret resource name and a testiampermissionsrequest and returns a
+// TestIamPermissions Call, so we can run a Do() method on it.
+func (r *SecretsService) TestIamPermissions(resource string, testiampermissionsrequest *secretmanager.TestIamPermissionsRequest) *SecretsTestIamPermissionsCall {
+	return &SecretsTestIamPermissionsCall{Service: r.Service, Resource: resource, Testiampermissionsrequest: testiampermissionsrequest}
+}
+
+// SecretsTestIamPermissionsCall is a structure that is returned by Secrets.TestIamPermissions.  Then we call
+// Do() on it to find which of the permissions the Caller has
+type SecretsTestIamPermissionsCall struct {
+	Service                   *MockService
+	Resource                  string
+	Testiampermissionsrequest *secretmanager.TestIamPermissionsRequest
+	ctx                       context.Context
+}
+
+// Do will be called on SecretsTestIamPermissionsCall and return the permissions the Caller has on the secret
+func (c *SecretsTestIamPermissionsCall) Do(opts ...googleapi.CallOption) (result *secretmanager.TestIamPermissionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.TestIamPermissions", c.Resource, c.Testiampermissionsrequest)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	response := &secretmanager.TestIamPermissionsResponse{}
+	if err := testAPIIamPermissions(c.Service, response, c.Testiampermissionsrequest, c.Resource, secretNameFormat, c.Service.Secrets.policyHolder); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *SecretsTestIamPermissionsCall) Context(ctx context.Context) *SecretsTestIamPermissionsCall {
+	c.ctx = ctx
+	return c
+}
+
+// SecretsVersionsService is a mock of google Cloud Secret Manager's projects.secrets.versions Service
+type SecretsVersionsService struct {
+	Service *MockService
+}
+
+// Get will take a secret version resource name and returns a Get Call, so we can run a Do() method on it.
+func (r *SecretsVersionsService) Get(name string) *SecretsVersionsGetCall {
+	return &SecretsVersionsGetCall{Service: r.Service, Name: name}
+}
+
+// SecretsVersionsGetCall is a structure that is returned by Secrets.Versions.Get.  Then we call Do() on it to
+// return the version
+type SecretsVersionsGetCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on SecretsVersionsGetCall and return the version found
+func (c *SecretsVersionsGetCall) Do(opts ...googleapi.CallOption) (result *secretmanager.SecretVersion, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.Versions.Get", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	version := c.Service.Secrets.findVersion(c.Name)
+	if version == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	return version.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *SecretsVersionsGetCall) Context(ctx context.Context) *SecretsVersionsGetCall {
+	c.ctx = ctx
+	return c
+}
+
+// List will take a secret resource name and returns a List Call, so we can run a Do() method on it.
+func (r *SecretsVersionsService) List(parent string) *SecretsVersionsListCall {
+	return &SecretsVersionsListCall{Service: r.Service, Parent: parent}
+}
+
+// SecretsVersionsListCall is a structure that is returned by Secrets.Versions.List.  Then we call Do() on it
+// to return the secret's versions
+type SecretsVersionsListCall struct {
+	Service *MockService
+	Parent  string
+	ctx     context.Context
+}
+
+// Do will be called on SecretsVersionsListCall and return the secret's versions, newest first
+func (c *SecretsVersionsListCall) Do(opts ...googleapi.CallOption) (result *secretmanager.ListSecretVersionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.Versions.List", c.Parent, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	secret := c.Service.Secrets.find(c.Parent)
+	if secret == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Parent)
+	}
+	response := &secretmanager.ListSecretVersionsResponse{TotalSize: int64(len(secret.Versions))}
+	for i := len(secret.Versions) - 1; i >= 0; i-- {
+		response.Versions = append(response.Versions, secret.Versions[i].toAPI())
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *SecretsVersionsListCall) Context(ctx context.Context) *SecretsVersionsListCall {
+	c.ctx = ctx
+	return c
+}
+
+// Access will take a secret version resource name and returns an Access Call, so we can run a Do() method on it.
+func (r *SecretsVersionsService) Access(name string) *SecretsVersionsAccessCall {
+	return &SecretsVersionsAccessCall{Service: r.Service, Name: name}
+}
+
+// SecretsVersionsAccessCall is a structure that is returned by Secrets.Versions.Access.  Then we call Do() on
+// it to return the version's payload
+type SecretsVersionsAccessCall struct {
+	Service *MockService
+	Name    string
+	ctx     context.Context
+}
+
+// Do will be called on SecretsVersionsAccessCall and return the payload.  Only enabled versions can be accessed
+func (c *SecretsVersionsAccessCall) Do(opts ...googleapi.CallOption) (result *secretmanager.AccessSecretVersionResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.Versions.Access", c.Name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	version := c.Service.Secrets.findVersion(c.Name)
+	if version == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	if version.State != "ENABLED" {
+		return nil, fmt.Errorf("secret version %v is in %v state", version.Name, version.State)
+	}
+	return &secretmanager.AccessSecretVersionResponse{
+		Name:    version.Name,
+		Payload: &secretmanager.SecretPayload{Data: version.Data},
+	}, nil
+}
+
+// Context sets the context used by Do
+func (c *SecretsVersionsAccessCall) Context(ctx context.Context) *SecretsVersionsAccessCall {
+	c.ctx = ctx
+	return c
+}
+
+// Disable will take a secret version resource name and a disablesecretversionrequest and returns a Disable
+// Call, so we can run a Do() method on it.
+func (r *SecretsVersionsService) Disable(name string, disablesecretversionrequest *secretmanager.DisableSecretVersionRequest) *SecretsVersionsStateCall {
+	return &SecretsVersionsStateCall{Service: r.Service, Name: name, State: "DISABLED"}
+}
+
+// Enable will take a secret version resource name and an enablesecretversionrequest and returns an Enable
+// Call, so we can run a Do() method on it.
+func (r *SecretsVersionsService) Enable(name string, enablesecretversionrequest *secretmanager.EnableSecretVersionRequest) *SecretsVersionsStateCall {
+	return &SecretsVersionsStateCall{Service: r.Service, Name: name, State: "ENABLED"}
+}
+
+// Destroy will take a secret version resource name and a destroysecretversionrequest and returns a Destroy
+// Call, so we can run a Do() method on it.
+func (r *SecretsVersionsService) Destroy(name string, destroysecretversionrequest *secretmanager.DestroySecretVersionRequest) *SecretsVersionsStateCall {
+	return &SecretsVersionsStateCall{Service: r.Service, Name: name, State: "DESTROYED"}
+}
+
+// SecretsVersionsStateCall is a structure that is returned by Secrets.Versions.Disable, Enable and Destroy.
+// Then we call Do() on it to move the version to State
+type SecretsVersionsStateCall struct {
+	Service *MockService
+	Name    string
+	State   string
+	ctx     context.Context
+}
+
+// versionStateMethods is the API method that moves a secret version into each state, for audit logs
+var versionStateMethods = map[string]string{
+	"ENABLED":   "EnableSecretVersion",
+	"DISABLED":  "DisableSecretVersion",
+	"DESTROYED": "DestroySecretVersion",
+}
+
+// Do will be called on SecretsVersionsStateCall to change the version's state and return it.  Destroyed
+// versions lose their payload, and can't be enabled or disabled again
+func (c *SecretsVersionsStateCall) Do(opts ...googleapi.CallOption) (result *secretmanager.SecretVersion, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Secrets.Versions."+strings.TrimSuffix(versionStateMethods[c.State], "SecretVersion"), c.Name, c.State)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(c.Name, "/latest") {
+		return nil, fmt.Errorf("resource format invalid")
+	}
+	version := c.Service.Secrets.findVersion(c.Name)
+	if version == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Name)
+	}
+	if version.State == "DESTROYED" {
+		return nil, fmt.Errorf("secret version %v is in %v state", version.Name, version.State)
+	}
+	version.State = c.State
+	if c.State == "DESTROYED" {
+		version.Data = ""
+		version.DestroyTime = c.Service.now().UTC()
+	}
+	c.Service.AuditLogs.recordMutation("secretmanager.googleapis.com", "google.cloud.secretmanager.v1.SecretManagerService."+versionStateMethods[c.State], version.Name)
+	return version.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *SecretsVersionsStateCall) Context(ctx context.Context) *SecretsVersionsStateCall {
+	c.ctx = ctx
+	return c
+}
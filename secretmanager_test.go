@@ -0,0 +1,118 @@
+package mockgcp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	secretmanager "google.golang.org/api/secretmanager/v1"
+)
+
+func TestSecrets_Create_Do(t *testing.T) {
+	t.Run("should create secret on project", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+
+		_, err := service.Secrets.Create("projects/test-project", &secretmanager.Secret{}).SecretId("test-secret").Do()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		want := "projects/test-project/secrets/test-secret"
+		got, _ := service.Secrets.Get(want).Do()
+
+		if got == nil || got.Name != want {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+	t.Run("should return err if secret already exists", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+		service.Secrets.NewSecret("test-project", "test-secret", nil)
+
+		_, err := service.Secrets.Create("projects/test-project", &secretmanager.Secret{}).SecretId("test-secret").Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestSecretsVersions_Access_Do(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject("projects/test-project", "", nil)
+	secret := service.Secrets.NewSecret("test-project", "test-secret", nil)
+	service.Secrets.AddVersion(secret.Name, &secretmanager.AddSecretVersionRequest{Payload: &secretmanager.SecretPayload{Data: "djE="}}).Do()
+	service.Secrets.AddVersion(secret.Name, &secretmanager.AddSecretVersionRequest{Payload: &secretmanager.SecretPayload{Data: "djI="}}).Do()
+
+	t.Run("should access latest enabled version", func(t *testing.T) {
+		want := "djI="
+		got, _ := service.Secrets.Versions.Access(secret.Name + "/versions/latest").Do()
+
+		if got.Payload.Data != want {
+			t.Errorf("got %v want %v", got.Payload.Data, want)
+		}
+	})
+	t.Run("should skip disabled versions for latest", func(t *testing.T) {
+		service.Secrets.Versions.Disable(secret.Name+"/versions/2", &secretmanager.DisableSecretVersionRequest{}).Do()
+
+		want := "djE="
+		got, _ := service.Secrets.Versions.Access(secret.Name + "/versions/latest").Do()
+
+		if got.Payload.Data != want {
+			t.Errorf("got %v want %v", got.Payload.Data, want)
+		}
+	})
+	t.Run("should return err accessing destroyed version", func(t *testing.T) {
+		service.Secrets.Versions.Destroy(secret.Name+"/versions/1", &secretmanager.DestroySecretVersionRequest{}).Do()
+
+		_, err := service.Secrets.Versions.Access(secret.Name + "/versions/1").Do()
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+		_, err = service.Secrets.Versions.Enable(secret.Name+"/versions/1", &secretmanager.EnableSecretVersionRequest{}).Do()
+		if err == nil {
+			t.Errorf("expected an error enabling a destroyed version but got none")
+		}
+	})
+}
+
+func TestSecrets_IamPolicy_Do(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject("projects/test-project", "", nil)
+	secret := service.Secrets.NewSecret("test-project", "test-secret", nil)
+	policy := &secretmanager.Policy{Bindings: []*secretmanager.Binding{
+		{Role: "roles/secretmanager.secretAccessor", Members: []string{"serviceAccount:app@test-project.iam.gserviceaccount.com"}},
+	}}
+
+	t.Run("should set and get secret policy", func(t *testing.T) {
+		service.Secrets.SetIamPolicy(secret.Name, &secretmanager.SetIamPolicyRequest{Policy: policy}).Do()
+
+		got, _ := service.Secrets.GetIamPolicy(secret.Name).Do()
+
+		if !reflect.DeepEqual(got.Bindings, policy.Bindings) {
+			t.Errorf("got %v want %v", got.Bindings, policy.Bindings)
+		}
+		if PolicyContains(secret.Policy, "roles/secretmanager.secretAccessor") == nil {
+			t.Errorf("expected stored policy to contain the binding")
+		}
+	})
+	t.Run("should test permissions for caller", func(t *testing.T) {
+		service.Caller = "serviceAccount:app@test-project.iam.gserviceaccount.com"
+		request := &secretmanager.TestIamPermissionsRequest{Permissions: []string{"secretmanager.versions.access", "secretmanager.secrets.delete"}}
+
+		want := []string{"secretmanager.versions.access"}
+		got, _ := service.Secrets.TestIamPermissions(secret.Name, request).Do()
+
+		if !reflect.DeepEqual(got.Permissions, want) {
+			t.Errorf("got %v want %v", got.Permissions, want)
+		}
+	})
+	t.Run("should return err if secret doesn't exist", func(t *testing.T) {
+		_, err := service.Secrets.GetIamPolicy("projects/test-project/secrets/missing").Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
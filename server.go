@@ -0,0 +1,366 @@
+package mockgcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+	option "google.golang.org/api/option"
+)
+
+// statusClientClosedRequest is the HTTP status GCP maps a canceled request to
+const statusClientClosedRequest = 499
+
+// errorStatus is the google.rpc.Code name GCP sends along with each HTTP status in its JSON errors
+var errorStatus = map[int]string{
+	statusClientClosedRequest:      "CANCELLED",
+	http.StatusBadRequest:          "INVALID_ARGUMENT",
+	http.StatusUnauthorized:        "UNAUTHENTICATED",
+	http.StatusForbidden:           "PERMISSION_DENIED",
+	http.StatusNotFound:            "NOT_FOUND",
+	http.StatusConflict:            "ALREADY_EXISTS",
+	http.StatusTooManyRequests:     "RESOURCE_EXHAUSTED",
+	http.StatusInternalServerError: "INTERNAL",
+	http.StatusNotImplemented:      "UNIMPLEMENTED",
+	http.StatusServiceUnavailable:  "UNAVAILABLE",
+	http.StatusGatewayTimeout:      "DEADLINE_EXCEEDED",
+}
+
+// crmServedPath matches the resource manager v3 paths the Handler serves
+var crmServedPath = regexp.MustCompile(`^/v3/((projects|folders|organizations)/[^/:]+)(?::(\w+))?$`)
+
+// crmSearchPath matches the resource manager v3 search paths the Handler serves
+var crmSearchPath = regexp.MustCompile(`^/v3/(projects|folders|organizations):search$`)
+
+// Handler returns an http.Handler serving the resource manager v3 REST API from the service's state, so
+// a real cloudresourcemanager client (or anything else that speaks REST) can be pointed at the mock with
+// option.WithEndpoint.  It serves Get, GetIamPolicy, SetIamPolicy and TestIamPermissions on projects,
+// folders and organizations, Search on each collection, and Delete, Undelete and Move on projects.  Requests
+// go through the same Do() calls as the Go API, so scripted faults apply to them too.  Gets and searches
+// are checked against faults as Projects.Get and Projects.Search (and the same for folders and
+// organizations)
+func (s *MockService) Handler() http.Handler {
+	return http.HandlerFunc(s.serveCRM)
+}
+
+// CRMClient returns a real cloudresourcemanager client wired straight to the service's Handler, without
+// going over the network
+func (s *MockService) CRMClient() *cloudresourcemanager.Service {
+	client, _ := cloudresourcemanager.NewService(context.TODO(),
+		option.WithEndpoint("http://mockgcp/"),
+		option.WithHTTPClient(&http.Client{Transport: handlerTransport{handler: s.Handler()}}))
+	return client
+}
+
+// handlerTransport is an http.RoundTripper that answers requests by running them through a Handler
+type handlerTransport struct {
+	handler http.Handler
+}
+
+// RoundTrip serves a single request
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	t.handler.ServeHTTP(recorder, req)
+	return recorder.Result(), nil
+}
+
+// serveCRM serves a single resource manager request
+func (s *MockService) serveCRM(w http.ResponseWriter, req *http.Request) {
+	if search := crmSearchPath.FindStringSubmatch(req.URL.Path); search != nil && req.Method == http.MethodGet {
+		result, err := s.crmSearch(req.Context(), search[1], req.URL.Query().Get("query"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+	match := crmServedPath.FindStringSubmatch(req.URL.Path)
+	if match == nil {
+		writeError(w, &googleapi.Error{Code: http.StatusNotFound, Message: fmt.Sprintf("%v %v not found", req.Method, req.URL.Path)})
+		return
+	}
+	name, collection, verb := match[1], match[2], match[3]
+	var result interface{}
+	var err error
+	switch {
+	case req.Method == http.MethodGet && verb == "":
+		result, err = s.crmGet(req.Context(), collection, name)
+	case req.Method == http.MethodPost && verb == "getIamPolicy":
+		request := &cloudresourcemanager.GetIamPolicyRequest{}
+		if err = decodeBody(req, request); err == nil {
+			result, err = s.crmGetIamPolicy(req.Context(), collection, name, request)
+		}
+	case req.Method == http.MethodPost && verb == "setIamPolicy":
+		request := &cloudresourcemanager.SetIamPolicyRequest{}
+		if err = decodeBody(req, request); err == nil {
+			result, err = s.crmSetIamPolicy(req.Context(), collection, name, request)
+		}
+	case req.Method == http.MethodPost && verb == "testIamPermissions":
+		request := &cloudresourcemanager.TestIamPermissionsRequest{}
+		if err = decodeBody(req, request); err == nil {
+			result, err = s.crmTestIamPermissions(req.Context(), collection, name, request)
+		}
+	case req.Method == http.MethodDelete && verb == "" && collection == "projects":
+		result, err = s.Projects.Delete(name).Context(req.Context()).Do()
+	case req.Method == http.MethodPost && verb == "undelete" && collection == "projects":
+		request := &cloudresourcemanager.UndeleteProjectRequest{}
+		if err = decodeBody(req, request); err == nil {
+			result, err = s.Projects.Undelete(name, request).Context(req.Context()).Do()
+		}
+	case req.Method == http.MethodPost && verb == "move" && collection == "projects":
+		request := &cloudresourcemanager.MoveProjectRequest{}
+		if err = decodeBody(req, request); err == nil {
+			result, err = s.Projects.Move(name, request).Context(req.Context()).Do()
+		}
+	default:
+		err = &googleapi.Error{Code: http.StatusNotImplemented, Message: fmt.Sprintf("%v %v is not implemented", req.Method, req.URL.Path)}
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(result)
+}
+
+// decodeBody reads a JSON request body into request.  An empty body leaves request as it is
+func decodeBody(req *http.Request, request interface{}) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if err := json.NewDecoder(req.Body).Decode(request); err != nil && !errors.Is(err, io.EOF) {
+		return &googleapi.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("request body invalid: %v", err)}
+	}
+	return nil
+}
+
+// crmGetMethods is the method each resource manager collection's Get is recorded and faulted as
+var crmGetMethods = map[string]string{
+	"projects":      "Projects.Get",
+	"folders":       "Folders.Get",
+	"organizations": "Organizations.Get",
+}
+
+// crmGet returns the project, folder or organization called name in its resource manager form
+func (s *MockService) crmGet(ctx context.Context, collection, name string) (result interface{}, err error) {
+	call, err := s.beginCall(ctx, crmGetMethods[collection], name, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	switch collection {
+	case "projects":
+		if project := s.Projects.get(name); project != nil {
+			return crmProject(project), nil
+		}
+		for _, project := range s.Projects.DeletedProjects() {
+			if project.ProjectID == name {
+				return crmProject(project), nil
+			}
+		}
+	case "folders":
+		for _, folder := range s.Folders.FolderList {
+			if folder.FolderID == name {
+				return crmFolder(folder), nil
+			}
+		}
+	default:
+		for _, organization := range s.Organizations.OrganizationList {
+			if organization.OrganizationID == name {
+				return crmOrganization(organization), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%v: %v", resourceNotFoundError, name)
+}
+
+// crmSearchMethods is the method each resource manager collection's Search is recorded and faulted as
+var crmSearchMethods = map[string]string{
+	"projects":      "Projects.Search",
+	"folders":       "Folders.Search",
+	"organizations": "Organizations.Search",
+}
+
+// crmSearchFields are the query fields each collection can be searched on, and the resource manager
+// field each one reads
+var crmSearchFields = map[string]map[string]string{
+	"projects": {
+		"id": "projectId", "projectid": "projectId", "name": "displayName", "displayname": "displayName",
+		"parent": "parent", "state": "state", "lifecyclestate": "state",
+	},
+	"folders": {
+		"displayname": "displayName", "parent": "parent", "state": "state", "lifecyclestate": "state",
+	},
+	"organizations": {
+		"domain": "displayName", "displayname": "displayName", "state": "state",
+	},
+}
+
+// crmSearch returns the projects, folders or organizations matching a search query.  A query is a list of
+// field:value or field=value terms, separated by spaces, that all have to match.  Values are matched
+// without regard to case, and can use * as a wildcard, so displayName:prod* finds every project named
+// starting with prod.  Deleted projects are found along with active ones unless the query has a state
+func (s *MockService) crmSearch(ctx context.Context, collection, query string) (result interface{}, err error) {
+	call, err := s.beginCall(ctx, crmSearchMethods[collection], collection, query)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	terms := map[string]string{}
+	for _, term := range strings.Fields(query) {
+		i := strings.IndexAny(term, ":=")
+		if i < 0 {
+			return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("query term %v invalid", term)}
+		}
+		field, ok := crmSearchFields[collection][strings.ToLower(term[:i])]
+		if !ok {
+			return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("query field %v invalid", term[:i])}
+		}
+		terms[field] = strings.ToLower(term[i+1:])
+	}
+	matches := func(fields map[string]string) bool {
+		for field, pattern := range terms {
+			if ok, _ := path.Match(pattern, strings.ToLower(fields[field])); !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch collection {
+	case "projects":
+		response := &cloudresourcemanager.SearchProjectsResponse{Projects: []*cloudresourcemanager.Project{}}
+		for _, project := range append(append([]*Project{}, s.Projects.ProjectList...), s.Projects.DeletedProjects()...) {
+			found := crmProject(project)
+			if matches(map[string]string{"projectId": found.ProjectId, "displayName": found.DisplayName, "parent": found.Parent, "state": found.State}) {
+				response.Projects = append(response.Projects, found)
+			}
+		}
+		return response, nil
+	case "folders":
+		response := &cloudresourcemanager.SearchFoldersResponse{Folders: []*cloudresourcemanager.Folder{}}
+		for _, folder := range s.Folders.FolderList {
+			found := crmFolder(folder)
+			if matches(map[string]string{"displayName": found.DisplayName, "parent": found.Parent, "state": found.State}) {
+				response.Folders = append(response.Folders, found)
+			}
+		}
+		return response, nil
+	}
+	response := &cloudresourcemanager.SearchOrganizationsResponse{Organizations: []*cloudresourcemanager.Organization{}}
+	for _, organization := range s.Organizations.OrganizationList {
+		found := crmOrganization(organization)
+		if matches(map[string]string{"displayName": found.DisplayName, "state": found.State}) {
+			response.Organizations = append(response.Organizations, found)
+		}
+	}
+	return response, nil
+}
+
+// crmProject returns a project in its resource manager form.  Deleted projects are in the DELETE_REQUESTED
+// state until they're purged
+func crmProject(project *Project) *cloudresourcemanager.Project {
+	result := &cloudresourcemanager.Project{
+		Name:        project.ProjectID,
+		ProjectId:   strings.TrimPrefix(project.ProjectID, "projects/"),
+		DisplayName: project.DisplayName,
+		Parent:      project.Parent,
+		State:       "ACTIVE",
+	}
+	if !project.DeleteTime.IsZero() {
+		result.State = "DELETE_REQUESTED"
+		result.DeleteTime = project.DeleteTime.Format(time.RFC3339Nano)
+	}
+	return result
+}
+
+// crmFolder returns a folder in its resource manager form
+func crmFolder(folder *Folder) *cloudresourcemanager.Folder {
+	return &cloudresourcemanager.Folder{Name: folder.FolderID, DisplayName: folder.DisplayName, Parent: folder.Parent, State: "ACTIVE"}
+}
+
+// crmOrganization returns an organization in its resource manager form
+func crmOrganization(organization *Organization) *cloudresourcemanager.Organization {
+	return &cloudresourcemanager.Organization{Name: organization.OrganizationID, DisplayName: organization.Domain, State: "ACTIVE"}
+}
+
+func (s *MockService) crmGetIamPolicy(ctx context.Context, collection, name string, request *cloudresourcemanager.GetIamPolicyRequest) (*cloudresourcemanager.Policy, error) {
+	switch collection {
+	case "projects":
+		return s.Projects.GetIamPolicy(name, request).Context(ctx).Do()
+	case "folders":
+		return s.Folders.GetIamPolicy(name, request).Context(ctx).Do()
+	}
+	return s.Organizations.GetIamPolicy(name, request).Context(ctx).Do()
+}
+
+func (s *MockService) crmSetIamPolicy(ctx context.Context, collection, name string, request *cloudresourcemanager.SetIamPolicyRequest) (*cloudresourcemanager.Policy, error) {
+	switch collection {
+	case "projects":
+		return s.Projects.SetIamPolicy(name, request).Context(ctx).Do()
+	case "folders":
+		return s.Folders.SetIamPolicy(name, request).Context(ctx).Do()
+	}
+	return s.Organizations.SetIamPolicy(name, request).Context(ctx).Do()
+}
+
+func (s *MockService) crmTestIamPermissions(ctx context.Context, collection, name string, request *cloudresourcemanager.TestIamPermissionsRequest) (*cloudresourcemanager.TestIamPermissionsResponse, error) {
+	switch collection {
+	case "projects":
+		return s.Projects.TestIamPermissions(name, request).Context(ctx).Do()
+	case "folders":
+		return s.Folders.TestIamPermissions(name, request).Context(ctx).Do()
+	}
+	return s.Organizations.TestIamPermissions(name, request).Context(ctx).Do()
+}
+
+// errorCode returns the HTTP status GCP would answer with for an error from a Do() call
+func errorCode(err error) int {
+	var apiError *googleapi.Error
+	switch {
+	case errors.As(err, &apiError):
+		return apiError.Code
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case strings.Contains(err.Error(), resourceNotFoundError):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), resourceAlreadyExistsError):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// writeError writes err as a google JSON error, which the google API clients turn back into a
+// googleapi.Error
+func writeError(w http.ResponseWriter, err error) {
+	code := errorCode(err)
+	body := map[string]interface{}{
+		"code":    code,
+		"message": err.Error(),
+		"status":  errorStatus[code],
+	}
+	var apiError *googleapi.Error
+	if errors.As(err, &apiError) {
+		body["message"] = apiError.Message
+		if len(apiError.Details) > 0 {
+			body["details"] = apiError.Details
+		}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": body})
+}
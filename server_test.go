@@ -0,0 +1,170 @@
+package mockgcp
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// servedClient returns a resource manager client talking to the service's Handler
+func servedClient(t *testing.T, service *MockService) *cloudresourcemanager.Service {
+	server := httptest.NewServer(service.Handler())
+	t.Cleanup(server.Close)
+	client, err := cloudresourcemanager.NewService(context.TODO(),
+		option.WithEndpoint(server.URL+"/"),
+		option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestMockService_Handler(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Organizations.NewOrganization("organizations/1", "testdomain.co", nil)
+	service.Folders.NewFolder("folders/2", "Test Folder", nil).Parent = "organizations/1"
+	service.Projects.NewProject("projects/test-project", "Test Project", GeneratePolicy(NewBinding("roles/owner", "user:alice@testdomain.co"))).Parent = "folders/2"
+	client := servedClient(t, service)
+
+	t.Run("should get a project", func(t *testing.T) {
+		project, err := client.Projects.Get("projects/test-project").Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.DisplayName != "Test Project" || project.Parent != "folders/2" {
+			t.Errorf("got %v %v", project.DisplayName, project.Parent)
+		}
+	})
+	t.Run("should set and get policies", func(t *testing.T) {
+		policy := GeneratePolicy(NewBinding("roles/viewer", "user:bob@testdomain.co"))
+		if _, err := client.Folders.SetIamPolicy("folders/2", &cloudresourcemanager.SetIamPolicyRequest{Policy: policy}).Do(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := client.Folders.GetIamPolicy("folders/2", &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !BindingContains(PolicyContains(got, "roles/viewer"), "user:bob@testdomain.co") {
+			t.Errorf("got %v", got.Bindings)
+		}
+	})
+	t.Run("should return a 404 for missing resources", func(t *testing.T) {
+		_, err := client.Projects.GetIamPolicy("projects/missing-project", &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+		var apiError *googleapi.Error
+		if !errors.As(err, &apiError) || apiError.Code != 404 {
+			t.Errorf("got %v want a 404", err)
+		}
+	})
+	t.Run("should serve scripted faults", func(t *testing.T) {
+		service.Faults.Inject(Fault{Method: "Projects.SetIamPolicy", Times: 1, Code: 429, Message: "Quota exceeded"})
+		_, err := client.Projects.SetIamPolicy("projects/test-project", &cloudresourcemanager.SetIamPolicyRequest{Policy: GeneratePolicy()}).Do()
+		var apiError *googleapi.Error
+		if !errors.As(err, &apiError) || apiError.Code != 429 || apiError.Message != "Quota exceeded" {
+			t.Errorf("got %v want a 429", err)
+		}
+		service.Faults.AssertConsumed(t)
+	})
+	t.Run("should move and delete projects", func(t *testing.T) {
+		if _, err := client.Projects.Move("projects/test-project", &cloudresourcemanager.MoveProjectRequest{DestinationParent: "organizations/1"}).Do(); err != nil {
+			t.Fatal(err)
+		}
+		if service.Projects.get("projects/test-project").Parent != "organizations/1" {
+			t.Errorf("expected the project to move")
+		}
+		if _, err := client.Projects.Delete("projects/test-project").Do(); err != nil {
+			t.Fatal(err)
+		}
+		if service.Projects.get("projects/test-project") != nil {
+			t.Errorf("expected the project to be deleted")
+		}
+		project, err := client.Projects.Get("projects/test-project").Do()
+		if err != nil || project.State != "DELETE_REQUESTED" {
+			t.Errorf("got %v %v want a project in DELETE_REQUESTED", project, err)
+		}
+		if _, err := client.Projects.Undelete("projects/test-project", &cloudresourcemanager.UndeleteProjectRequest{}).Do(); err != nil {
+			t.Fatal(err)
+		}
+		if service.Projects.get("projects/test-project") == nil {
+			t.Errorf("expected the project to be undeleted")
+		}
+	})
+}
+
+func TestMockService_Handler_search(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Organizations.NewOrganization("organizations/1", "testdomain.co", nil)
+	service.Folders.NewFolder("folders/2", "Test Folder", nil).Parent = "organizations/1"
+	service.Projects.NewProject("projects/prod-api", "Prod API", nil).Parent = "folders/2"
+	service.Projects.NewProject("projects/prod-web", "Prod Web", nil).Parent = "organizations/1"
+	service.Projects.NewProject("projects/test-api", "Test API", nil).Parent = "folders/2"
+	client := service.CRMClient()
+
+	t.Run("should search projects", func(t *testing.T) {
+		for query, want := range map[string]int{
+			"":                                2 + 1,
+			"displayName:prod*":               2,
+			"parent:folders/2":                2,
+			"parent=folders/2 name:PROD*":     1,
+			"id:test-api state:ACTIVE":        1,
+			"id:missing-project":              0,
+			"lifecycleState:DELETE_REQUESTED": 0,
+		} {
+			response, err := client.Projects.Search().Query(query).Do()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Projects) != want {
+				t.Errorf("got %v projects for %q want %v", len(response.Projects), query, want)
+			}
+		}
+	})
+	t.Run("should search folders and organizations", func(t *testing.T) {
+		folders, err := client.Folders.Search().Query("parent:organizations/1").Do()
+		if err != nil || len(folders.Folders) != 1 || folders.Folders[0].DisplayName != "Test Folder" {
+			t.Errorf("got %v %v want folders/2", folders, err)
+		}
+		organizations, err := client.Organizations.Search().Query("domain:testdomain.co").Do()
+		if err != nil || len(organizations.Organizations) != 1 || organizations.Organizations[0].Name != "organizations/1" {
+			t.Errorf("got %v %v want organizations/1", organizations, err)
+		}
+	})
+	t.Run("should find deleted projects by state", func(t *testing.T) {
+		service.Projects.Delete("projects/test-api").Do()
+		response, err := client.Projects.Search().Query("state:DELETE_REQUESTED").Do()
+		if err != nil || len(response.Projects) != 1 || response.Projects[0].ProjectId != "test-api" {
+			t.Errorf("got %v %v want test-api", response, err)
+		}
+	})
+	t.Run("should reject invalid queries", func(t *testing.T) {
+		for _, query := range []string{"prod", "domain:testdomain.co"} {
+			_, err := client.Projects.Search().Query(query).Do()
+			var apiError *googleapi.Error
+			if !errors.As(err, &apiError) || apiError.Code != 400 {
+				t.Errorf("got %v for %q want a 400", err, query)
+			}
+		}
+	})
+	t.Run("should record searches", func(t *testing.T) {
+		service.Calls.ExpectCalls(t, 1, ExpectedCall{Method: "Folders.Search", Request: "parent:organizations/1"})
+	})
+}
+
+func TestMockService_Handler_deadline(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject("projects/test-project", "", nil)
+	service.Faults.Inject(Fault{Method: "Projects.GetIamPolicy", Latency: time.Minute})
+	client := servedClient(t, service)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.Projects.GetIamPolicy("projects/test-project", &cloudresourcemanager.GetIamPolicyRequest{}).Context(ctx).Do()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,208 @@
+package mockgcp
+
+import (
+	logging "google.golang.org/api/logging/v2"
+)
+
+// Snapshot is a deep copy of everything held in a MockService, taken with Snapshot and put back with
+// Restore.  A snapshot can be restored any number of times
+type Snapshot struct {
+	organizations []*Organization
+	folders       []*Folder
+	projects      []*Project
+	deleted       []*Project
+	groups        []*Group
+	exports       map[string][]byte
+	auditLogs     []*logging.LogEntry
+	caller        string
+}
+
+// Snapshot returns a deep copy of the service's state: every resource and policy (deleted projects too),
+// groups, asset exports, audit logs and the Caller.  Build an expensive world once, snapshot it, and Restore
+// it at the start of each subtest.
+//
+// The test harness around the state isn't part of a snapshot, and carries on across a Restore as it was:
+// asset feed subscriptions, the Clock and its time, scripted faults and how many calls they've matched and
+// fired on, quotas with their token buckets and Rejected counts, and the calls recorded in Calls.  Reset
+// those separately if a subtest needs them fresh
+func (s *MockService) Snapshot() *Snapshot {
+	return &Snapshot{
+		organizations: copyOrganizations(s.Organizations.OrganizationList),
+		folders:       copyFolders(s.Folders.FolderList),
+		projects:      copyProjects(s.Projects.ProjectList, s),
+		deleted:       copyProjects(s.Projects.deleted, s),
+		groups:        copyGroups(s.Groups.GroupList, s),
+		exports:       copyExports(s.Assets.Exports),
+		auditLogs:     copyLogEntries(s.AuditLogs.Entries),
+		caller:        s.Caller,
+	}
+}
+
+// Restore puts the service back to the state it was in when snapshot was taken.  Pointers to resources
+// taken before the Restore point at the old state, so look them up again afterwards.  Nothing is published
+// to asset feed subscribers
+func (s *MockService) Restore(snapshot *Snapshot) {
+	s.Organizations.OrganizationList = copyOrganizations(snapshot.organizations)
+	s.Folders.FolderList = copyFolders(snapshot.folders)
+	s.Projects.ProjectList = copyProjects(snapshot.projects, s)
+	s.Projects.deleted = copyProjects(snapshot.deleted, s)
+	s.Groups.GroupList = copyGroups(snapshot.groups, s)
+	s.Assets.Exports = copyExports(snapshot.exports)
+	s.AuditLogs.Entries = copyLogEntries(snapshot.auditLogs)
+	s.Caller = snapshot.caller
+}
+
+// copyHolder returns a deep copy of a policy holder
+func copyHolder(holder IamPolicyHolder) IamPolicyHolder {
+	return IamPolicyHolder{Policy: copyPolicy(holder.Policy)}
+}
+
+func copyOrganizations(organizations []*Organization) []*Organization {
+	if organizations == nil {
+		return nil
+	}
+	result := make([]*Organization, len(organizations))
+	for i, o := range organizations {
+		organization := *o
+		organization.IamPolicyHolder = copyHolder(o.IamPolicyHolder)
+		result[i] = &organization
+	}
+	return result
+}
+
+func copyFolders(folders []*Folder) []*Folder {
+	if folders == nil {
+		return nil
+	}
+	result := make([]*Folder, len(folders))
+	for i, f := range folders {
+		folder := *f
+		folder.IamPolicyHolder = copyHolder(f.IamPolicyHolder)
+		result[i] = &folder
+	}
+	return result
+}
+
+// copyProjects returns a deep copy of projects and everything in them.  Secrets and key rings are attached
+// to service
+func copyProjects(projects []*Project, service *MockService) []*Project {
+	if projects == nil {
+		return nil
+	}
+	result := make([]*Project, len(projects))
+	for i, p := range projects {
+		project := *p
+		project.IamPolicyHolder = copyHolder(p.IamPolicyHolder)
+		project.ServiceAccounts = nil
+		for _, a := range p.ServiceAccounts {
+			account := *a
+			account.IamPolicyHolder = copyHolder(a.IamPolicyHolder)
+			account.Keys = nil
+			for _, k := range a.Keys {
+				key := *k
+				account.Keys = append(account.Keys, &key)
+			}
+			project.ServiceAccounts = append(project.ServiceAccounts, &account)
+		}
+		project.Buckets = nil
+		for _, b := range p.Buckets {
+			bucket := *b
+			bucket.IamPolicyHolder = copyHolder(b.IamPolicyHolder)
+			bucket.Labels = copyLabels(b.Labels)
+			project.Buckets = append(project.Buckets, &bucket)
+		}
+		project.Topics = nil
+		for _, t := range p.Topics {
+			topic := *t
+			topic.IamPolicyHolder = copyHolder(t.IamPolicyHolder)
+			topic.Labels = copyLabels(t.Labels)
+			project.Topics = append(project.Topics, &topic)
+		}
+		project.Subscriptions = nil
+		for _, sub := range p.Subscriptions {
+			subscription := *sub
+			subscription.IamPolicyHolder = copyHolder(sub.IamPolicyHolder)
+			subscription.Labels = copyLabels(sub.Labels)
+			project.Subscriptions = append(project.Subscriptions, &subscription)
+		}
+		project.Secrets = nil
+		for _, sec := range p.Secrets {
+			secret := *sec
+			secret.IamPolicyHolder = copyHolder(sec.IamPolicyHolder)
+			secret.service = service
+			secret.Labels = copyLabels(sec.Labels)
+			secret.Versions = nil
+			for _, v := range sec.Versions {
+				version := *v
+				secret.Versions = append(secret.Versions, &version)
+			}
+			project.Secrets = append(project.Secrets, &secret)
+		}
+		project.KeyRings = nil
+		for _, k := range p.KeyRings {
+			keyRing := *k
+			keyRing.IamPolicyHolder = copyHolder(k.IamPolicyHolder)
+			keyRing.service = service
+			keyRing.CryptoKeys = nil
+			for _, c := range k.CryptoKeys {
+				cryptoKey := *c
+				cryptoKey.IamPolicyHolder = copyHolder(c.IamPolicyHolder)
+				cryptoKey.Labels = copyLabels(c.Labels)
+				keyRing.CryptoKeys = append(keyRing.CryptoKeys, &cryptoKey)
+			}
+			project.KeyRings = append(project.KeyRings, &keyRing)
+		}
+		result[i] = &project
+	}
+	return result
+}
+
+// copyGroups returns a deep copy of groups and their memberships, attached to service
+func copyGroups(groups []*Group, service *MockService) []*Group {
+	if groups == nil {
+		return nil
+	}
+	result := make([]*Group, len(groups))
+	for i, g := range groups {
+		group := *g
+		group.Labels = copyLabels(g.Labels)
+		group.service = service
+		group.Memberships = nil
+		for _, m := range g.Memberships {
+			membership := *m
+			membership.Roles = copyStrings(m.Roles)
+			group.Memberships = append(group.Memberships, &membership)
+		}
+		result[i] = &group
+	}
+	return result
+}
+
+func copyExports(exports map[string][]byte) map[string][]byte {
+	if exports == nil {
+		return nil
+	}
+	result := make(map[string][]byte, len(exports))
+	for uri, data := range exports {
+		result[uri] = append([]byte(nil), data...)
+	}
+	return result
+}
+
+func copyLogEntries(entries []*logging.LogEntry) []*logging.LogEntry {
+	if entries == nil {
+		return nil
+	}
+	result := make([]*logging.LogEntry, len(entries))
+	for i, e := range entries {
+		entry := *e
+		entry.ProtoPayload = append([]byte(nil), e.ProtoPayload...)
+		if e.Resource != nil {
+			resource := *e.Resource
+			resource.Labels = copyLabels(e.Resource.Labels)
+			entry.Resource = &resource
+		}
+		result[i] = &entry
+	}
+	return result
+}
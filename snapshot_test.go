@@ -0,0 +1,87 @@
+package mockgcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/storage/v1"
+)
+
+func TestMockService_Restore(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	if err := service.LoadFixtureFile("testdata/org.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	baseline := service.Snapshot()
+
+	tests := []struct {
+		name   string
+		mutate func()
+	}{
+		{"should undo a project SetIamPolicy", func() {
+			service.Projects.SetIamPolicy("projects/prod-project", &cloudresourcemanager.SetIamPolicyRequest{
+				Policy: GeneratePolicy(NewBinding("roles/owner", "user:mallory@testdomain.co")),
+			}).Do()
+		}},
+		{"should undo an in place policy edit", func() {
+			service.Projects.get("projects/prod-project").Policy.Bindings[0].Members[0] = "user:mallory@testdomain.co"
+		}},
+		{"should undo deletes and new resources", func() {
+			service.Buckets.Delete("prod-logs").Do()
+			service.Topics.NewTopic("prod-project", "extra-topic", nil)
+			service.Groups.findByEmail("auditors@testdomain.co").AddMember("user:mallory@testdomain.co")
+		}},
+		{"should undo audit logs and the caller", func() {
+			service.Caller = "user:mallory@testdomain.co"
+			service.Buckets.Insert("prod-project", &storage.Bucket{Name: "exfil-bucket"}).Do()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service.Restore(baseline)
+			tt.mutate()
+			service.Restore(baseline)
+
+			owners, _ := PolicyRoleMembers(service.Projects.get("projects/prod-project").GetIamPolicy(), "roles/owner")
+			if len(owners) != 1 || owners[0] != "user:alice@testdomain.co" {
+				t.Errorf("got owners %v want %v", owners, []string{"user:alice@testdomain.co"})
+			}
+			if service.Buckets.find("prod-logs") == nil || service.Buckets.find("exfil-bucket") != nil {
+				t.Errorf("expected the baseline buckets")
+			}
+			if service.Topics.find("projects/prod-project/topics/extra-topic") != nil {
+				t.Errorf("expected extra-topic to be gone")
+			}
+			if len(service.Groups.TransitiveMembers("auditors@testdomain.co")) != 2 {
+				t.Errorf("got %v want 2 members", service.Groups.TransitiveMembers("auditors@testdomain.co"))
+			}
+			if len(service.AuditLogs.Entries) != 0 || service.Caller != "" {
+				t.Errorf("got %v entries and caller %v", len(service.AuditLogs.Entries), service.Caller)
+			}
+		})
+	}
+	t.Run("should keep key rings attached to the service", func(t *testing.T) {
+		service.Restore(baseline)
+		changes, cancel := service.WatchAssetChanges(AssetFeedFilter{}, 1)
+		defer cancel()
+		service.KeyRings.find("projects/prod-project/locations/global/keyRings/prod-ring").NewCryptoKey("new-key", "", nil)
+		if len(changes) != 1 {
+			t.Errorf("expected the new crypto key to be published")
+		}
+	})
+	t.Run("should attach secrets and groups to the service restoring them", func(t *testing.T) {
+		restored, _ := NewService(context.TODO())
+		clock := NewFakeClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+		restored.Clock = clock
+		restored.Restore(baseline)
+
+		version := restored.Secrets.find("projects/prod-project/secrets/api-key").AddVersion("dGhpcmQ=")
+		membership := restored.Groups.findByEmail("auditors@testdomain.co").AddMember("user:erin@testdomain.co")
+
+		if !version.CreateTime.Equal(clock.Now()) || !membership.CreateTime.Equal(clock.Now()) {
+			t.Errorf("got %v and %v want %v", version.CreateTime, membership.CreateTime, clock.Now())
+		}
+	})
+}
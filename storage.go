@@ -0,0 +1,512 @@
+package mockgcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v3"
+	googleapi "google.golang.org/api/googleapi"
+	storage "google.golang.org/api/storage/v1"
+)
+
+var (
+	bucketNameFormat = regexp.MustCompile(`^[a-z0-9][-a-z0-9_.]{1,220}[a-z0-9]$`)
+
+	// bucketConvenienceMembers maps the projectOwner:, projectEditor: and projectViewer: members
+	// buckets accept to the basic role on the project they stand for
+	bucketConvenienceMembers = map[string]string{
+		"projectOwner:":  "roles/owner",
+		"projectEditor:": "roles/editor",
+		"projectViewer:": "roles/viewer",
+	}
+)
+
+// Bucket is a mock of a google cloud storage Bucket, which belongs to a Project
+type Bucket struct {
+	IamPolicyHolder
+	Name                     string
+	ProjectID                string
+	Location                 string
+	StorageClass             string
+	Labels                   map[string]string
+	UniformBucketLevelAccess bool
+	TimeCreated              time.Time
+}
+
+// toAPI converts the mock bucket to the type returned by the storage API
+func (b *Bucket) toAPI() *storage.Bucket {
+	return &storage.Bucket{
+		Kind:         "storage#bucket",
+		Id:           b.Name,
+		Name:         b.Name,
+		Location:     b.Location,
+		StorageClass: b.StorageClass,
+		Labels:       copyLabels(b.Labels),
+		TimeCreated:  b.TimeCreated.Format(time.RFC3339),
+		IamConfiguration: &storage.BucketIamConfiguration{
+			UniformBucketLevelAccess: &storage.BucketIamConfigurationUniformBucketLevelAccess{Enabled: b.UniformBucketLevelAccess},
+			BucketPolicyOnly:         &storage.BucketIamConfigurationBucketPolicyOnly{Enabled: b.UniformBucketLevelAccess},
+		},
+	}
+}
+
+// BucketsService is a mock of google Cloud Storage's Buckets Service
+type BucketsService struct {
+	Service *MockService
+}
+
+// NewBucketsService will return a new Buckets Service
+func NewBucketsService(s *MockService) *BucketsService {
+	rs := &BucketsService{Service: s}
+	return rs
+}
+
+// NewBucket creates a new bucket on the project with the specified name and policy, and returns a pointer to
+// the created bucket.  If policy isn't specified it gets the default policy google gives new buckets, which
+// grants the legacy bucket roles to the project's owners, editors and viewers.  It returns nil if the
+// project doesn't exist in the Projects Service
+func (r *BucketsService) NewBucket(projectID, name string, policy *cloudresourcemanager.Policy) *Bucket {
+	project := r.Service.Projects.get(projectResourceName(projectID))
+	if project == nil {
+		return nil
+	}
+	if policy == nil {
+		policy = DefaultBucketPolicy(project.ProjectID)
+	}
+	bucket := &Bucket{
+		IamPolicyHolder: IamPolicyHolder{Policy: policy},
+		Name:            name,
+		ProjectID:       project.ProjectID,
+		Location:        "US",
+		StorageClass:    "STANDARD",
+		TimeCreated:     r.Service.now().UTC(),
+	}
+	project.Buckets = append(project.Buckets, bucket)
+	r.Service.publishNewAsset(&bucket.IamPolicyHolder)
+	return bucket
+}
+
+// DefaultBucketPolicy returns the policy google puts on a new bucket in the project
+func DefaultBucketPolicy(projectID string) *cloudresourcemanager.Policy {
+	id := strings.TrimPrefix(projectID, "projects/")
+	return NewPolicy([]*cloudresourcemanager.Binding{
+		NewBinding("roles/storage.legacyBucketOwner", "projectEditor:"+id, "projectOwner:"+id),
+		NewBinding("roles/storage.legacyBucketReader", "projectViewer:"+id),
+		NewBinding("roles/storage.legacyObjectOwner", "projectEditor:"+id, "projectOwner:"+id),
+		NewBinding("roles/storage.legacyObjectReader", "projectViewer:"+id),
+	})
+}
+
+// find returns the bucket with the given name, or nil if there isn't one.  Bucket names are global
+// so this searches every project
+func (r *BucketsService) find(name string) *Bucket {
+	for _, project := range r.Service.Projects.ProjectList {
+		for _, bucket := range project.Buckets {
+			if bucket.Name == name {
+				return bucket
+			}
+		}
+	}
+	return nil
+}
+
+// policyHolder returns the policy holder for the bucket, or nil if there isn't one
+func (r *BucketsService) policyHolder(resource string) *IamPolicyHolder {
+	bucket := r.find(resource)
+	if bucket == nil {
+		return nil
+	}
+	return &bucket.IamPolicyHolder
+}
+
+// memberMatches extends the service's callerMatches with the projectOwner:, projectEditor: and
+// projectViewer: members, which match the caller if they have that basic role on the project
+func (r *BucketsService) memberMatches(member string) bool {
+	for prefix, role := range bucketConvenienceMembers {
+		if !strings.HasPrefix(member, prefix) {
+			continue
+		}
+		project := r.Service.Projects.get(projectResourceName(strings.TrimPrefix(member, prefix)))
+		if project == nil || project.Policy == nil {
+			return false
+		}
+		for _, binding := range project.Policy.Bindings {
+			if binding == nil || binding.Role != role {
+				continue
+			}
+			for _, m := range binding.Members {
+				if r.Service.callerMatches(m) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return r.Service.callerMatches(member)
+}
+
+// Insert will take a project ID and a bucket and returns an Insert Call, so we can run a Do() method on it.
+func (r *BucketsService) Insert(projectid string, bucket *storage.Bucket) *BucketsInsertCall {
+	return &BucketsInsertCall{Service: r.Service, Projectid: projectid, Bucket: bucket}
+}
+
+// BucketsInsertCall is a structure that is returned by Buckets.Insert.  Then we call Do() on it
+// to create the bucket
+type BucketsInsertCall struct {
+	Service   *MockService
+	Projectid string
+	Bucket    *storage.Bucket
+	ctx       context.Context
+}
+
+// Do will be called on BucketsInsertCall to create the bucket and return it
+func (c *BucketsInsertCall) Do(opts ...googleapi.CallOption) (result *storage.Bucket, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Buckets.Insert", c.Projectid, c.Bucket)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if c.Service.Projects.get(projectResourceName(c.Projectid)) == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Projectid)
+	}
+	if c.Bucket == nil {
+		return nil, fmt.Errorf("bucket is required")
+	}
+	if !bucketNameFormat.MatchString(c.Bucket.Name) {
+		return nil, fmt.Errorf("invalid bucket name: %v", c.Bucket.Name)
+	}
+	if c.Service.Buckets.find(c.Bucket.Name) != nil {
+		return nil, fmt.Errorf("%v: %v", resourceAlreadyExistsError, c.Bucket.Name)
+	}
+
+	bucket := c.Service.Buckets.NewBucket(c.Projectid, c.Bucket.Name, nil)
+	if c.Bucket.Location != "" {
+		bucket.Location = strings.ToUpper(c.Bucket.Location)
+	}
+	if c.Bucket.StorageClass != "" {
+		bucket.StorageClass = c.Bucket.StorageClass
+	}
+	bucket.Labels = copyLabels(c.Bucket.Labels)
+	if iamConfiguration := c.Bucket.IamConfiguration; iamConfiguration != nil && iamConfiguration.UniformBucketLevelAccess != nil {
+		bucket.UniformBucketLevelAccess = iamConfiguration.UniformBucketLevelAccess.Enabled
+	}
+	c.Service.AuditLogs.record("storage.googleapis.com", "storage.buckets.create", bucketAuditName(bucket.Name), bucket.ProjectID, nil)
+	return bucket.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *BucketsInsertCall) Context(ctx context.Context) *BucketsInsertCall {
+	c.ctx = ctx
+	return c
+}
+
+// Get will take a bucket name and returns a Get Call, so we can run a Do() method on it.
+func (r *BucketsService) Get(bucket string) *BucketsGetCall {
+	return &BucketsGetCall{Service: r.Service, Bucket: bucket}
+}
+
+// BucketsGetCall is a structure that is returned by Buckets.Get.  Then we call Do() on it to return the bucket
+type BucketsGetCall struct {
+	Service *MockService
+	Bucket  string
+	ctx     context.Context
+}
+
+// Do will be called on BucketsGetCall and return the bucket found
+func (c *BucketsGetCall) Do(opts ...googleapi.CallOption) (result *storage.Bucket, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Buckets.Get", c.Bucket, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	bucket := c.Service.Buckets.find(c.Bucket)
+	if bucket == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Bucket)
+	}
+	return bucket.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *BucketsGetCall) Context(ctx context.Context) *BucketsGetCall {
+	c.ctx = ctx
+	return c
+}
+
+// List will take a project ID and returns a List Call, so we can run a Do() method on it.
+func (r *BucketsService) List(projectid string) *BucketsListCall {
+	return &BucketsListCall{Service: r.Service, Projectid: projectid}
+}
+
+// BucketsListCall is a structure that is returned by Buckets.List.  Then we call Do() on it to return the
+// project's buckets
+type BucketsListCall struct {
+	Service   *MockService
+	Projectid string
+	ctx       context.Context
+}
+
+// Do will be called on BucketsListCall and return the buckets in the project
+func (c *BucketsListCall) Do(opts ...googleapi.CallOption) (result *storage.Buckets, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Buckets.List", c.Projectid, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	project := c.Service.Projects.get(projectResourceName(c.Projectid))
+	if project == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Projectid)
+	}
+	response := &storage.Buckets{Kind: "storage#buckets"}
+	for _, bucket := range project.Buckets {
+		response.Items = append(response.Items, bucket.toAPI())
+	}
+	return response, nil
+}
+
+// Context sets the context used by Do
+func (c *BucketsListCall) Context(ctx context.Context) *BucketsListCall {
+	c.ctx = ctx
+	return c
+}
+
+// Patch will take a bucket name and the bucket fields to change, and returns a Patch Call, so we can run a
+// Do() method on it.  Only labels and uniform bucket-level access are patched
+func (r *BucketsService) Patch(bucket string, bucket2 *storage.Bucket) *BucketsPatchCall {
+	return &BucketsPatchCall{Service: r.Service, Bucket: bucket, Bucket2: bucket2}
+}
+
+// BucketsPatchCall is a structure that is returned by Buckets.Patch.  Then we call Do() on it to update the bucket
+type BucketsPatchCall struct {
+	Service *MockService
+	Bucket  string
+	Bucket2 *storage.Bucket
+	ctx     context.Context
+}
+
+// Do will be called on BucketsPatchCall to update the bucket and return it
+func (c *BucketsPatchCall) Do(opts ...googleapi.CallOption) (result *storage.Bucket, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Buckets.Patch", c.Bucket, c.Bucket2)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	bucket := c.Service.Buckets.find(c.Bucket)
+	if bucket == nil {
+		return nil, fmt.Errorf("%v: %v", resourceNotFoundError, c.Bucket)
+	}
+	if c.Bucket2 == nil {
+		return nil, fmt.Errorf("bucket is required")
+	}
+	if c.Bucket2.Labels != nil {
+		bucket.Labels = copyLabels(c.Bucket2.Labels)
+	}
+	if iamConfiguration := c.Bucket2.IamConfiguration; iamConfiguration != nil && iamConfiguration.UniformBucketLevelAccess != nil {
+		if !iamConfiguration.UniformBucketLevelAccess.Enabled && policyHasConditions(bucket.Policy) {
+			return nil, fmt.Errorf("uniform bucket-level access can't be disabled on a bucket with IAM conditions")
+		}
+		bucket.UniformBucketLevelAccess = iamConfiguration.UniformBucketLevelAccess.Enabled
+	}
+	c.Service.AuditLogs.record("storage.googleapis.com", "storage.buckets.update", bucketAuditName(bucket.Name), bucket.ProjectID, nil)
+	return bucket.toAPI(), nil
+}
+
+// Context sets the context used by Do
+func (c *BucketsPatchCall) Context(ctx context.Context) *BucketsPatchCall {
+	c.ctx = ctx
+	return c
+}
+
+// Delete will take a bucket name and returns a Delete Call, so we can run a Do() method on it.
+func (r *BucketsService) Delete(bucket string) *BucketsDeleteCall {
+	return &BucketsDeleteCall{Service: r.Service, Bucket: bucket}
+}
+
+// BucketsDeleteCall is a structure that is returned by Buckets.Delete.  Then we call Do() on it to delete the bucket
+type BucketsDeleteCall struct {
+	Service *MockService
+	Bucket  string
+	ctx     context.Context
+}
+
+// Do will be called on BucketsDeleteCall to delete the bucket
+func (c *BucketsDeleteCall) Do(opts ...googleapi.CallOption) (err error) {
+	call, err := c.Service.beginCall(c.ctx, "Buckets.Delete", c.Bucket, nil)
+	defer func() { call.end(nil, err) }()
+	if err != nil {
+		return err
+	}
+	for _, project := range c.Service.Projects.ProjectList {
+		for i, bucket := range project.Buckets {
+			if bucket.Name == c.Bucket {
+				prior := c.Service.assetSnapshot(&bucket.IamPolicyHolder)
+				project.Buckets = append(project.Buckets[:i], project.Buckets[i+1:]...)
+				c.Service.AuditLogs.record("storage.googleapis.com", "storage.buckets.delete", bucketAuditName(bucket.Name), bucket.ProjectID, nil)
+				c.Service.publishAssetChange(prior, nil)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%v: %v", resourceNotFoundError, c.Bucket)
+}
+
+// Context sets the context used by Do
+func (c *BucketsDeleteCall) Context(ctx context.Context) *BucketsDeleteCall {
+	c.ctx = ctx
+	return c
+}
+
+// GetIamPolicy will take a bucket name and returns a GetIamPolicy Call, so we can run a Do() method on it.
+func (r *BucketsService) GetIamPolicy(bucket string) *BucketsGetIamPolicyCall {
+	return &BucketsGetIamPolicyCall{Service: r.Service, Bucket: bucket}
+}
+
+// BucketsGetIamPolicyCall is a structure that is returned by Buckets.GetIamPolicy.  Then we call Do() on it
+// to actually return the policy
+type BucketsGetIamPolicyCall struct {
+	Service                *MockService
+	Bucket                 string
+	requestedPolicyVersion int64
+	ctx                    context.Context
+}
+
+// OptionsRequestedPolicyVersion sets the policy version the caller understands.  Policies with conditions
+// can only be read with version 3
+func (c *BucketsGetIamPolicyCall) OptionsRequestedPolicyVersion(optionsRequestedPolicyVersion int64) *BucketsGetIamPolicyCall {
+	c.requestedPolicyVersion = optionsRequestedPolicyVersion
+	return c
+}
+
+// Do will be called on BucketsGetIamPolicyCall and return the policy found
+func (c *BucketsGetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *storage.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Buckets.GetIamPolicy", c.Bucket, nil)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	policy, err := getIamPolicy(c.Bucket, bucketNameFormat, c.Service.Buckets.policyHolder)
+	if err != nil {
+		return nil, err
+	}
+	if policyHasConditions(policy) && c.requestedPolicyVersion < 3 {
+		return nil, fmt.Errorf("policy has conditions, requested policy version must be 3")
+	}
+	return bucketPolicy(c.Bucket, policy)
+}
+
+// Context sets the context used by Do
+func (c *BucketsGetIamPolicyCall) Context(ctx context.Context) *BucketsGetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// SetIamPolicy will take a bucket name and a policy and returns a SetIamPolicy Call, so we can run a
+// Do() method on it.
+func (r *BucketsService) SetIamPolicy(bucket string, policy *storage.Policy) *BucketsSetIamPolicyCall {
+	return &BucketsSetIamPolicyCall{Service: r.Service, Bucket: bucket, Policy: policy}
+}
+
+// BucketsSetIamPolicyCall is a structure that is returned by Buckets.SetIamPolicy.  Then we call Do() on it
+// to Set the Bucket Policy
+type BucketsSetIamPolicyCall struct {
+	Service *MockService
+	Bucket  string
+	Policy  *storage.Policy
+	ctx     context.Context
+}
+
+// Do will be called on BucketsSetIamPolicyCall to process the policy change and returns the policy it sets.
+// Like the real API, conditions can only be used on buckets with uniform bucket-level access enabled
+func (c *BucketsSetIamPolicyCall) Do(opts ...googleapi.CallOption) (result *storage.Policy, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Buckets.SetIamPolicy", c.Bucket, c.Policy)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	if c.Policy == nil {
+		return nil, fmt.Errorf("policy is required")
+	}
+	request := &cloudresourcemanager.SetIamPolicyRequest{Policy: &cloudresourcemanager.Policy{}}
+	if err := convertPolicy(c.Policy, request.Policy); err != nil {
+		return nil, err
+	}
+	if bucket := c.Service.Buckets.find(c.Bucket); bucket != nil && policyHasConditions(request.Policy) && !bucket.UniformBucketLevelAccess {
+		return nil, fmt.Errorf("IAM conditions require uniform bucket-level access to be enabled on bucket: %v", c.Bucket)
+	}
+	policy, err := setIamPolicy(c.Service, c.Bucket, bucketNameFormat, c.Service.Buckets.policyHolder, request)
+	if err != nil {
+		return nil, err
+	}
+	return bucketPolicy(c.Bucket, policy)
+}
+
+// Context sets the context used by Do
+func (c *BucketsSetIamPolicyCall) Context(ctx context.Context) *BucketsSetIamPolicyCall {
+	c.ctx = ctx
+	return c
+}
+
+// TestIamPermissions will take a bucket name and a list of permissions and returns a TestIamPermissions
+// Call, so we can run a Do() method on it.
+func (r *BucketsService) TestIamPermissions(bucket string, permissions []string) *BucketsTestIamPermissionsCall {
+	return &BucketsTestIamPermissionsCall{Service: r.Service, Bucket: bucket, Permissions: permissions}
+}
+
+// BucketsTestIamPermissionsCall is a structure that is returned by Buckets.TestIamPermissions.  Then we
+// call Do() on it to find which of the permissions the Caller has
+type BucketsTestIamPermissionsCall struct {
+	Service     *MockService
+	Bucket      string
+	Permissions []string
+	ctx         context.Context
+}
+
+// Do will be called on BucketsTestIamPermissionsCall and return the permissions the Caller has on the bucket,
+// including the ones granted through projectOwner:, projectEditor: and projectViewer: members
+func (c *BucketsTestIamPermissionsCall) Do(opts ...googleapi.CallOption) (result *storage.TestIamPermissionsResponse, err error) {
+	call, err := c.Service.beginCall(c.ctx, "Buckets.TestIamPermissions", c.Bucket, c.Permissions)
+	defer func() { call.end(result, err) }()
+	if err != nil {
+		return nil, err
+	}
+	holder, err := findPolicyHolder(c.Bucket, bucketNameFormat, c.Service.Buckets.policyHolder)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.TestIamPermissionsResponse{
+		Kind:        "storage#testIamPermissionsResponse",
+		Permissions: holder.testIamPermissions(c.Permissions, c.Service.Buckets.memberMatches, c.Service.conditionHolds(holder)),
+	}, nil
+}
+
+// Context sets the context used by Do
+func (c *BucketsTestIamPermissionsCall) Context(ctx context.Context) *BucketsTestIamPermissionsCall {
+	c.ctx = ctx
+	return c
+}
+
+// bucketPolicy converts a policy to the storage API's policy type, filling in the bucket specific fields
+func bucketPolicy(bucket string, policy *cloudresourcemanager.Policy) (*storage.Policy, error) {
+	result := &storage.Policy{}
+	if err := convertPolicy(policy, result); err != nil {
+		return nil, err
+	}
+	result.Kind = "storage#policy"
+	result.ResourceId = "projects/_/buckets/" + bucket
+	return result, nil
+}
+
+// policyHasConditions returns true if any of the policy's bindings has a condition
+func policyHasConditions(policy *cloudresourcemanager.Policy) bool {
+	if policy == nil {
+		return false
+	}
+	for _, binding := range policy.Bindings {
+		if binding != nil && binding.Condition != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package mockgcp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	storage "google.golang.org/api/storage/v1"
+)
+
+func TestBuckets_Insert_Do(t *testing.T) {
+	t.Run("should create bucket on project with default policy", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+
+		_, err := service.Buckets.Insert("test-project", &storage.Bucket{Name: "test-bucket"}).Do()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		policy, _ := service.Buckets.GetIamPolicy("test-bucket").Do()
+		want := []string{"projectEditor:test-project", "projectOwner:test-project"}
+		got := policy.Bindings[0].Members
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+		if len(service.Projects.ProjectList[0].Buckets) != 1 {
+			t.Errorf("expected bucket to be added to the project")
+		}
+	})
+	t.Run("should return err if project doesn't exist", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+
+		_, err := service.Buckets.Insert("test-project", &storage.Bucket{Name: "test-bucket"}).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+	t.Run("should return err if bucket name is taken", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+		service.Projects.NewProject("projects/other-project", "", nil)
+		service.Buckets.NewBucket("test-project", "test-bucket", nil)
+
+		_, err := service.Buckets.Insert("other-project", &storage.Bucket{Name: "test-bucket"}).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+	t.Run("should return err if bucket is nil", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+
+		_, err := service.Buckets.Insert("test-project", nil).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestBuckets_Patch_Do(t *testing.T) {
+	t.Run("should return err if bucket is nil", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+		service.Buckets.NewBucket("test-project", "test-bucket", nil)
+
+		_, err := service.Buckets.Patch("test-bucket", nil).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+	})
+}
+
+func TestBuckets_SetIamPolicy_Do(t *testing.T) {
+	t.Run("should return err and keep the policy if policy is nil", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+		service.Buckets.NewBucket("test-project", "test-bucket", nil)
+
+		_, err := service.Buckets.SetIamPolicy("test-bucket", nil).Do()
+
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+		if policy, _ := service.Buckets.GetIamPolicy("test-bucket").Do(); len(policy.Bindings) == 0 {
+			t.Errorf("expected the default policy to be kept")
+		}
+	})
+	t.Run("should set policy on bucket", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+		service.Buckets.NewBucket("test-project", "test-bucket", nil)
+
+		policy := &storage.Policy{Bindings: []*storage.PolicyBindings{
+			{Role: "roles/storage.objectViewer", Members: []string{"user:test@testdomain.co"}},
+		}}
+		service.Buckets.SetIamPolicy("test-bucket", policy).Do()
+
+		got, _ := service.Buckets.GetIamPolicy("test-bucket").Do()
+
+		if !reflect.DeepEqual(got.Bindings, policy.Bindings) {
+			t.Errorf("got %v want %v", got.Bindings, policy.Bindings)
+		}
+		if got.ResourceId != "projects/_/buckets/test-bucket" {
+			t.Errorf("got %v want %v", got.ResourceId, "projects/_/buckets/test-bucket")
+		}
+	})
+	t.Run("should require uniform bucket-level access for conditions", func(t *testing.T) {
+		service, _ := NewService(context.TODO())
+		service.Projects.NewProject("projects/test-project", "", nil)
+		service.Buckets.NewBucket("test-project", "test-bucket", nil)
+		policy := &storage.Policy{Version: 3, Bindings: []*storage.PolicyBindings{{
+			Role:      "roles/storage.objectViewer",
+			Members:   []string{"user:test@testdomain.co"},
+			Condition: &storage.Expr{Expression: `request.time < timestamp("2030-01-01T00:00:00Z")`},
+		}}}
+
+		_, err := service.Buckets.SetIamPolicy("test-bucket", policy).Do()
+		if err == nil {
+			t.Errorf("expected an error but got none")
+		}
+
+		service.Buckets.Patch("test-bucket", &storage.Bucket{IamConfiguration: &storage.BucketIamConfiguration{
+			UniformBucketLevelAccess: &storage.BucketIamConfigurationUniformBucketLevelAccess{Enabled: true},
+		}}).Do()
+		_, err = service.Buckets.SetIamPolicy("test-bucket", policy).Do()
+		if err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+
+		_, err = service.Buckets.GetIamPolicy("test-bucket").Do()
+		if err == nil {
+			t.Errorf("expected an error reading a conditional policy without version 3")
+		}
+		_, err = service.Buckets.GetIamPolicy("test-bucket").OptionsRequestedPolicyVersion(3).Do()
+		if err != nil {
+			t.Errorf("expected no error but got %v", err)
+		}
+	})
+}
+
+func TestBuckets_TestIamPermissions_Do(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject("projects/test-project", "", GeneratePolicy(NewBinding("roles/owner", "user:owner@testdomain.co")))
+	service.Buckets.NewBucket("test-project", "test-bucket", nil)
+	permissions := []string{"storage.buckets.setIamPolicy"}
+
+	t.Run("should grant permissions through projectOwner member", func(t *testing.T) {
+		service.Caller = "user:owner@testdomain.co"
+
+		got, _ := service.Buckets.TestIamPermissions("test-bucket", permissions).Do()
+
+		if !reflect.DeepEqual(got.Permissions, permissions) {
+			t.Errorf("got %v want %v", got.Permissions, permissions)
+		}
+	})
+	t.Run("should not grant permissions to other callers", func(t *testing.T) {
+		service.Caller = "user:other@testdomain.co"
+
+		got, _ := service.Buckets.TestIamPermissions("test-bucket", permissions).Do()
+
+		if len(got.Permissions) != 0 {
+			t.Errorf("expected no permissions but got %v", got.Permissions)
+		}
+	})
+}
+
+func TestBuckets_Delete_Do(t *testing.T) {
+	service, _ := NewService(context.TODO())
+	service.Projects.NewProject("projects/test-project", "", nil)
+	service.Buckets.NewBucket("test-project", "test-bucket", nil)
+
+	if err := service.Buckets.Delete("test-bucket").Do(); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	_, err := service.Buckets.Get("test-bucket").Do()
+	if err == nil {
+		t.Errorf("expected an error but got none")
+	}
+}